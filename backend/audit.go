@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/codyseavey/bets/config"
+	"github.com/codyseavey/bets/models"
+	"github.com/codyseavey/bets/services"
+	"github.com/codyseavey/bets/storage"
+)
+
+// runAudit implements `bets audit`: it recomputes every member balance and
+// pool escrow from PointsLog and reports anything that doesn't match. It exits
+// 1 if discrepancies remain, so it can run from cron or CI.
+func runAudit(args []string) int {
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	dbPath := fs.String("db", "", "path to the SQLite database (defaults to $DB_PATH)")
+	groupID := fs.String("group", "", "only audit this group")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	fix := fs.Bool("fix", false, "correct member balances to match PointsLog")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: bets audit [--db PATH] [--group ID] [--json] [--fix]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	path := *dbPath
+	if path == "" {
+		path = config.Load().DBPath
+	}
+	db := storage.InitDB(path)
+
+	groupIDs := []string{*groupID}
+	if *groupID == "" {
+		groupIDs = nil
+		if err := db.Model(&models.Group{}).Order("created_at").Pluck("id", &groupIDs).Error; err != nil {
+			fmt.Fprintf(os.Stderr, "audit: %v\n", err)
+			return 2
+		}
+	}
+
+	reports := make([]*services.AuditReport, 0, len(groupIDs))
+	unresolved := 0
+	for _, id := range groupIDs {
+		report, err := services.AuditGroup(db, id)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "audit: group %s: %v\n", id, err)
+			return 2
+		}
+		if *fix && len(report.Balances) > 0 {
+			if err := services.FixBalances(db, report); err != nil {
+				fmt.Fprintf(os.Stderr, "audit: fixing group %s: %v\n", id, err)
+				return 2
+			}
+		}
+		for _, b := range report.Balances {
+			if !b.Fixed {
+				unresolved++
+			}
+		}
+		unresolved += len(report.Escrows)
+		reports = append(reports, report)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(reports); err != nil {
+			fmt.Fprintf(os.Stderr, "audit: %v\n", err)
+			return 2
+		}
+	} else {
+		printAudit(reports)
+	}
+
+	if unresolved > 0 {
+		return 1
+	}
+	return 0
+}
+
+func printAudit(reports []*services.AuditReport) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	for _, r := range reports {
+		status := "ok"
+		if !r.Clean() {
+			status = fmt.Sprintf("%d discrepancies", len(r.Balances)+len(r.Escrows))
+		}
+		fmt.Fprintf(w, "%s (%s): %d members, %d pools, %s\n", r.GroupName, r.GroupID, r.Members, r.Pools, status)
+		for _, b := range r.Balances {
+			fixed := ""
+			if b.Fixed {
+				fixed = "fixed"
+			}
+			fmt.Fprintf(w, "  member\t%s\t%s\tbalance %d\tlog says %d\t%+d\t%s\n",
+				b.Name, b.UserID, b.Balance, b.Expected, b.Expected-b.Balance, fixed)
+		}
+		for _, e := range r.Escrows {
+			fmt.Fprintf(w, "  pool\t%s\t%s\theld %d\texpected %d\t%+d\t%s\n",
+				e.Title, e.PoolID, e.Held, e.Expected, e.Held-e.Expected, e.Status)
+		}
+	}
+}
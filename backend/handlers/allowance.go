@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/codyseavey/bets/middleware"
+	"github.com/codyseavey/bets/services"
+)
+
+type AllowanceHandler struct {
+	allowanceService *services.AllowanceService
+	groupService     *services.GroupService
+	hub              *services.Hub
+}
+
+func NewAllowanceHandler(allowanceService *services.AllowanceService, groupService *services.GroupService, hub *services.Hub) *AllowanceHandler {
+	return &AllowanceHandler{
+		allowanceService: allowanceService,
+		groupService:     groupService,
+		hub:              hub,
+	}
+}
+
+func (h *AllowanceHandler) Get(c *gin.Context) {
+	status, err := h.allowanceService.GetAllowance(c.Param("id"), middleware.GetUserID(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+func (h *AllowanceHandler) Set(c *gin.Context) {
+	var req services.SetAllowanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	groupID := c.Param("id")
+	allowance, approval, err := h.allowanceService.SetAllowance(groupID, middleware.GetUserID(c), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if approval != nil {
+		pendingApproval(c, h.hub, h.groupService, approval)
+		return
+	}
+
+	h.hub.BroadcastToGroup(groupID, services.WSEvent{
+		Type:    "allowance_updated",
+		Payload: allowance,
+	})
+	c.JSON(http.StatusOK, allowance)
+}
+
+func (h *AllowanceHandler) Delete(c *gin.Context) {
+	groupID := c.Param("id")
+	if err := h.allowanceService.DeleteAllowance(groupID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.hub.BroadcastToGroup(groupID, services.WSEvent{
+		Type:    "allowance_updated",
+		Payload: nil,
+	})
+	c.JSON(http.StatusOK, gin.H{"message": "allowance removed"})
+}
+
+func (h *AllowanceHandler) Claim(c *gin.Context) {
+	groupID := c.Param("id")
+	payment, err := h.allowanceService.Claim(groupID, middleware.GetUserID(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.hub.BroadcastToGroup(groupID, services.WSEvent{
+		Type:    "allowance_claimed",
+		Payload: payment,
+	})
+	c.JSON(http.StatusCreated, payment)
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/codyseavey/bets/middleware"
+	"github.com/codyseavey/bets/services"
+)
+
+type BailoutHandler struct {
+	bailoutService *services.BailoutService
+	groupService   *services.GroupService
+	hub            *services.Hub
+}
+
+func NewBailoutHandler(bailoutService *services.BailoutService, groupService *services.GroupService, hub *services.Hub) *BailoutHandler {
+	return &BailoutHandler{
+		bailoutService: bailoutService,
+		groupService:   groupService,
+		hub:            hub,
+	}
+}
+
+// Get returns the group's bailout policy and whether the caller can use it.
+func (h *BailoutHandler) Get(c *gin.Context) {
+	status, err := h.bailoutService.GetStatus(c.Param("id"), middleware.GetUserID(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+func (h *BailoutHandler) SetPolicy(c *gin.Context) {
+	var req services.SetBailoutPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, approval, err := h.bailoutService.SetPolicy(c.Param("id"), middleware.GetUserID(c), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if approval != nil {
+		pendingApproval(c, h.hub, h.groupService, approval)
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+func (h *BailoutHandler) DeletePolicy(c *gin.Context) {
+	if err := h.bailoutService.DeletePolicy(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "bailout policy removed"})
+}
+
+func (h *BailoutHandler) Claim(c *gin.Context) {
+	groupID := c.Param("id")
+	userID := middleware.GetUserID(c)
+	status, err := h.bailoutService.Claim(groupID, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.hub.BroadcastToGroup(groupID, services.WSEvent{
+		Type: "member_bailed_out",
+		Payload: gin.H{
+			"user_id":  userID,
+			"balance":  status.Balance,
+			"bailouts": status.Used,
+		},
+	})
+	c.JSON(http.StatusOK, status)
+}
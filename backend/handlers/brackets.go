@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/codyseavey/bets/middleware"
+	"github.com/codyseavey/bets/services"
+)
+
+type BracketHandler struct {
+	bracketService *services.BracketService
+	hub            *services.Hub
+}
+
+func NewBracketHandler(bracketService *services.BracketService, hub *services.Hub) *BracketHandler {
+	return &BracketHandler{
+		bracketService: bracketService,
+		hub:            hub,
+	}
+}
+
+// inGroup makes sure the bracket in the URL belongs to the group the caller
+// was authorized against.
+func (h *BracketHandler) inGroup(c *gin.Context) bool {
+	groupID, err := h.bracketService.GetBracketGroupID(c.Param("bid"))
+	if err != nil || groupID != c.Param("id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "bracket not found"})
+		return false
+	}
+	return true
+}
+
+func (h *BracketHandler) Create(c *gin.Context) {
+	var req services.CreateBracketRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	groupID := c.Param("id")
+	bracket, err := h.bracketService.CreateBracket(groupID, middleware.GetUserID(c), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.hub.BroadcastToGroup(groupID, services.WSEvent{
+		Type:    "bracket_created",
+		Payload: bracket,
+	})
+
+	c.JSON(http.StatusCreated, bracket)
+}
+
+func (h *BracketHandler) List(c *gin.Context) {
+	brackets, err := h.bracketService.GetGroupBrackets(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, brackets)
+}
+
+func (h *BracketHandler) Get(c *gin.Context) {
+	bracket, err := h.bracketService.GetBracket(c.Param("bid"))
+	if err != nil || bracket.GroupID != c.Param("id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "bracket not found"})
+		return
+	}
+	c.JSON(http.StatusOK, bracket)
+}
+
+func (h *BracketHandler) SubmitEntry(c *gin.Context) {
+	if !h.inGroup(c) {
+		return
+	}
+
+	var req services.SubmitBracketRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	bracketID := c.Param("bid")
+	userID := middleware.GetUserID(c)
+	entry, err := h.bracketService.SubmitEntry(bracketID, userID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Don't broadcast the picks themselves; they stay hidden until lock
+	h.hub.BroadcastToGroup(c.Param("id"), services.WSEvent{
+		Type: "bracket_entry_submitted",
+		Payload: gin.H{
+			"bracket_id": bracketID,
+			"user_id":    userID,
+		},
+	})
+
+	c.JSON(http.StatusOK, entry)
+}
+
+func (h *BracketHandler) GetEntry(c *gin.Context) {
+	if !h.inGroup(c) {
+		return
+	}
+
+	entry, err := h.bracketService.GetEntry(c.Param("bid"), middleware.GetUserID(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no entry for this bracket"})
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+func (h *BracketHandler) Leaderboard(c *gin.Context) {
+	if !h.inGroup(c) {
+		return
+	}
+
+	standings, err := h.bracketService.GetLeaderboard(c.Param("bid"), middleware.GetUserID(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "bracket not found"})
+		return
+	}
+	c.JSON(http.StatusOK, standings)
+}
+
+func (h *BracketHandler) Lock(c *gin.Context) {
+	if !h.inGroup(c) {
+		return
+	}
+
+	bracketID := c.Param("bid")
+	if err := h.bracketService.LockBracket(bracketID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.hub.BroadcastToGroup(c.Param("id"), services.WSEvent{
+		Type:    "bracket_locked",
+		Payload: gin.H{"bracket_id": bracketID},
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "bracket locked"})
+}
+
+func (h *BracketHandler) RecordResult(c *gin.Context) {
+	if !h.inGroup(c) {
+		return
+	}
+
+	var req services.RecordBracketResultRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	bracket, err := h.bracketService.RecordResult(c.Param("bid"), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.hub.BroadcastToGroup(c.Param("id"), services.WSEvent{
+		Type:    "bracket_result",
+		Payload: bracket,
+	})
+
+	c.JSON(http.StatusOK, bracket)
+}
+
+func (h *BracketHandler) Cancel(c *gin.Context) {
+	if !h.inGroup(c) {
+		return
+	}
+
+	bracketID := c.Param("bid")
+	if err := h.bracketService.CancelBracket(bracketID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.hub.BroadcastToGroup(c.Param("id"), services.WSEvent{
+		Type:    "bracket_cancelled",
+		Payload: gin.H{"bracket_id": bracketID},
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "bracket cancelled, entry fees refunded"})
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/codyseavey/bets/middleware"
+	"github.com/codyseavey/bets/models"
+	"github.com/codyseavey/bets/services"
+)
+
+type ChallengeHandler struct {
+	challengeService *services.ChallengeService
+	hub              *services.Hub
+}
+
+func NewChallengeHandler(challengeService *services.ChallengeService, hub *services.Hub) *ChallengeHandler {
+	return &ChallengeHandler{
+		challengeService: challengeService,
+		hub:              hub,
+	}
+}
+
+// notify sends a challenge event to just the two members involved rather than
+// broadcasting it to the whole group.
+func (h *ChallengeHandler) notify(eventType string, challenge *models.Challenge) {
+	h.hub.SendToUsers(challenge.GroupID, []string{challenge.ChallengerID, challenge.OpponentID}, services.WSEvent{
+		Type:    eventType,
+		Payload: challenge,
+	})
+}
+
+func (h *ChallengeHandler) Create(c *gin.Context) {
+	var req services.CreateChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	groupID := c.Param("id")
+	userID := middleware.GetUserID(c)
+
+	challenge, err := h.challengeService.CreateChallenge(groupID, userID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.notify("challenge_received", challenge)
+	c.JSON(http.StatusCreated, challenge)
+}
+
+func (h *ChallengeHandler) List(c *gin.Context) {
+	groupID := c.Param("id")
+	userID := middleware.GetUserID(c)
+
+	challenges, err := h.challengeService.GetUserChallenges(groupID, userID, c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, challenges)
+}
+
+func (h *ChallengeHandler) Get(c *gin.Context) {
+	challenge, err := h.challengeService.GetChallengeFor(c.Param("id"), c.Param("cid"), middleware.GetUserID(c), isGroupAdmin(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, challenge)
+}
+
+func (h *ChallengeHandler) Accept(c *gin.Context) {
+	challenge, err := h.challengeService.AcceptChallenge(c.Param("cid"), middleware.GetUserID(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.notify("challenge_accepted", challenge)
+	c.JSON(http.StatusOK, challenge)
+}
+
+func (h *ChallengeHandler) Decline(c *gin.Context) {
+	challenge, err := h.challengeService.DeclineChallenge(c.Param("cid"), middleware.GetUserID(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.notify("challenge_declined", challenge)
+	c.JSON(http.StatusOK, challenge)
+}
+
+func (h *ChallengeHandler) Withdraw(c *gin.Context) {
+	challenge, err := h.challengeService.WithdrawChallenge(c.Param("cid"), middleware.GetUserID(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.notify("challenge_withdrawn", challenge)
+	c.JSON(http.StatusOK, challenge)
+}
+
+func (h *ChallengeHandler) ReportResult(c *gin.Context) {
+	var req services.ReportResultRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	challenge, err := h.challengeService.ReportResult(c.Param("cid"), middleware.GetUserID(c), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.notify("challenge_result", challenge)
+	c.JSON(http.StatusOK, challenge)
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/codyseavey/bets/middleware"
+	"github.com/codyseavey/bets/models"
 	"github.com/codyseavey/bets/services"
 )
 
@@ -94,6 +95,8 @@ func (h *GroupHandler) Join(c *gin.Context) {
 type UpdateGroupRequest struct {
 	Name          string `json:"name" binding:"required"`
 	DefaultPoints int    `json:"default_points" binding:"required,gt=0"`
+	TransferLimit *int   `json:"transfer_limit" binding:"omitempty,gte=0"`
+	CashRate      *int   `json:"cash_rate" binding:"omitempty,gte=0"`
 }
 
 func (h *GroupHandler) Update(c *gin.Context) {
@@ -104,7 +107,7 @@ func (h *GroupHandler) Update(c *gin.Context) {
 	}
 
 	groupID := c.Param("id")
-	if err := h.groupService.UpdateGroup(groupID, req.Name, req.DefaultPoints); err != nil {
+	if err := h.groupService.UpdateGroup(groupID, req.Name, req.DefaultPoints, req.TransferLimit, req.CashRate); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -118,6 +121,8 @@ type GrantPointsRequest struct {
 	Note   string `json:"note"`
 }
 
+// GrantPoints grants points to one member, or files an approval request if
+// the group's two-admin rule covers the amount.
 func (h *GroupHandler) GrantPoints(c *gin.Context) {
 	var req GrantPointsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -126,10 +131,15 @@ func (h *GroupHandler) GrantPoints(c *gin.Context) {
 	}
 
 	groupID := c.Param("id")
-	if err := h.groupService.GrantPoints(groupID, req.UserID, req.Amount, req.Note); err != nil {
+	approval, err := h.groupService.RequestGrant(groupID, middleware.GetUserID(c), req.UserID, req.Amount, req.Note)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if approval != nil {
+		pendingApproval(c, h.hub, h.groupService, approval)
+		return
+	}
 
 	h.hub.BroadcastToGroup(groupID, services.WSEvent{
 		Type: "points_granted",
@@ -143,6 +153,200 @@ func (h *GroupHandler) GrantPoints(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "points granted"})
 }
 
+func (h *GroupHandler) BulkGrant(c *gin.Context) {
+	var req services.BulkGrantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	groupID := c.Param("id")
+	batch, approval, err := h.groupService.RequestBulkGrant(groupID, middleware.GetUserID(c), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if approval != nil {
+		pendingApproval(c, h.hub, h.groupService, approval)
+		return
+	}
+
+	h.hub.BroadcastToGroup(groupID, services.WSEvent{
+		Type:    "points_granted",
+		Payload: batch,
+	})
+
+	c.JSON(http.StatusCreated, batch)
+}
+
+func (h *GroupHandler) ListGrantBatches(c *gin.Context) {
+	batches, err := h.groupService.GetGrantBatches(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, batches)
+}
+
+func (h *GroupHandler) UndoGrantBatch(c *gin.Context) {
+	groupID := c.Param("id")
+	batch, approval, err := h.groupService.RequestUndoGrantBatch(groupID, c.Param("bid"), middleware.GetUserID(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if approval != nil {
+		pendingApproval(c, h.hub, h.groupService, approval)
+		return
+	}
+
+	h.hub.BroadcastToGroup(groupID, services.WSEvent{
+		Type:    "grant_undone",
+		Payload: batch,
+	})
+
+	c.JSON(http.StatusOK, batch)
+}
+
+type SetApproveAboveRequest struct {
+	ApproveAbove *int `json:"approve_above" binding:"required,gte=0"`
+}
+
+// SetApproveAbove turns the two-admin rule for grants on or off or changes its
+// threshold. Loosening it needs a second admin like a grant would.
+func (h *GroupHandler) SetApproveAbove(c *gin.Context) {
+	var req SetApproveAboveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	approval, err := h.groupService.SetApproveAbove(c.Param("id"), middleware.GetUserID(c), *req.ApproveAbove)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if approval != nil {
+		pendingApproval(c, h.hub, h.groupService, approval)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "approval threshold updated"})
+}
+
+func (h *GroupHandler) ListGrantApprovals(c *gin.Context) {
+	approvals, err := h.groupService.GetGrantApprovals(c.Param("id"), c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, approvals)
+}
+
+func (h *GroupHandler) ApproveGrant(c *gin.Context) {
+	groupID := c.Param("id")
+	approval, err := h.groupService.ApproveGrant(groupID, c.Param("aid"), middleware.GetUserID(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Balances (or the threshold) changed, so the whole group hears about it
+	h.hub.BroadcastToGroup(groupID, services.WSEvent{
+		Type:    "grant_approved",
+		Payload: approval,
+	})
+	c.JSON(http.StatusOK, approval)
+}
+
+func (h *GroupHandler) RejectGrant(c *gin.Context) {
+	groupID := c.Param("id")
+	approval, err := h.groupService.RejectGrant(groupID, c.Param("aid"), middleware.GetUserID(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.hub.SendToUsers(groupID, h.groupService.GetAdminIDs(groupID), services.WSEvent{
+		Type:    "grant_rejected",
+		Payload: approval,
+	})
+	c.JSON(http.StatusOK, approval)
+}
+
+// pendingApproval tells the group's admins about a new approval request and
+// answers 202 with it.
+// pendingApproval answers a request the two-admin rule held back, and lets the
+// group's admins know there's something to review.
+func pendingApproval(c *gin.Context, hub *services.Hub, groupService *services.GroupService, approval *models.GrantApproval) {
+	hub.SendToUsers(approval.GroupID, groupService.GetAdminIDs(approval.GroupID), services.WSEvent{
+		Type:    "grant_approval_requested",
+		Payload: approval,
+	})
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":  "waiting for another admin to approve",
+		"approval": approval,
+	})
+}
+
+type SetMemberRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=admin member"`
+}
+
+func (h *GroupHandler) SetMemberRole(c *gin.Context) {
+	var req SetMemberRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	groupID := c.Param("id")
+	targetUserID := c.Param("uid")
+	approval, err := h.groupService.SetMemberRole(groupID, middleware.GetUserID(c), targetUserID, req.Role)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if approval != nil {
+		pendingApproval(c, h.hub, h.groupService, approval)
+		return
+	}
+
+	h.hub.BroadcastToGroup(groupID, services.WSEvent{
+		Type:    "member_role_changed",
+		Payload: gin.H{"user_id": targetUserID, "role": req.Role},
+	})
+	c.JSON(http.StatusOK, gin.H{"message": "role updated"})
+}
+
+// Reconcile recomputes every points balance in the group from the ledger and
+// reports any drift.
+func (h *GroupHandler) Reconcile(c *gin.Context) {
+	report, err := h.groupService.Reconcile(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"ok":     report.OK(),
+		"report": report,
+	})
+}
+
+// VerifyChain checks the group's PointsLog hash chain for edited or deleted
+// entries. Any member can run it.
+func (h *GroupHandler) VerifyChain(c *gin.Context) {
+	report, err := h.groupService.VerifyChain(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"ok":     report.OK(),
+		"report": report,
+	})
+}
+
 func (h *GroupHandler) KickMember(c *gin.Context) {
 	groupID := c.Param("id")
 	targetUserID := c.Param("uid")
@@ -191,3 +395,24 @@ func (h *GroupHandler) RegenerateInvite(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, gin.H{"invite_code": code})
 }
+
+// RegenerateWebhookSecret rotates the group's inbound webhook secret. This is
+// the only time the secret is returned, so callers must store it.
+func (h *GroupHandler) RegenerateWebhookSecret(c *gin.Context) {
+	groupID := c.Param("id")
+	secret, err := h.groupService.RegenerateWebhookSecret(groupID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"webhook_secret": secret})
+}
+
+func (h *GroupHandler) DisableWebhooks(c *gin.Context) {
+	groupID := c.Param("id")
+	if err := h.groupService.DisableWebhooks(groupID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "webhooks disabled"})
+}
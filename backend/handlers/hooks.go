@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/codyseavey/bets/models"
+	"github.com/codyseavey/bets/services"
+)
+
+// HookHandler serves signed inbound webhooks so scripts and automations can
+// lock or resolve pools without a user session. Requests are authenticated by
+// middleware.WebhookSignatureRequired, not by JWT.
+type HookHandler struct {
+	poolService *services.PoolService
+	hub         *services.Hub
+}
+
+func NewHookHandler(poolService *services.PoolService, hub *services.Hub) *HookHandler {
+	return &HookHandler{
+		poolService: poolService,
+		hub:         hub,
+	}
+}
+
+// hookPool loads the :pid pool and makes sure it belongs to the :id group the
+// signature was verified against.
+func (h *HookHandler) hookPool(c *gin.Context) *models.Pool {
+	pool, err := h.poolService.GetPool(c.Param("pid"), "")
+	if err != nil || pool.GroupID != c.Param("id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "pool not found"})
+		return nil
+	}
+	return pool
+}
+
+func (h *HookHandler) Lock(c *gin.Context) {
+	pool := h.hookPool(c)
+	if pool == nil {
+		return
+	}
+
+	// Hooks act with the pool creator's authority
+	if err := h.poolService.LockPool(pool.ID, pool.CreatedBy, true); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.poolService.BroadcastPoolEvent(h.hub, pool.ID, services.WSEvent{
+		Type:    "pool_locked",
+		Payload: gin.H{"pool_id": pool.ID},
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "pool locked"})
+}
+
+type HookResolveRequest struct {
+	WinningOptionID string   `json:"winning_option_id"`
+	WinningOption   string   `json:"winning_option"` // option label, for scripts that don't know IDs
+	PushOptions     []string `json:"push_options"`   // IDs or labels of options whose bets are refunded
+}
+
+func (h *HookHandler) Resolve(c *gin.Context) {
+	var req HookResolveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pool := h.hookPool(c)
+	if pool == nil {
+		return
+	}
+
+	outcome := req.WinningOptionID
+	if outcome == "" {
+		outcome = req.WinningOption
+	}
+	optionID, pushIDs, err := services.WebhookOutcome(pool.Options, outcome, req.PushOptions)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.poolService.ResolvePoolAs(pool.ID, optionID, pushIDs, services.WebhookActor); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resolved, _ := h.poolService.GetPool(pool.ID, "")
+	h.poolService.BroadcastPoolEvent(h.hub, pool.ID, services.WSEvent{
+		Type:    "pool_resolved",
+		Payload: resolved,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "pool resolved"})
+}
@@ -7,7 +7,9 @@ import (
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 
+	"github.com/codyseavey/bets/middleware"
 	"github.com/codyseavey/bets/models"
+	"github.com/codyseavey/bets/services"
 )
 
 type LeaderboardHandler struct {
@@ -26,6 +28,7 @@ type LeaderboardEntry struct {
 	TotalWins     int64  `json:"total_wins"`
 	TotalLosses   int64  `json:"total_losses"`
 	TotalBets     int64  `json:"total_bets"`
+	Bailouts      int64  `json:"bailouts"`
 	Rank          int    `json:"rank"`
 }
 
@@ -41,42 +44,27 @@ func (h *LeaderboardHandler) GetLeaderboard(c *gin.Context) {
 		return
 	}
 
+	// Records only count the current season; balances were reset when the
+	// last one closed
+	_, seasonStart, err := services.CurrentSeason(h.db, groupID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	entries := make([]LeaderboardEntry, 0, len(members))
 	for i, m := range members {
-		var totalBets int64
-		h.db.Model(&models.Bet{}).
-			Joins("JOIN pools ON pools.id = bets.pool_id").
-			Where("bets.user_id = ? AND pools.group_id = ?", m.UserID, groupID).
-			Count(&totalBets)
-
-		var totalWins int64
-		h.db.Model(&models.PointsLog{}).
-			Where("user_id = ? AND group_id = ? AND type = ?", m.UserID, groupID, models.PointsLogBetWon).
-			Count(&totalWins)
-
-		// Losses = bets placed on pools that resolved, where user didn't win
-		var totalLosses int64
-		h.db.Model(&models.PointsLog{}).
-			Where("user_id = ? AND group_id = ? AND type = ?", m.UserID, groupID, models.PointsLogBetPlaced).
-			Count(&totalLosses)
-		// Subtract wins and refunds to get actual losses
-		var refunds int64
-		h.db.Model(&models.PointsLog{}).
-			Where("user_id = ? AND group_id = ? AND type = ?", m.UserID, groupID, models.PointsLogBetRefund).
-			Count(&refunds)
-		actualLosses := totalLosses - totalWins - refunds
-		if actualLosses < 0 {
-			actualLosses = 0
-		}
+		rec := services.MemberRecordSince(h.db, groupID, m.UserID, seasonStart)
 
 		entries = append(entries, LeaderboardEntry{
 			UserID:        m.UserID,
 			Name:          m.User.Name,
 			AvatarURL:     m.User.AvatarURL,
 			PointsBalance: m.PointsBalance,
-			TotalWins:     totalWins,
-			TotalLosses:   actualLosses,
-			TotalBets:     totalBets,
+			TotalWins:     rec.Wins,
+			TotalLosses:   rec.Losses,
+			TotalBets:     rec.Bets,
+			Bailouts:      rec.Bailouts,
 			Rank:          i + 1,
 		})
 	}
@@ -100,8 +88,22 @@ func (h *LeaderboardHandler) GetHistory(c *gin.Context) {
 	var logs []models.PointsLog
 	var total int64
 
-	h.db.Model(&models.PointsLog{}).Where("group_id = ?", groupID).Count(&total)
-	if err := h.db.Where("group_id = ?", groupID).
+	// Hide ledger entries for restricted pools the viewer isn't part of;
+	// their notes name the pool, which would spoil e.g. a surprise-party bet.
+	// Side bets are private to the members invited to them.
+	userID := middleware.GetUserID(c)
+	hidden := services.HiddenPoolIDs(h.db, groupID, userID)
+	invited := h.db.Model(&models.SideBetMember{}).Select("side_bet_id").Where("user_id = ?", userID)
+	visible := func(q *gorm.DB) *gorm.DB {
+		return q.Where("group_id = ?", groupID).
+			Where("COALESCE(reference_id, '') NOT IN (?)", h.db.Model(&models.SideBet{}).Select("id").Where("group_id = ? AND id NOT IN (?)", groupID, invited)).
+			Where("COALESCE(reference_id, '') NOT IN (?)", h.db.Model(&models.Bet{}).Select("id").Where("pool_id IN (?)", hidden)).
+			Where("COALESCE(reference_id, '') NOT IN (?)", h.db.Model(&models.PoolOption{}).Select("id").Where("pool_id IN (?)", hidden)).
+			Where("COALESCE(reference_id, '') NOT IN (?)", h.db.Model(&models.PoolSeed{}).Select("id").Where("pool_id IN (?)", hidden))
+	}
+
+	h.db.Model(&models.PointsLog{}).Scopes(visible).Count(&total)
+	if err := h.db.Scopes(visible).
 		Preload("User").
 		Order("created_at DESC").
 		Offset(offset).
@@ -135,7 +137,7 @@ func (h *LeaderboardHandler) GetStats(c *gin.Context) {
 	h.db.Model(&models.Pool{}).Where("group_id = ?", groupID).Count(&stats.TotalPools)
 	h.db.Model(&models.Pool{}).Where("group_id = ? AND status = ?", groupID, models.PoolStatusOpen).Count(&stats.OpenPools)
 	h.db.Model(&models.Pool{}).Where("group_id = ? AND status = ?", groupID, models.PoolStatusResolved).Count(&stats.ResolvedPools)
-	h.db.Model(&models.Bet{}).Joins("JOIN pools ON pools.id = bets.pool_id").Where("pools.group_id = ?", groupID).Count(&stats.TotalBets)
+	h.db.Model(&models.Bet{}).Joins("JOIN pools ON pools.id = bets.pool_id").Where("pools.group_id = ? AND pools.type <> ?", groupID, models.PoolTypePoll).Count(&stats.TotalBets)
 	h.db.Model(&models.GroupMember{}).Where("group_id = ?", groupID).Count(&stats.TotalMembers)
 
 	var totalPoints int64
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/codyseavey/bets/middleware"
+	"github.com/codyseavey/bets/services"
+)
+
+type LeagueHandler struct {
+	leagueService *services.LeagueService
+	hub           *services.Hub
+}
+
+func NewLeagueHandler(leagueService *services.LeagueService, hub *services.Hub) *LeagueHandler {
+	return &LeagueHandler{
+		leagueService: leagueService,
+		hub:           hub,
+	}
+}
+
+// inGroup makes sure the league in the URL belongs to the group the caller
+// was authorized against.
+func (h *LeagueHandler) inGroup(c *gin.Context) bool {
+	groupID, err := h.leagueService.GetLeagueGroupID(c.Param("lid"))
+	if err != nil || groupID != c.Param("id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "league not found"})
+		return false
+	}
+	return true
+}
+
+func (h *LeagueHandler) Create(c *gin.Context) {
+	var req services.CreateLeagueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	groupID := c.Param("id")
+	league, err := h.leagueService.CreateLeague(groupID, middleware.GetUserID(c), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.hub.BroadcastToGroup(groupID, services.WSEvent{
+		Type:    "league_created",
+		Payload: league,
+	})
+
+	c.JSON(http.StatusCreated, league)
+}
+
+func (h *LeagueHandler) List(c *gin.Context) {
+	leagues, err := h.leagueService.GetGroupLeagues(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, leagues)
+}
+
+func (h *LeagueHandler) Get(c *gin.Context) {
+	league, err := h.leagueService.GetLeague(c.Param("lid"))
+	if err != nil || league.GroupID != c.Param("id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "league not found"})
+		return
+	}
+	c.JSON(http.StatusOK, league)
+}
+
+func (h *LeagueHandler) AddRound(c *gin.Context) {
+	if !h.inGroup(c) {
+		return
+	}
+
+	var req services.AddLeagueRoundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	round, err := h.leagueService.AddRound(c.Param("lid"), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.hub.BroadcastToGroup(c.Param("id"), services.WSEvent{
+		Type:    "league_round_added",
+		Payload: round,
+	})
+
+	c.JSON(http.StatusCreated, round)
+}
+
+func (h *LeagueHandler) SubmitPicks(c *gin.Context) {
+	if !h.inGroup(c) {
+		return
+	}
+
+	var req services.SubmitLeaguePicksRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	picks, err := h.leagueService.SubmitPicks(c.Param("lid"), c.Param("rid"), middleware.GetUserID(c), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, picks)
+}
+
+func (h *LeagueHandler) MyPicks(c *gin.Context) {
+	if !h.inGroup(c) {
+		return
+	}
+
+	picks, err := h.leagueService.GetUserPicks(c.Param("lid"), middleware.GetUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, picks)
+}
+
+func (h *LeagueHandler) Standings(c *gin.Context) {
+	if !h.inGroup(c) {
+		return
+	}
+
+	standings, err := h.leagueService.GetStandings(c.Param("lid"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "league not found"})
+		return
+	}
+	c.JSON(http.StatusOK, standings)
+}
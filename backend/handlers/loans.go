@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/codyseavey/bets/middleware"
+	"github.com/codyseavey/bets/models"
+	"github.com/codyseavey/bets/services"
+)
+
+type LoanHandler struct {
+	loanService *services.LoanService
+	hub         *services.Hub
+}
+
+func NewLoanHandler(loanService *services.LoanService, hub *services.Hub) *LoanHandler {
+	return &LoanHandler{
+		loanService: loanService,
+		hub:         hub,
+	}
+}
+
+// notify sends a loan event to just the lender and borrower.
+func (h *LoanHandler) notify(eventType string, loan *models.Loan) {
+	h.hub.SendToUsers(loan.GroupID, []string{loan.LenderID, loan.BorrowerID}, services.WSEvent{
+		Type:    eventType,
+		Payload: loan,
+	})
+}
+
+func (h *LoanHandler) Offer(c *gin.Context) {
+	var req services.OfferLoanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	loan, err := h.loanService.OfferLoan(c.Param("id"), middleware.GetUserID(c), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.notify("loan_offered", loan)
+	c.JSON(http.StatusCreated, loan)
+}
+
+func (h *LoanHandler) List(c *gin.Context) {
+	loans, err := h.loanService.GetUserLoans(c.Param("id"), middleware.GetUserID(c), c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, loans)
+}
+
+func (h *LoanHandler) Get(c *gin.Context) {
+	loan, err := h.loanService.GetLoan(c.Param("lid"))
+	if err != nil || loan.GroupID != c.Param("id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "loan not found"})
+		return
+	}
+	c.JSON(http.StatusOK, loan)
+}
+
+func (h *LoanHandler) Accept(c *gin.Context) {
+	h.respond(c, "loan_accepted", h.loanService.AcceptLoan)
+}
+
+func (h *LoanHandler) Decline(c *gin.Context) {
+	h.respond(c, "loan_declined", h.loanService.DeclineLoan)
+}
+
+func (h *LoanHandler) Withdraw(c *gin.Context) {
+	h.respond(c, "loan_withdrawn", h.loanService.WithdrawLoan)
+}
+
+func (h *LoanHandler) Forgive(c *gin.Context) {
+	h.respond(c, "loan_forgiven", h.loanService.ForgiveLoan)
+}
+
+func (h *LoanHandler) Repay(c *gin.Context) {
+	var req services.RepayLoanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	loan, err := h.loanService.RepayLoan(c.Param("lid"), middleware.GetUserID(c), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.notify("loan_repaid", loan)
+	c.JSON(http.StatusOK, loan)
+}
+
+// respond runs a status change on the loan in the URL and notifies both sides.
+func (h *LoanHandler) respond(c *gin.Context, eventType string, action func(loanID, userID string) (*models.Loan, error)) {
+	loan, err := action(c.Param("lid"), middleware.GetUserID(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.notify(eventType, loan)
+	c.JSON(http.StatusOK, loan)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/codyseavey/bets/middleware"
+	"github.com/codyseavey/bets/models"
+	"github.com/codyseavey/bets/services"
+)
+
+type OracleHandler struct {
+	oracleService *services.OracleService
+}
+
+func NewOracleHandler(oracleService *services.OracleService) *OracleHandler {
+	return &OracleHandler{oracleService: oracleService}
+}
+
+func isGroupAdmin(c *gin.Context) bool {
+	member := middleware.GetGroupMember(c)
+	return member != nil && member.Role == "admin"
+}
+
+// visibleOracle hides what the feed returned from everyone but admins, so a
+// binding can't be used to read whatever the URL serves.
+func visibleOracle(c *gin.Context, binding *models.PoolOracle) *models.PoolOracle {
+	if isGroupAdmin(c) {
+		return binding
+	}
+	redacted := *binding
+	redacted.LastValue = ""
+	if redacted.LastError != "" {
+		redacted.LastError = services.ErrOracleCheckFailed.Error()
+	}
+	return &redacted
+}
+
+func (h *OracleHandler) Get(c *gin.Context) {
+	binding, err := h.oracleService.GetOracle(c.Param("id"), c.Param("pid"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "pool has no oracle"})
+		return
+	}
+	c.JSON(http.StatusOK, visibleOracle(c, binding))
+}
+
+func (h *OracleHandler) Bind(c *gin.Context) {
+	var req services.BindOracleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	binding, err := h.oracleService.BindOracle(c.Param("id"), c.Param("pid"), userID, isGroupAdmin(c), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, visibleOracle(c, binding))
+}
+
+func (h *OracleHandler) Unbind(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	if err := h.oracleService.UnbindOracle(c.Param("id"), c.Param("pid"), userID, isGroupAdmin(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "oracle removed"})
+}
+
+// Check polls the oracle right away. The pool_resolved broadcast is sent by
+// the service, since scheduled checks need it too.
+func (h *OracleHandler) Check(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	binding, resolved, err := h.oracleService.CheckNow(c.Request.Context(), c.Param("id"), c.Param("pid"), userID, isGroupAdmin(c))
+	if err != nil {
+		if errors.Is(err, services.ErrOracleCheckFailed) && !isGroupAdmin(c) {
+			err = services.ErrOracleCheckFailed
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"resolved": resolved,
+		"oracle":   visibleOracle(c, binding),
+	})
+}
@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"io"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
@@ -39,7 +41,7 @@ func (h *PoolHandler) Create(c *gin.Context) {
 		return
 	}
 
-	h.hub.BroadcastToGroup(groupID, services.WSEvent{
+	h.poolService.BroadcastPoolEvent(h.hub, pool.ID, services.WSEvent{
 		Type:    "pool_created",
 		Payload: pool,
 	})
@@ -47,11 +49,126 @@ func (h *PoolHandler) Create(c *gin.Context) {
 	c.JSON(http.StatusCreated, pool)
 }
 
+func (h *PoolHandler) CreatePoll(c *gin.Context) {
+	var req services.CreatePoolRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pool, err := h.poolService.CreatePoll(c.Param("id"), middleware.GetUserID(c), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.poolService.BroadcastPoolEvent(h.hub, pool.ID, services.WSEvent{
+		Type:    "pool_created",
+		Payload: pool,
+	})
+
+	c.JSON(http.StatusCreated, pool)
+}
+
+// CreateSpread opens a spread pool with one option on each side of the line.
+func (h *PoolHandler) CreateSpread(c *gin.Context) {
+	var req services.CreateSpreadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pool, err := h.poolService.CreateSpread(c.Param("id"), middleware.GetUserID(c), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.poolService.BroadcastPoolEvent(h.hub, pool.ID, services.WSEvent{
+		Type:    "pool_created",
+		Payload: pool,
+	})
+
+	c.JSON(http.StatusCreated, pool)
+}
+
+// maxImportFileSize bounds fixture uploads; a full season schedule is a few KB.
+const maxImportFileSize = 1 << 20
+
+// Import bulk-creates pools from an uploaded CSV or .ics fixture file.
+// Pass ?dry_run=true to preview the pools without creating them.
+func (h *PoolHandler) Import(c *gin.Context) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	if fileHeader.Size > maxImportFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file too large (max 1MB)"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxImportFileSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+		return
+	}
+
+	format := c.PostForm("format")
+	if format == "" {
+		format = services.DetectImportFormat(fileHeader.Filename, data)
+	}
+
+	var defaultOptions []string
+	for _, o := range strings.Split(c.PostForm("options"), ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			defaultOptions = append(defaultOptions, o)
+		}
+	}
+
+	groupID := c.Param("id")
+	userID := middleware.GetUserID(c)
+	dryRun := c.Query("dry_run") == "true" || c.PostForm("dry_run") == "true"
+
+	result, err := h.poolService.ImportPools(groupID, userID, services.ImportPoolsRequest{
+		Format:         format,
+		Data:           data,
+		DefaultOptions: defaultOptions,
+		DryRun:         dryRun,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if dryRun {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+
+	for i := range result.Pools {
+		h.hub.BroadcastToGroup(groupID, services.WSEvent{
+			Type:    "pool_created",
+			Payload: result.Pools[i],
+		})
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
 func (h *PoolHandler) List(c *gin.Context) {
 	groupID := c.Param("id")
+	userID := middleware.GetUserID(c)
 	status := c.Query("status")
 
-	pools, err := h.poolService.GetGroupPools(groupID, status)
+	pools, err := h.poolService.GetGroupPools(groupID, userID, status)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -62,7 +179,7 @@ func (h *PoolHandler) List(c *gin.Context) {
 
 func (h *PoolHandler) Get(c *gin.Context) {
 	poolID := c.Param("pid")
-	pool, err := h.poolService.GetPool(poolID)
+	pool, err := h.poolService.GetPool(poolID, middleware.GetUserID(c))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "pool not found"})
 		return
@@ -86,9 +203,7 @@ func (h *PoolHandler) PlaceBet(c *gin.Context) {
 		return
 	}
 
-	// Get group ID for broadcast
-	groupID, _ := h.poolService.GetPoolGroupID(poolID)
-	h.hub.BroadcastToGroup(groupID, services.WSEvent{
+	h.poolService.BroadcastPoolEvent(h.hub, poolID, services.WSEvent{
 		Type: "bet_placed",
 		Payload: gin.H{
 			"pool_id": poolID,
@@ -100,6 +215,67 @@ func (h *PoolHandler) PlaceBet(c *gin.Context) {
 	c.JSON(http.StatusCreated, bet)
 }
 
+// Seed adds bonus points to a pool's pot, from the creator's balance or (for
+// admins) the group treasury.
+func (h *PoolHandler) Seed(c *gin.Context) {
+	var req services.SeedPoolRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	poolID := c.Param("pid")
+	member := middleware.GetGroupMember(c)
+	isAdmin := member != nil && member.Role == "admin"
+
+	seed, approval, err := h.poolService.SeedPool(c.Param("id"), poolID, middleware.GetUserID(c), isAdmin, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if approval != nil {
+		pendingApproval(c, h.hub, h.groupService, approval)
+		return
+	}
+
+	pool, _ := h.poolService.GetPool(poolID, "")
+	h.poolService.BroadcastPoolEvent(h.hub, poolID, services.WSEvent{
+		Type:    "pool_seeded",
+		Payload: pool,
+	})
+
+	c.JSON(http.StatusCreated, seed)
+}
+
+type VoteRequest struct {
+	OptionID string `json:"option_id" binding:"required"`
+}
+
+// Vote casts or changes a member's vote in a poll and pushes the new tally to
+// everyone watching.
+func (h *PoolHandler) Vote(c *gin.Context) {
+	var req VoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	poolID := c.Param("pid")
+	bet, err := h.poolService.PlaceBet(poolID, middleware.GetUserID(c), services.PlaceBetRequest{OptionID: req.OptionID})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pool, _ := h.poolService.GetPool(poolID, "")
+	h.poolService.BroadcastPoolEvent(h.hub, poolID, services.WSEvent{
+		Type:    "vote_cast",
+		Payload: pool,
+	})
+
+	c.JSON(http.StatusOK, bet)
+}
+
 func (h *PoolHandler) Lock(c *gin.Context) {
 	poolID := c.Param("pid")
 	userID := middleware.GetUserID(c)
@@ -111,8 +287,7 @@ func (h *PoolHandler) Lock(c *gin.Context) {
 		return
 	}
 
-	groupID, _ := h.poolService.GetPoolGroupID(poolID)
-	h.hub.BroadcastToGroup(groupID, services.WSEvent{
+	h.poolService.BroadcastPoolEvent(h.hub, poolID, services.WSEvent{
 		Type:    "pool_locked",
 		Payload: gin.H{"pool_id": poolID},
 	})
@@ -121,7 +296,8 @@ func (h *PoolHandler) Lock(c *gin.Context) {
 }
 
 type ResolveRequest struct {
-	WinningOptionID string `json:"winning_option_id" binding:"required"`
+	WinningOptionID string   `json:"winning_option_id"` // may be left out when every option is a push
+	PushOptionIDs   []string `json:"push_option_ids"`   // options whose bets are refunded instead of lost
 }
 
 func (h *PoolHandler) Resolve(c *gin.Context) {
@@ -130,21 +306,24 @@ func (h *PoolHandler) Resolve(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if req.WinningOptionID == "" && len(req.PushOptionIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "winning_option_id is required unless every option is a push"})
+		return
+	}
 
 	poolID := c.Param("pid")
 	userID := middleware.GetUserID(c)
 	member := middleware.GetGroupMember(c)
 	isAdmin := member != nil && member.Role == "admin"
 
-	if err := h.poolService.ResolvePool(poolID, req.WinningOptionID, userID, isAdmin); err != nil {
+	if err := h.poolService.ResolvePoolWithPushes(poolID, req.WinningOptionID, req.PushOptionIDs, userID, isAdmin); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Get the updated pool to broadcast full results
-	pool, _ := h.poolService.GetPool(poolID)
-	groupID, _ := h.poolService.GetPoolGroupID(poolID)
-	h.hub.BroadcastToGroup(groupID, services.WSEvent{
+	pool, _ := h.poolService.GetPool(poolID, "")
+	h.poolService.BroadcastPoolEvent(h.hub, poolID, services.WSEvent{
 		Type:    "pool_resolved",
 		Payload: pool,
 	})
@@ -152,6 +331,33 @@ func (h *PoolHandler) Resolve(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "pool resolved"})
 }
 
+// ResolveSpread settles a spread pool from the final score; the winning side
+// (or a push) is worked out against the line.
+func (h *PoolHandler) ResolveSpread(c *gin.Context) {
+	var req services.ResolveSpreadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	poolID := c.Param("pid")
+	member := middleware.GetGroupMember(c)
+	isAdmin := member != nil && member.Role == "admin"
+
+	if err := h.poolService.ResolveSpread(poolID, middleware.GetUserID(c), isAdmin, req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pool, _ := h.poolService.GetPool(poolID, "")
+	h.poolService.BroadcastPoolEvent(h.hub, poolID, services.WSEvent{
+		Type:    "pool_resolved",
+		Payload: pool,
+	})
+
+	c.JSON(http.StatusOK, pool)
+}
+
 func (h *PoolHandler) Cancel(c *gin.Context) {
 	poolID := c.Param("pid")
 	userID := middleware.GetUserID(c)
@@ -163,11 +369,33 @@ func (h *PoolHandler) Cancel(c *gin.Context) {
 		return
 	}
 
-	groupID, _ := h.poolService.GetPoolGroupID(poolID)
-	h.hub.BroadcastToGroup(groupID, services.WSEvent{
+	h.poolService.BroadcastPoolEvent(h.hub, poolID, services.WSEvent{
 		Type:    "pool_cancelled",
 		Payload: gin.H{"pool_id": poolID},
 	})
 
 	c.JSON(http.StatusOK, gin.H{"message": "pool cancelled, all bets refunded"})
 }
+
+func (h *PoolHandler) UpdateParticipants(c *gin.Context) {
+	var req services.UpdateParticipantsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	poolID := c.Param("pid")
+	pool, err := h.poolService.UpdateParticipants(poolID, middleware.GetUserID(c), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Newly invited members learn about the pool the same way as on creation
+	h.poolService.BroadcastPoolEvent(h.hub, poolID, services.WSEvent{
+		Type:    "pool_updated",
+		Payload: pool,
+	})
+
+	c.JSON(http.StatusOK, pool)
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/codyseavey/bets/middleware"
+	"github.com/codyseavey/bets/services"
+)
+
+type RaffleHandler struct {
+	raffleService *services.RaffleService
+	poolService   *services.PoolService
+	hub           *services.Hub
+}
+
+func NewRaffleHandler(raffleService *services.RaffleService, poolService *services.PoolService, hub *services.Hub) *RaffleHandler {
+	return &RaffleHandler{
+		raffleService: raffleService,
+		poolService:   poolService,
+		hub:           hub,
+	}
+}
+
+// visiblePool makes sure the raffle pool in the URL is in this group and the
+// caller is allowed to see it.
+func (h *RaffleHandler) visiblePool(c *gin.Context) bool {
+	pool, err := h.poolService.GetPool(c.Param("pid"), middleware.GetUserID(c))
+	if err != nil || pool.GroupID != c.Param("id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "pool not found"})
+		return false
+	}
+	return true
+}
+
+func (h *RaffleHandler) Create(c *gin.Context) {
+	var req services.CreateRaffleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pool, err := h.raffleService.CreateRaffle(c.Param("id"), middleware.GetUserID(c), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.poolService.BroadcastPoolEvent(h.hub, pool.ID, services.WSEvent{
+		Type:    "pool_created",
+		Payload: pool,
+	})
+
+	c.JSON(http.StatusCreated, pool)
+}
+
+func (h *RaffleHandler) Get(c *gin.Context) {
+	if !h.visiblePool(c) {
+		return
+	}
+
+	raffle, err := h.raffleService.GetRaffle(c.Param("pid"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "raffle not found"})
+		return
+	}
+	c.JSON(http.StatusOK, raffle)
+}
+
+func (h *RaffleHandler) BuyTickets(c *gin.Context) {
+	if !h.visiblePool(c) {
+		return
+	}
+
+	var req services.BuyTicketsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	poolID := c.Param("pid")
+	userID := middleware.GetUserID(c)
+
+	bet, err := h.raffleService.BuyTickets(poolID, userID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.poolService.BroadcastPoolEvent(h.hub, poolID, services.WSEvent{
+		Type: "bet_placed",
+		Payload: gin.H{
+			"pool_id": poolID,
+			"user_id": userID,
+			"bet_id":  bet.ID,
+		},
+	})
+
+	c.JSON(http.StatusOK, bet)
+}
+
+func (h *RaffleHandler) Draw(c *gin.Context) {
+	if !h.visiblePool(c) {
+		return
+	}
+
+	poolID := c.Param("pid")
+	member := middleware.GetGroupMember(c)
+	isAdmin := member != nil && member.Role == "admin"
+
+	raffle, err := h.raffleService.DrawWinner(poolID, middleware.GetUserID(c), isAdmin)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pool, _ := h.poolService.GetPool(poolID, "")
+	h.poolService.BroadcastPoolEvent(h.hub, poolID, services.WSEvent{
+		Type:    "pool_resolved",
+		Payload: pool,
+	})
+
+	c.JSON(http.StatusOK, raffle)
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/codyseavey/bets/middleware"
+	"github.com/codyseavey/bets/services"
+)
+
+type SeasonHandler struct {
+	seasonService *services.SeasonService
+	hub           *services.Hub
+}
+
+func NewSeasonHandler(seasonService *services.SeasonService, hub *services.Hub) *SeasonHandler {
+	return &SeasonHandler{
+		seasonService: seasonService,
+		hub:           hub,
+	}
+}
+
+func (h *SeasonHandler) List(c *gin.Context) {
+	seasons, err := h.seasonService.GetSeasons(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, seasons)
+}
+
+func (h *SeasonHandler) Get(c *gin.Context) {
+	season, err := h.seasonService.GetSeason(c.Param("sid"))
+	if err != nil || season.GroupID != c.Param("id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "season not found"})
+		return
+	}
+	c.JSON(http.StatusOK, season)
+}
+
+func (h *SeasonHandler) Close(c *gin.Context) {
+	var req services.CloseSeasonRequest
+	// Body is optional; without one the season gets a default name
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	groupID := c.Param("id")
+	season, err := h.seasonService.CloseSeason(groupID, middleware.GetUserID(c), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.hub.BroadcastToGroup(groupID, services.WSEvent{
+		Type:    "season_closed",
+		Payload: season,
+	})
+
+	c.JSON(http.StatusCreated, season)
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/codyseavey/bets/middleware"
+	"github.com/codyseavey/bets/services"
+)
+
+type SettlementHandler struct {
+	settlementService *services.SettlementService
+	hub               *services.Hub
+}
+
+func NewSettlementHandler(settlementService *services.SettlementService, hub *services.Hub) *SettlementHandler {
+	return &SettlementHandler{
+		settlementService: settlementService,
+		hub:               hub,
+	}
+}
+
+// Preview shows who would pay whom if the season in progress ended now.
+func (h *SettlementHandler) Preview(c *gin.Context) {
+	settlement, err := h.settlementService.PreviewSettlement(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, settlement)
+}
+
+func (h *SettlementHandler) Get(c *gin.Context) {
+	settlement, err := h.settlementService.GetSettlement(c.Param("id"), c.Param("sid"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, settlement)
+}
+
+func (h *SettlementHandler) Create(c *gin.Context) {
+	groupID := c.Param("id")
+	settlement, err := h.settlementService.CreateSettlement(groupID, c.Param("sid"), middleware.GetUserID(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.hub.BroadcastToGroup(groupID, services.WSEvent{
+		Type:    "settlement_created",
+		Payload: settlement,
+	})
+	c.JSON(http.StatusCreated, settlement)
+}
+
+func (h *SettlementHandler) MarkPaid(c *gin.Context) {
+	h.markPaid(c, true)
+}
+
+func (h *SettlementHandler) MarkUnpaid(c *gin.Context) {
+	h.markPaid(c, false)
+}
+
+func (h *SettlementHandler) markPaid(c *gin.Context, paid bool) {
+	groupID := c.Param("id")
+	payment, err := h.settlementService.MarkPaymentPaid(groupID, c.Param("sid"), c.Param("spid"), middleware.GetUserID(c), paid)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.hub.BroadcastToGroup(groupID, services.WSEvent{
+		Type:    "settlement_payment_updated",
+		Payload: payment,
+	})
+	c.JSON(http.StatusOK, payment)
+}
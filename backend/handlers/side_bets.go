@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/codyseavey/bets/middleware"
+	"github.com/codyseavey/bets/models"
+	"github.com/codyseavey/bets/services"
+)
+
+type SideBetHandler struct {
+	sideBetService *services.SideBetService
+	hub            *services.Hub
+}
+
+func NewSideBetHandler(sideBetService *services.SideBetService, hub *services.Hub) *SideBetHandler {
+	return &SideBetHandler{
+		sideBetService: sideBetService,
+		hub:            hub,
+	}
+}
+
+// notify sends a side bet event to just the invited members; the rest of the
+// group never sees it.
+func (h *SideBetHandler) notify(eventType string, sideBet *models.SideBet) {
+	userIDs := make([]string, 0, len(sideBet.Members))
+	for _, m := range sideBet.Members {
+		userIDs = append(userIDs, m.UserID)
+	}
+	h.hub.SendToUsers(sideBet.GroupID, userIDs, services.WSEvent{
+		Type:    eventType,
+		Payload: sideBet,
+	})
+}
+
+func (h *SideBetHandler) Create(c *gin.Context) {
+	var req services.CreateSideBetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sideBet, err := h.sideBetService.CreateSideBet(c.Param("id"), c.Param("pid"), middleware.GetUserID(c), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.notify("side_bet_created", sideBet)
+	c.JSON(http.StatusCreated, sideBet)
+}
+
+// List returns the caller's side bets in the group; ?pool_id= narrows it to
+// one pool.
+func (h *SideBetHandler) List(c *gin.Context) {
+	sideBets, err := h.sideBetService.GetUserSideBets(c.Param("id"), middleware.GetUserID(c), c.Query("pool_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, sideBets)
+}
+
+func (h *SideBetHandler) Get(c *gin.Context) {
+	sideBet, err := h.sideBetService.GetSideBet(c.Param("sbid"), middleware.GetUserID(c))
+	if err != nil || sideBet.GroupID != c.Param("id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "side bet not found"})
+		return
+	}
+	c.JSON(http.StatusOK, sideBet)
+}
+
+func (h *SideBetHandler) Join(c *gin.Context) {
+	var req services.JoinSideBetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sideBet, err := h.sideBetService.JoinSideBet(c.Param("sbid"), middleware.GetUserID(c), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.notify("side_bet_joined", sideBet)
+	c.JSON(http.StatusOK, sideBet)
+}
+
+func (h *SideBetHandler) Cancel(c *gin.Context) {
+	sideBet, err := h.sideBetService.CancelSideBet(c.Param("sbid"), middleware.GetUserID(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.notify("side_bet_cancelled", sideBet)
+	c.JSON(http.StatusOK, sideBet)
+}
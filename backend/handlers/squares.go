@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/codyseavey/bets/middleware"
+	"github.com/codyseavey/bets/models"
+	"github.com/codyseavey/bets/services"
+)
+
+type SquaresHandler struct {
+	squaresService *services.SquaresService
+	hub            *services.Hub
+}
+
+func NewSquaresHandler(squaresService *services.SquaresService, hub *services.Hub) *SquaresHandler {
+	return &SquaresHandler{
+		squaresService: squaresService,
+		hub:            hub,
+	}
+}
+
+// inGroup makes sure the squares game in the URL belongs to the group the
+// caller was authorized against.
+func (h *SquaresHandler) inGroup(c *gin.Context) bool {
+	groupID, err := h.squaresService.GetGameGroupID(c.Param("sid"))
+	if err != nil || groupID != c.Param("id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "squares game not found"})
+		return false
+	}
+	return true
+}
+
+func (h *SquaresHandler) broadcast(groupID, eventType string, game *models.SquaresGame) {
+	h.hub.BroadcastToGroup(groupID, services.WSEvent{
+		Type:    eventType,
+		Payload: game,
+	})
+}
+
+func (h *SquaresHandler) Create(c *gin.Context) {
+	var req services.CreateSquaresRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	groupID := c.Param("id")
+	game, err := h.squaresService.CreateGame(groupID, middleware.GetUserID(c), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.broadcast(groupID, "squares_created", game)
+	c.JSON(http.StatusCreated, game)
+}
+
+func (h *SquaresHandler) List(c *gin.Context) {
+	games, err := h.squaresService.GetGroupGames(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, games)
+}
+
+func (h *SquaresHandler) Get(c *gin.Context) {
+	game, err := h.squaresService.GetGame(c.Param("sid"))
+	if err != nil || game.GroupID != c.Param("id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "squares game not found"})
+		return
+	}
+	c.JSON(http.StatusOK, game)
+}
+
+func (h *SquaresHandler) Claim(c *gin.Context) {
+	if !h.inGroup(c) {
+		return
+	}
+
+	var req services.ClaimSquaresRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	game, err := h.squaresService.ClaimSquares(c.Param("sid"), middleware.GetUserID(c), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	event := "squares_claimed"
+	if game.Status == models.SquaresStatusDrawn {
+		event = "squares_drawn"
+	}
+	h.broadcast(c.Param("id"), event, game)
+	c.JSON(http.StatusOK, game)
+}
+
+func (h *SquaresHandler) ReportQuarter(c *gin.Context) {
+	if !h.inGroup(c) {
+		return
+	}
+
+	var req services.ReportQuarterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	game, err := h.squaresService.ReportQuarter(c.Param("sid"), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.broadcast(c.Param("id"), "squares_quarter", game)
+	c.JSON(http.StatusOK, game)
+}
+
+func (h *SquaresHandler) Cancel(c *gin.Context) {
+	if !h.inGroup(c) {
+		return
+	}
+
+	gameID := c.Param("sid")
+	if err := h.squaresService.CancelGame(gameID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.hub.BroadcastToGroup(c.Param("id"), services.WSEvent{
+		Type:    "squares_cancelled",
+		Payload: gin.H{"game_id": gameID},
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "squares game cancelled, squares refunded"})
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/codyseavey/bets/middleware"
+	"github.com/codyseavey/bets/services"
+)
+
+type TransferHandler struct {
+	transferService *services.TransferService
+	hub             *services.Hub
+}
+
+func NewTransferHandler(transferService *services.TransferService, hub *services.Hub) *TransferHandler {
+	return &TransferHandler{
+		transferService: transferService,
+		hub:             hub,
+	}
+}
+
+func (h *TransferHandler) Send(c *gin.Context) {
+	var req services.SendTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	groupID := c.Param("id")
+	transfer, err := h.transferService.SendPoints(groupID, middleware.GetUserID(c), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Balances are public but the note is between the two of them
+	h.hub.BroadcastToGroup(groupID, services.WSEvent{
+		Type: "points_transferred",
+		Payload: gin.H{
+			"from_user_id": transfer.FromUserID,
+			"to_user_id":   transfer.ToUserID,
+			"amount":       transfer.Amount,
+		},
+	})
+
+	c.JSON(http.StatusCreated, transfer)
+}
+
+// List returns the transfers the caller sent or received.
+func (h *TransferHandler) List(c *gin.Context) {
+	transfers, err := h.transferService.GetUserTransfers(c.Param("id"), middleware.GetUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, transfers)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
@@ -17,8 +18,23 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "audit":
+			os.Exit(runAudit(os.Args[2:]))
+		case "verify":
+			os.Exit(runVerify(os.Args[2:]))
+		}
+	}
+
 	cfg := config.Load()
 	db := storage.InitDB(cfg.DBPath)
+	if err := services.BackfillLedger(db); err != nil {
+		log.Fatalf("Failed to open points ledger: %v", err)
+	}
+	if err := services.BackfillHashChain(db); err != nil {
+		log.Fatalf("Failed to chain points log: %v", err)
+	}
 
 	// Services
 	authService := services.NewAuthService(db, cfg)
@@ -26,11 +42,40 @@ func main() {
 	poolService := services.NewPoolService(db)
 	hub := services.NewHub()
 	go hub.Run()
+	challengeService := services.NewChallengeService(db, poolService)
+	bracketService := services.NewBracketService(db)
+	leagueService := services.NewLeagueService(db)
+	squaresService := services.NewSquaresService(db)
+	raffleService := services.NewRaffleService(db, poolService)
+	seasonService := services.NewSeasonService(db)
+	settlementService := services.NewSettlementService(db)
+	sideBetService := services.NewSideBetService(db)
+	transferService := services.NewTransferService(db)
+	loanService := services.NewLoanService(db)
+	bailoutService := services.NewBailoutService(db)
+	oracleService := services.NewOracleService(db, poolService, hub)
+	go oracleService.Run(context.Background())
+	allowanceService := services.NewAllowanceService(db, hub)
+	go allowanceService.Run(context.Background())
 
 	// Handlers
 	authHandler := handlers.NewAuthHandler(authService, cfg.BaseURL)
 	groupHandler := handlers.NewGroupHandler(groupService, hub)
 	poolHandler := handlers.NewPoolHandler(poolService, groupService, hub)
+	oracleHandler := handlers.NewOracleHandler(oracleService)
+	hookHandler := handlers.NewHookHandler(poolService, hub)
+	challengeHandler := handlers.NewChallengeHandler(challengeService, hub)
+	bracketHandler := handlers.NewBracketHandler(bracketService, hub)
+	leagueHandler := handlers.NewLeagueHandler(leagueService, hub)
+	squaresHandler := handlers.NewSquaresHandler(squaresService, hub)
+	raffleHandler := handlers.NewRaffleHandler(raffleService, poolService, hub)
+	seasonHandler := handlers.NewSeasonHandler(seasonService, hub)
+	settlementHandler := handlers.NewSettlementHandler(settlementService, hub)
+	sideBetHandler := handlers.NewSideBetHandler(sideBetService, hub)
+	transferHandler := handlers.NewTransferHandler(transferService, hub)
+	loanHandler := handlers.NewLoanHandler(loanService, hub)
+	allowanceHandler := handlers.NewAllowanceHandler(allowanceService, groupService, hub)
+	bailoutHandler := handlers.NewBailoutHandler(bailoutService, groupService, hub)
 	leaderboardHandler := handlers.NewLeaderboardHandler(db)
 	wsHandler := handlers.NewWebSocketHandler(hub, authService, db)
 
@@ -40,7 +85,7 @@ func main() {
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{cfg.BaseURL},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", services.WebhookTimestampHeader, services.WebhookSignatureHeader},
 		AllowCredentials: true,
 	}))
 
@@ -59,6 +104,14 @@ func main() {
 		auth.POST("/logout", authHandler.Logout)
 	}
 
+	// Signed inbound webhooks (HMAC with the group's secret instead of a JWT)
+	hooks := r.Group("/api/hooks/groups/:id")
+	hooks.Use(middleware.WebhookSignatureRequired(groupService))
+	{
+		hooks.POST("/pools/:pid/lock", hookHandler.Lock)
+		hooks.POST("/pools/:pid/resolve", hookHandler.Resolve)
+	}
+
 	// Auth-required routes
 	api := r.Group("/api")
 	api.Use(middleware.AuthRequired(authService))
@@ -78,15 +131,100 @@ func main() {
 			groupRoutes.GET("/leaderboard", leaderboardHandler.GetLeaderboard)
 			groupRoutes.GET("/history", leaderboardHandler.GetHistory)
 			groupRoutes.GET("/stats", leaderboardHandler.GetStats)
+			groupRoutes.GET("/seasons", seasonHandler.List)
+			groupRoutes.GET("/seasons/:sid", seasonHandler.Get)
+
+			// Cash settlement of season results
+			groupRoutes.GET("/settlement", settlementHandler.Preview)
+			groupRoutes.GET("/seasons/:sid/settlement", settlementHandler.Get)
+			groupRoutes.POST("/seasons/:sid/settlement/payments/:spid/paid", settlementHandler.MarkPaid)
+			groupRoutes.DELETE("/seasons/:sid/settlement/payments/:spid/paid", settlementHandler.MarkUnpaid)
 
 			// Pools
 			groupRoutes.POST("/pools", poolHandler.Create)
+			groupRoutes.POST("/pools/import", poolHandler.Import)
+			groupRoutes.POST("/polls", poolHandler.CreatePoll)
+			groupRoutes.POST("/spreads", poolHandler.CreateSpread)
 			groupRoutes.GET("/pools", poolHandler.List)
 			groupRoutes.GET("/pools/:pid", poolHandler.Get)
 			groupRoutes.POST("/pools/:pid/bet", poolHandler.PlaceBet)
+			groupRoutes.POST("/pools/:pid/vote", poolHandler.Vote)
+			groupRoutes.POST("/pools/:pid/seed", poolHandler.Seed)
 			groupRoutes.POST("/pools/:pid/lock", poolHandler.Lock)
 			groupRoutes.POST("/pools/:pid/resolve", poolHandler.Resolve)
+			groupRoutes.POST("/pools/:pid/spread/resolve", poolHandler.ResolveSpread)
 			groupRoutes.POST("/pools/:pid/cancel", poolHandler.Cancel)
+			groupRoutes.PUT("/pools/:pid/participants", poolHandler.UpdateParticipants)
+			groupRoutes.GET("/pools/:pid/oracle", oracleHandler.Get)
+			groupRoutes.PUT("/pools/:pid/oracle", oracleHandler.Bind)
+			groupRoutes.DELETE("/pools/:pid/oracle", oracleHandler.Unbind)
+			groupRoutes.POST("/pools/:pid/oracle/check", oracleHandler.Check)
+
+			// Raffles (pools with type "raffle")
+			groupRoutes.POST("/raffles", raffleHandler.Create)
+			groupRoutes.GET("/pools/:pid/raffle", raffleHandler.Get)
+			groupRoutes.POST("/pools/:pid/tickets", raffleHandler.BuyTickets)
+			groupRoutes.POST("/pools/:pid/draw", raffleHandler.Draw)
+
+			// Head-to-head challenges
+			groupRoutes.POST("/challenges", challengeHandler.Create)
+			groupRoutes.GET("/challenges", challengeHandler.List)
+			groupRoutes.GET("/challenges/:cid", challengeHandler.Get)
+			groupRoutes.POST("/challenges/:cid/accept", challengeHandler.Accept)
+			groupRoutes.POST("/challenges/:cid/decline", challengeHandler.Decline)
+			groupRoutes.POST("/challenges/:cid/withdraw", challengeHandler.Withdraw)
+			groupRoutes.POST("/challenges/:cid/result", challengeHandler.ReportResult)
+
+			// Private side bets riding on a pool
+			groupRoutes.POST("/pools/:pid/side-bets", sideBetHandler.Create)
+			groupRoutes.GET("/side-bets", sideBetHandler.List)
+			groupRoutes.GET("/side-bets/:sbid", sideBetHandler.Get)
+			groupRoutes.POST("/side-bets/:sbid/join", sideBetHandler.Join)
+			groupRoutes.POST("/side-bets/:sbid/cancel", sideBetHandler.Cancel)
+
+			// Member-to-member point transfers
+			groupRoutes.POST("/transfers", transferHandler.Send)
+			groupRoutes.GET("/transfers", transferHandler.List)
+
+			// Member loans
+			groupRoutes.POST("/loans", loanHandler.Offer)
+			groupRoutes.GET("/loans", loanHandler.List)
+			groupRoutes.GET("/loans/:lid", loanHandler.Get)
+			groupRoutes.POST("/loans/:lid/accept", loanHandler.Accept)
+			groupRoutes.POST("/loans/:lid/decline", loanHandler.Decline)
+			groupRoutes.POST("/loans/:lid/withdraw", loanHandler.Withdraw)
+			groupRoutes.POST("/loans/:lid/repay", loanHandler.Repay)
+			groupRoutes.POST("/loans/:lid/forgive", loanHandler.Forgive)
+
+			// Recurring allowance
+			groupRoutes.GET("/allowance", allowanceHandler.Get)
+			groupRoutes.POST("/allowance/claim", allowanceHandler.Claim)
+
+			// Bailouts for busted members
+			groupRoutes.GET("/bailout", bailoutHandler.Get)
+			groupRoutes.POST("/bailout/claim", bailoutHandler.Claim)
+
+			// Tamper check on the points log
+			groupRoutes.GET("/ledger/verify", groupHandler.VerifyChain)
+
+			// Bracket tournaments
+			groupRoutes.GET("/brackets", bracketHandler.List)
+			groupRoutes.GET("/brackets/:bid", bracketHandler.Get)
+			groupRoutes.GET("/brackets/:bid/entry", bracketHandler.GetEntry)
+			groupRoutes.PUT("/brackets/:bid/entry", bracketHandler.SubmitEntry)
+			groupRoutes.GET("/brackets/:bid/leaderboard", bracketHandler.Leaderboard)
+
+			// Pick'em and survivor leagues
+			groupRoutes.GET("/leagues", leagueHandler.List)
+			groupRoutes.GET("/leagues/:lid", leagueHandler.Get)
+			groupRoutes.GET("/leagues/:lid/standings", leagueHandler.Standings)
+			groupRoutes.GET("/leagues/:lid/picks", leagueHandler.MyPicks)
+			groupRoutes.PUT("/leagues/:lid/rounds/:rid/picks", leagueHandler.SubmitPicks)
+
+			// Squares
+			groupRoutes.GET("/squares", squaresHandler.List)
+			groupRoutes.GET("/squares/:sid", squaresHandler.Get)
+			groupRoutes.POST("/squares/:sid/claim", squaresHandler.Claim)
 
 			// Admin-only
 			admin := groupRoutes.Group("")
@@ -94,8 +232,34 @@ func main() {
 			{
 				admin.PUT("", groupHandler.Update)
 				admin.POST("/grant", groupHandler.GrantPoints)
+				admin.POST("/grant/bulk", groupHandler.BulkGrant)
+				admin.GET("/grants", groupHandler.ListGrantBatches)
+				admin.POST("/grants/:bid/undo", groupHandler.UndoGrantBatch)
+				admin.PUT("/grant-approvals/threshold", groupHandler.SetApproveAbove)
+				admin.GET("/grant-approvals", groupHandler.ListGrantApprovals)
+				admin.POST("/grant-approvals/:aid/approve", groupHandler.ApproveGrant)
+				admin.POST("/grant-approvals/:aid/reject", groupHandler.RejectGrant)
+				admin.PUT("/members/:uid/role", groupHandler.SetMemberRole)
+				admin.GET("/ledger/reconcile", groupHandler.Reconcile)
+				admin.PUT("/allowance", allowanceHandler.Set)
+				admin.DELETE("/allowance", allowanceHandler.Delete)
+				admin.PUT("/bailout", bailoutHandler.SetPolicy)
+				admin.DELETE("/bailout", bailoutHandler.DeletePolicy)
 				admin.DELETE("/members/:uid", groupHandler.KickMember)
 				admin.POST("/regenerate-invite", groupHandler.RegenerateInvite)
+				admin.POST("/webhook-secret", groupHandler.RegenerateWebhookSecret)
+				admin.DELETE("/webhook-secret", groupHandler.DisableWebhooks)
+				admin.POST("/brackets", bracketHandler.Create)
+				admin.POST("/brackets/:bid/lock", bracketHandler.Lock)
+				admin.POST("/brackets/:bid/results", bracketHandler.RecordResult)
+				admin.POST("/brackets/:bid/cancel", bracketHandler.Cancel)
+				admin.POST("/leagues", leagueHandler.Create)
+				admin.POST("/leagues/:lid/rounds", leagueHandler.AddRound)
+				admin.POST("/squares", squaresHandler.Create)
+				admin.POST("/squares/:sid/quarters", squaresHandler.ReportQuarter)
+				admin.POST("/squares/:sid/cancel", squaresHandler.Cancel)
+				admin.POST("/seasons/close", seasonHandler.Close)
+				admin.POST("/seasons/:sid/settlement", settlementHandler.Create)
 				admin.DELETE("", groupHandler.Delete)
 			}
 		}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/codyseavey/bets/services"
+)
+
+// maxWebhookBodySize keeps signature verification from buffering huge bodies.
+const maxWebhookBodySize = 64 << 10
+
+// WebhookSignatureRequired verifies the HMAC signature on inbound hooks for
+// the group in the :id URL param. The body is buffered for verification and
+// then restored so handlers can bind it as usual.
+func WebhookSignatureRequired(groupService *services.GroupService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBodySize+1))
+		if err != nil || len(body) > maxWebhookBodySize {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		secret, err := groupService.GetWebhookSecret(c.Param("id"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid webhook signature"})
+			return
+		}
+
+		timestamp := c.GetHeader(services.WebhookTimestampHeader)
+		signature := c.GetHeader(services.WebhookSignatureHeader)
+		now := time.Now()
+		if err := services.VerifyWebhookSignature(
+			secret,
+			c.Request.Method,
+			c.Request.URL.Path,
+			timestamp,
+			signature,
+			body,
+			now,
+		); err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		if err := groupService.RecordWebhookDelivery(c.Param("id"), timestamp, signature, now); err != nil {
+			if errors.Is(err, services.ErrWebhookReplayed) {
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to record webhook"})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+type AllowanceSchedule string
+
+const (
+	AllowanceDaily  AllowanceSchedule = "daily"
+	AllowanceWeekly AllowanceSchedule = "weekly"
+)
+
+// Allowance is a group's recurring stipend from the treasury. Periods are
+// UTC days, or UTC weeks starting on Weekday. Scheduled allowances are paid
+// to every member at the start of each period; claimable ones wait for each
+// member to claim them at some point during the period.
+type Allowance struct {
+	GroupID   string            `json:"group_id" gorm:"primaryKey;type:text"`
+	Amount    int               `json:"amount" gorm:"not null"`
+	Schedule  AllowanceSchedule `json:"schedule" gorm:"type:text;not null"`
+	Weekday   time.Weekday      `json:"weekday"` // weekly only; 0 is Sunday
+	Claimable bool              `json:"claimable" gorm:"not null;default:false"`
+	CreatedBy string            `json:"created_by" gorm:"type:text;not null"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// AllowancePayment records that a member got their allowance for a period.
+// The unique index makes paying the same period twice impossible, however
+// many times the scheduler or a claim races.
+type AllowancePayment struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:text"`
+	GroupID   string    `json:"group_id" gorm:"uniqueIndex:idx_allowance_period;type:text;not null"`
+	UserID    string    `json:"user_id" gorm:"uniqueIndex:idx_allowance_period;type:text;not null"`
+	Period    string    `json:"period" gorm:"uniqueIndex:idx_allowance_period;type:text;not null"` // start date of the period, YYYY-MM-DD
+	Amount    int       `json:"amount" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// BailoutPolicy lets busted members reset their balance. A member whose
+// balance, counting anything still riding on unsettled bets, is below
+// Threshold can claim a bailout that tops them up to ResetTo, at most
+// MaxPerSeason times per season. Each bailout is a "bailout" PointsLog entry.
+type BailoutPolicy struct {
+	GroupID      string    `json:"group_id" gorm:"primaryKey;type:text"`
+	Threshold    int       `json:"threshold" gorm:"not null"`
+	ResetTo      int       `json:"reset_to" gorm:"not null"`
+	MaxPerSeason int       `json:"max_per_season" gorm:"not null"`
+	CreatedBy    string    `json:"created_by" gorm:"type:text;not null"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
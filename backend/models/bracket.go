@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+type BracketStatus string
+
+const (
+	BracketStatusOpen      BracketStatus = "open"
+	BracketStatusLocked    BracketStatus = "locked"
+	BracketStatusCompleted BracketStatus = "completed"
+	BracketStatusCancelled BracketStatus = "cancelled"
+)
+
+// Bracket is a single-elimination tournament. Teams are seeded in order, so
+// round 1 game i is Teams[2i] vs Teams[2i+1] and the winners of games 2i and
+// 2i+1 meet in game i of the next round.
+type Bracket struct {
+	ID          string        `json:"id" gorm:"primaryKey;type:text"`
+	GroupID     string        `json:"group_id" gorm:"index;type:text;not null"`
+	Title       string        `json:"title" gorm:"type:text;not null"`
+	Description string        `json:"description" gorm:"type:text"`
+	Status      BracketStatus `json:"status" gorm:"type:text;not null;default:open"`
+	Rounds      int           `json:"rounds" gorm:"not null"`
+	EntryFee    int           `json:"entry_fee" gorm:"not null;default:0"`
+	ScoringBase int           `json:"scoring_base" gorm:"not null;default:1"` // a correct pick in round r scores ScoringBase * 2^(r-1)
+	LockAt      *time.Time    `json:"lock_at"`
+	CreatedBy   string        `json:"created_by" gorm:"type:text;not null"`
+	CompletedAt *time.Time    `json:"completed_at"`
+	CreatedAt   time.Time     `json:"created_at"`
+	Creator     User          `json:"creator,omitempty" gorm:"foreignKey:CreatedBy"`
+	Games       []BracketGame `json:"games,omitempty" gorm:"foreignKey:BracketID"`
+}
+
+// BracketGame is one matchup. Round 1 games have both teams set when the
+// bracket is created; later rounds fill in as results are recorded.
+type BracketGame struct {
+	BracketID string `json:"bracket_id" gorm:"primaryKey;type:text"`
+	Round     int    `json:"round" gorm:"primaryKey"`
+	Position  int    `json:"position" gorm:"primaryKey"`
+	TeamA     string `json:"team_a" gorm:"type:text"`
+	TeamB     string `json:"team_b" gorm:"type:text"`
+	Winner    string `json:"winner" gorm:"type:text"`
+}
+
+// BracketEntry is one member's full set of predictions for a bracket.
+type BracketEntry struct {
+	ID            string        `json:"id" gorm:"primaryKey;type:text"`
+	BracketID     string        `json:"bracket_id" gorm:"uniqueIndex:idx_bracket_user;type:text;not null"`
+	UserID        string        `json:"user_id" gorm:"uniqueIndex:idx_bracket_user;type:text;not null"`
+	Score         int           `json:"score" gorm:"not null;default:0"`
+	PossibleScore int           `json:"possible_score" gorm:"not null;default:0"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+	User          User          `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Picks         []BracketPick `json:"picks,omitempty" gorm:"foreignKey:EntryID"`
+}
+
+type BracketPick struct {
+	EntryID  string `json:"-" gorm:"primaryKey;type:text"`
+	Round    int    `json:"round" gorm:"primaryKey"`
+	Position int    `json:"position" gorm:"primaryKey"`
+	Team     string `json:"team" gorm:"type:text;not null"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+type ChallengeStatus string
+
+const (
+	ChallengeStatusPending   ChallengeStatus = "pending"
+	ChallengeStatusAccepted  ChallengeStatus = "accepted"
+	ChallengeStatusDeclined  ChallengeStatus = "declined"
+	ChallengeStatusWithdrawn ChallengeStatus = "withdrawn"
+	// The challenge's pool was cancelled before the opponent answered
+	ChallengeStatusCancelled ChallengeStatus = "cancelled"
+)
+
+// ChallengeResultCancel is the result a side reports to call the challenge
+// off rather than name a winner.
+const ChallengeResultCancel = "cancel"
+
+// Challenge is a 1v1 bet proposed by one member to another. It's backed by a
+// challenge-type Pool with one option per side; the challenger's stake is
+// escrowed on creation and the opponent's on acceptance. Once accepted, the
+// outcome (resolved/cancelled) lives on the pool. The challenger created the
+// pool, so it's settled either by an admin who isn't one of the two sides or
+// by both sides reporting the same result (an option ID or
+// ChallengeResultCancel).
+type Challenge struct {
+	ID                 string          `json:"id" gorm:"primaryKey;type:text"`
+	GroupID            string          `json:"group_id" gorm:"index;type:text;not null"`
+	PoolID             string          `json:"pool_id" gorm:"uniqueIndex;type:text;not null"`
+	ChallengerID       string          `json:"challenger_id" gorm:"index;type:text;not null"`
+	OpponentID         string          `json:"opponent_id" gorm:"index;type:text;not null"`
+	ChallengerOptionID string          `json:"challenger_option_id" gorm:"type:text;not null"`
+	OpponentOptionID   string          `json:"opponent_option_id" gorm:"type:text;not null"`
+	ChallengerStake    int             `json:"challenger_stake" gorm:"not null"`
+	OpponentStake      int             `json:"opponent_stake" gorm:"not null"`
+	Status             ChallengeStatus `json:"status" gorm:"type:text;not null;default:pending"`
+	ChallengerResult   string          `json:"challenger_result" gorm:"type:text"`
+	OpponentResult     string          `json:"opponent_result" gorm:"type:text"`
+	RespondedAt        *time.Time      `json:"responded_at"`
+	CreatedAt          time.Time       `json:"created_at"`
+	Pool               Pool            `json:"pool,omitempty" gorm:"foreignKey:PoolID"`
+	Challenger         User            `json:"challenger,omitempty" gorm:"foreignKey:ChallengerID"`
+	Opponent           User            `json:"opponent,omitempty" gorm:"foreignKey:OpponentID"`
+}
@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+// GrantBatch is one bulk grant from the treasury to every member matching a
+// filter. Each member's admin_grant PointsLog entry references the batch, and
+// undoing it takes the points back with grant_undo entries.
+type GrantBatch struct {
+	ID           string     `json:"id" gorm:"primaryKey;type:text"`
+	GroupID      string     `json:"group_id" gorm:"index;type:text;not null"`
+	CreatedBy    string     `json:"created_by" gorm:"type:text;not null"`
+	Amount       int        `json:"amount" gorm:"not null"` // per member
+	Note         string     `json:"note" gorm:"type:text"`
+	Role         string     `json:"role,omitempty" gorm:"type:text"` // filter: only members with this role
+	BalanceBelow *int       `json:"balance_below,omitempty"`         // filter: only members with less than this
+	Recipients   int        `json:"recipients" gorm:"not null"`
+	Total        int        `json:"total" gorm:"not null"`
+	UndoneBy     string     `json:"undone_by,omitempty" gorm:"type:text"`
+	UndoneAt     *time.Time `json:"undone_at"`
+	Reclaimed    int        `json:"reclaimed" gorm:"not null;default:0"` // what the undo actually took back
+	CreatedAt    time.Time  `json:"created_at"`
+	Creator      User       `json:"creator,omitempty" gorm:"foreignKey:CreatedBy"`
+}
+
+type GrantApprovalKind string
+
+const (
+	GrantApprovalGrant     GrantApprovalKind = "grant"      // single member grant
+	GrantApprovalBulk      GrantApprovalKind = "bulk_grant" // bulk grant with filters
+	GrantApprovalUndo      GrantApprovalKind = "grant_undo" // undoing a bulk grant batch
+	GrantApprovalThreshold GrantApprovalKind = "threshold"  // loosening the approval threshold itself
+	GrantApprovalSeed      GrantApprovalKind = "pool_seed"  // seeding a pool from the treasury
+	GrantApprovalAllowance GrantApprovalKind = "allowance"  // setting the group's recurring allowance
+	GrantApprovalBailout   GrantApprovalKind = "bailout"    // setting the group's bailout policy
+	GrantApprovalPromote   GrantApprovalKind = "promote"    // making a member an admin
+)
+
+type GrantApprovalStatus string
+
+const (
+	GrantApprovalPending   GrantApprovalStatus = "pending"
+	GrantApprovalApproved  GrantApprovalStatus = "approved"
+	GrantApprovalRejected  GrantApprovalStatus = "rejected"
+	GrantApprovalCancelled GrantApprovalStatus = "cancelled" // withdrawn by the admin who asked
+)
+
+// GrantApproval is a grant, or anything else that can mint points or hand out
+// admin rights, held back by the group's two-admin rule (see
+// Group.ApproveAbove). Nothing takes effect until an admin other than the
+// requester approves it; the resulting PointsLog entries, GrantBatch or
+// PoolSeed are linked through ReferenceID.
+type GrantApproval struct {
+	ID           string              `json:"id" gorm:"primaryKey;type:text"`
+	GroupID      string              `json:"group_id" gorm:"index;type:text;not null"`
+	Kind         GrantApprovalKind   `json:"kind" gorm:"type:text;not null"`
+	RequestedBy  string              `json:"requested_by" gorm:"type:text;not null"`
+	UserID       string              `json:"user_id,omitempty" gorm:"type:text"`  // grant: the recipient; promote: the member
+	Amount       int                 `json:"amount"`                              // grant and bulk_grant: per member; threshold: the new threshold; pool_seed and allowance: the amount; bailout: reset_to
+	Note         string              `json:"note" gorm:"type:text"`               // grant and bulk_grant
+	Role         string              `json:"role,omitempty" gorm:"type:text"`     // bulk_grant filter
+	BalanceBelow *int                `json:"balance_below,omitempty"`             // bulk_grant filter
+	BatchID      string              `json:"batch_id,omitempty" gorm:"type:text"` // grant_undo: the batch to undo
+	PoolID       string              `json:"pool_id,omitempty" gorm:"type:text"`  // pool_seed: the pool
+	Settings     string              `json:"settings,omitempty" gorm:"type:text"` // allowance and bailout: the requested settings, as JSON
+	Total        int                 `json:"total"`                               // points minted (or destroyed, if negative) as of the request
+	Status       GrantApprovalStatus `json:"status" gorm:"type:text;not null;default:pending"`
+	ReviewedBy   string              `json:"reviewed_by,omitempty" gorm:"type:text"`
+	ReviewedAt   *time.Time          `json:"reviewed_at"`
+	ReferenceID  string              `json:"reference_id,omitempty" gorm:"type:text"` // batch created once approved
+	CreatedAt    time.Time           `json:"created_at"`
+	Requester    User                `json:"requester,omitempty" gorm:"foreignKey:RequestedBy"`
+}
@@ -7,6 +7,10 @@ type Group struct {
 	Name          string        `json:"name" gorm:"type:text;not null"`
 	InviteCode    string        `json:"invite_code" gorm:"uniqueIndex;type:text;not null"`
 	DefaultPoints int           `json:"default_points" gorm:"not null;default:1000"`
+	WebhookSecret string        `json:"-" gorm:"type:text"`                         // HMAC key for inbound hooks; empty means hooks are disabled
+	TransferLimit int           `json:"transfer_limit" gorm:"not null;default:500"` // most points a member can send others per 24 hours; 0 disables transfers
+	ApproveAbove  int           `json:"approve_above" gorm:"not null;default:0"`    // grants above this, and any negative grant, need a second admin's approval; 0 turns the rule off
+	CashRate      int           `json:"cash_rate" gorm:"not null;default:0"`        // points per dollar when settling seasons in cash; 0 means the group doesn't
 	CreatedBy     string        `json:"created_by" gorm:"type:text;not null"`
 	Creator       User          `json:"creator,omitempty" gorm:"foreignKey:CreatedBy"`
 	Members       []GroupMember `json:"members,omitempty" gorm:"foreignKey:GroupID"`
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+type LeagueMode string
+
+const (
+	LeagueModePickem   LeagueMode = "pickem"   // pick a winner in every pool of each round
+	LeagueModeSurvivor LeagueMode = "survivor" // pick one team per round, never the same team twice
+)
+
+// League groups existing pools into numbered rounds (usually weeks) and
+// ranks members by how their picks fared once those pools resolve. Picks
+// carry no points; standings are independent of balances.
+type League struct {
+	ID          string        `json:"id" gorm:"primaryKey;type:text"`
+	GroupID     string        `json:"group_id" gorm:"index;type:text;not null"`
+	Title       string        `json:"title" gorm:"type:text;not null"`
+	Description string        `json:"description" gorm:"type:text"`
+	Mode        LeagueMode    `json:"mode" gorm:"type:text;not null"`
+	CreatedBy   string        `json:"created_by" gorm:"type:text;not null"`
+	CreatedAt   time.Time     `json:"created_at"`
+	Creator     User          `json:"creator,omitempty" gorm:"foreignKey:CreatedBy"`
+	Rounds      []LeagueRound `json:"rounds,omitempty" gorm:"foreignKey:LeagueID"`
+}
+
+type LeagueRound struct {
+	ID        string            `json:"id" gorm:"primaryKey;type:text"`
+	LeagueID  string            `json:"league_id" gorm:"uniqueIndex:idx_league_round;type:text;not null"`
+	Number    int               `json:"number" gorm:"uniqueIndex:idx_league_round;not null"`
+	Title     string            `json:"title" gorm:"type:text"`
+	CreatedAt time.Time         `json:"created_at"`
+	Pools     []LeagueRoundPool `json:"pools,omitempty" gorm:"foreignKey:RoundID"`
+}
+
+// LeagueRoundPool attaches a pool to a round. A pool belongs to at most one
+// round per league.
+type LeagueRoundPool struct {
+	RoundID string `json:"round_id" gorm:"primaryKey;type:text"`
+	PoolID  string `json:"pool_id" gorm:"primaryKey;type:text"`
+	Pool    Pool   `json:"pool,omitempty" gorm:"foreignKey:PoolID"`
+}
+
+// LeaguePick is a member's pick for one pool in a round. Survivor leagues
+// allow a single pick per round.
+type LeaguePick struct {
+	ID        string     `json:"id" gorm:"primaryKey;type:text"`
+	LeagueID  string     `json:"league_id" gorm:"index;type:text;not null"`
+	RoundID   string     `json:"round_id" gorm:"uniqueIndex:idx_pick_round_user_pool;type:text;not null"`
+	UserID    string     `json:"user_id" gorm:"uniqueIndex:idx_pick_round_user_pool;type:text;not null"`
+	PoolID    string     `json:"pool_id" gorm:"uniqueIndex:idx_pick_round_user_pool;type:text;not null"`
+	OptionID  string     `json:"option_id" gorm:"type:text;not null"`
+	CreatedAt time.Time  `json:"created_at"`
+	Option    PoolOption `json:"option,omitempty" gorm:"foreignKey:OptionID"`
+}
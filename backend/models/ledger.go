@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// LedgerEntry is one leg of a double-entry points movement. Every movement
+// writes two or more entries sharing a TxnID whose amounts sum to zero, so an
+// account's balance is just the sum of its entries. Accounts are named
+// "member:<user id>", "treasury", or "escrow:<kind>:<id>" for whatever is
+// holding stakes (a pool, bracket, squares game or side bet).
+type LedgerEntry struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:text"`
+	GroupID   string    `json:"group_id" gorm:"index:idx_ledger_group_account;type:text;not null"`
+	TxnID     string    `json:"txn_id" gorm:"index;type:text;not null"`
+	Account   string    `json:"account" gorm:"index:idx_ledger_group_account;type:text;not null"`
+	Amount    int       `json:"amount" gorm:"not null"`  // positive adds to the account, negative takes from it
+	LogID     string    `json:"log_id" gorm:"type:text"` // the PointsLog row describing the movement, if any
+	CreatedAt time.Time `json:"created_at"`
+}
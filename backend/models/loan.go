@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type LoanStatus string
+
+const (
+	LoanStatusOffered   LoanStatus = "offered"   // waiting on the borrower
+	LoanStatusActive    LoanStatus = "active"    // principal paid out, repayment outstanding
+	LoanStatusRepaid    LoanStatus = "repaid"    // paid back in full
+	LoanStatusForgiven  LoanStatus = "forgiven"  // lender wrote off what was left
+	LoanStatusDeclined  LoanStatus = "declined"  // borrower turned the offer down
+	LoanStatusWithdrawn LoanStatus = "withdrawn" // lender pulled the offer before it was accepted
+)
+
+// Loan is points lent by one member to another. The lender offers terms and
+// nothing moves until the borrower accepts. Interest is a flat percentage of
+// the principal, added once, so Owed never changes after the offer. Active
+// loans are paid back by hand or automatically out of the borrower's pool
+// winnings, oldest loan first. DueAt doesn't change any of that; an active
+// loan past it is flagged Overdue so the lender knows to chase it.
+type Loan struct {
+	ID              string     `json:"id" gorm:"primaryKey;type:text"`
+	GroupID         string     `json:"group_id" gorm:"index;type:text;not null"`
+	LenderID        string     `json:"lender_id" gorm:"index;type:text;not null"`
+	BorrowerID      string     `json:"borrower_id" gorm:"index;type:text;not null"`
+	Principal       int        `json:"principal" gorm:"not null"`
+	InterestPercent int        `json:"interest_percent" gorm:"not null;default:0"`
+	Owed            int        `json:"owed" gorm:"not null"` // principal plus interest
+	Repaid          int        `json:"repaid" gorm:"not null;default:0"`
+	DueAt           *time.Time `json:"due_at"`
+	Overdue         bool       `json:"overdue" gorm:"-"`
+	Note            string     `json:"note" gorm:"type:text"`
+	Status          LoanStatus `json:"status" gorm:"type:text;not null;default:offered"`
+	AcceptedAt      *time.Time `json:"accepted_at"`
+	ClosedAt        *time.Time `json:"closed_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	Lender          User       `json:"lender,omitempty" gorm:"foreignKey:LenderID"`
+	Borrower        User       `json:"borrower,omitempty" gorm:"foreignKey:BorrowerID"`
+}
+
+func (l *Loan) AfterFind(tx *gorm.DB) error {
+	l.Overdue = l.Status == LoanStatusActive && l.DueAt != nil && time.Now().After(*l.DueAt)
+	return nil
+}
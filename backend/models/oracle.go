@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+type OracleStatus string
+
+const (
+	OracleStatusActive   OracleStatus = "active"
+	OracleStatusResolved OracleStatus = "resolved"
+)
+
+// PoolOracle binds a pool to an external data source that resolves it
+// automatically once the source reports a winning option.
+type PoolOracle struct {
+	ID                  string       `json:"id" gorm:"primaryKey;type:text"`
+	PoolID              string       `json:"pool_id" gorm:"uniqueIndex;type:text;not null"`
+	Type                string       `json:"type" gorm:"type:text;not null"` // e.g. "http_json"
+	URL                 string       `json:"url" gorm:"type:text;not null"`
+	Selector            string       `json:"selector" gorm:"type:text;not null"`
+	PollIntervalSeconds int          `json:"poll_interval_seconds" gorm:"not null;default:300"`
+	Status              OracleStatus `json:"status" gorm:"type:text;not null;default:active"`
+	LastCheckedAt       *time.Time   `json:"last_checked_at"`
+	LastValue           string       `json:"last_value" gorm:"type:text"`
+	LastError           string       `json:"last_error" gorm:"type:text"`
+	CreatedBy           string       `json:"created_by" gorm:"type:text;not null"`
+	CreatedAt           time.Time    `json:"created_at"`
+}
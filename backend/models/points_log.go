@@ -1,15 +1,57 @@
 package models
 
-import "time"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type PointsLogType string
 
 const (
 	PointsLogInitial    PointsLogType = "initial"
 	PointsLogAdminGrant PointsLogType = "admin_grant"
+	PointsLogGrantUndo  PointsLogType = "grant_undo" // reverses a bulk admin_grant batch
+	PointsLogForfeit    PointsLogType = "forfeit"    // balance returned to the treasury when a member is removed
+	PointsLogAuditFix   PointsLogType = "audit_fix"  // zero-amount note of a balance corrected by `bets audit --fix`
 	PointsLogBetPlaced  PointsLogType = "bet_placed"
 	PointsLogBetWon     PointsLogType = "bet_won"
 	PointsLogBetRefund  PointsLogType = "bet_refund"
+
+	PointsLogRaffleTopUp PointsLogType = "raffle_top_up" // more tickets on an existing raffle bet; not another bet on the member's record
+
+	PointsLogPoolSeeded PointsLogType = "pool_seeded"
+	PointsLogBountyWon  PointsLogType = "bounty_won"
+	PointsLogSeedRefund PointsLogType = "seed_refund"
+
+	PointsLogBracketEntry  PointsLogType = "bracket_entry"
+	PointsLogBracketWon    PointsLogType = "bracket_won"
+	PointsLogBracketRefund PointsLogType = "bracket_refund"
+
+	PointsLogSquaresClaim  PointsLogType = "squares_claim"
+	PointsLogSquaresWon    PointsLogType = "squares_won"
+	PointsLogSquaresRefund PointsLogType = "squares_refund"
+	PointsLogSquaresDraw   PointsLogType = "squares_draw" // zero-amount audit record of the digit draw
+
+	PointsLogSideBetStake  PointsLogType = "side_bet_stake"
+	PointsLogSideBetWon    PointsLogType = "side_bet_won"
+	PointsLogSideBetRefund PointsLogType = "side_bet_refund"
+
+	PointsLogTransferSent     PointsLogType = "transfer_sent"
+	PointsLogTransferReceived PointsLogType = "transfer_received"
+
+	PointsLogLoanGiven     PointsLogType = "loan_given"     // lender's side of the principal
+	PointsLogLoanReceived  PointsLogType = "loan_received"  // borrower's side of the principal
+	PointsLogLoanRepayment PointsLogType = "loan_repayment" // borrower paying back
+	PointsLogLoanCollected PointsLogType = "loan_collected" // lender getting paid back
+
+	PointsLogAllowance PointsLogType = "allowance"
+	PointsLogBailout   PointsLogType = "bailout"
+
+	PointsLogSeasonReset PointsLogType = "season_reset"
 )
 
 type PointsLog struct {
@@ -20,6 +62,60 @@ type PointsLog struct {
 	Type        PointsLogType `json:"type" gorm:"type:text;not null"`
 	ReferenceID string        `json:"reference_id" gorm:"type:text"`
 	Note        string        `json:"note" gorm:"type:text"`
+	Actor       string        `json:"actor,omitempty" gorm:"type:text"` // set when an automated source (oracle, webhook) acted instead of UserID
 	CreatedAt   time.Time     `json:"created_at"`
 	User        User          `json:"user,omitempty" gorm:"foreignKey:UserID"`
+
+	// Each group's entries form a hash chain: Seq counts up from 1, PrevHash
+	// is the previous entry's Hash, and Hash covers PrevHash plus this
+	// entry's contents. Editing or deleting a row breaks the chain.
+	Seq      int64  `json:"seq" gorm:"index"`
+	PrevHash string `json:"prev_hash" gorm:"type:text"`
+	Hash     string `json:"hash" gorm:"type:text"`
+}
+
+// PointsChainHead is the latest link in a group's PointsLog chain, so entries
+// deleted from the end show up too.
+type PointsChainHead struct {
+	GroupID string `json:"group_id" gorm:"primaryKey;type:text"`
+	Seq     int64  `json:"seq" gorm:"not null"`
+	Hash    string `json:"hash" gorm:"type:text;not null"`
+}
+
+// ChainHash computes the entry's hash from PrevHash and its contents.
+func (l *PointsLog) ChainHash() string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%q|%d|%q|%q|%q|%d|%q|%q|%q|%q|%d",
+		l.PrevHash, l.Seq, l.ID, l.GroupID, l.UserID, l.Amount, l.Type, l.ReferenceID, l.Note, l.Actor, l.CreatedAt.UnixNano())))
+	return hex.EncodeToString(sum[:])
+}
+
+// BeforeCreate links a new entry onto the end of its group's chain, inside
+// whatever transaction is writing it.
+func (l *PointsLog) BeforeCreate(tx *gorm.DB) error {
+	if l.CreatedAt.IsZero() {
+		l.CreatedAt = time.Now()
+	}
+
+	db := tx.Session(&gorm.Session{NewDB: true})
+	var head PointsChainHead
+	if err := db.Where("group_id = ?", l.GroupID).Limit(1).Find(&head).Error; err != nil {
+		return err
+	}
+	l.Seq = head.Seq + 1
+	l.PrevHash = head.Hash
+	l.Hash = l.ChainHash()
+
+	if head.GroupID == "" {
+		return db.Create(&PointsChainHead{GroupID: l.GroupID, Seq: l.Seq, Hash: l.Hash}).Error
+	}
+	result := db.Model(&PointsChainHead{}).
+		Where("group_id = ? AND seq = ?", l.GroupID, head.Seq).
+		Updates(map[string]interface{}{"seq": l.Seq, "hash": l.Hash})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("points log chain moved while writing, try again")
+	}
+	return nil
 }
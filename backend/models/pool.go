@@ -11,24 +11,74 @@ const (
 	PoolStatusCancelled PoolStatus = "cancelled"
 )
 
+type PoolType string
+
+const (
+	PoolTypeStandard  PoolType = "standard"
+	PoolTypeChallenge PoolType = "challenge" // 1v1 head-to-head, stakes placed via Challenge
+	PoolTypeRaffle    PoolType = "raffle"    // tickets bought with points, winner drawn via Raffle
+	PoolTypePoll      PoolType = "poll"      // zero-stake vote; Bets hold votes with no points
+	PoolTypeSpread    PoolType = "spread"    // two sides against a points line, resolved from the final score
+)
+
 type Pool struct {
-	ID          string       `json:"id" gorm:"primaryKey;type:text"`
-	GroupID     string       `json:"group_id" gorm:"index;type:text;not null"`
-	Title       string       `json:"title" gorm:"type:text;not null"`
-	Description string       `json:"description" gorm:"type:text"`
-	Status      PoolStatus   `json:"status" gorm:"type:text;not null;default:open"`
-	CreatedBy   string       `json:"created_by" gorm:"type:text;not null"`
-	ResolvedAt  *time.Time   `json:"resolved_at"`
-	CreatedAt   time.Time    `json:"created_at"`
-	Creator     User         `json:"creator,omitempty" gorm:"foreignKey:CreatedBy"`
-	Options     []PoolOption `json:"options,omitempty" gorm:"foreignKey:PoolID"`
-	Bets        []Bet        `json:"bets,omitempty" gorm:"foreignKey:PoolID"`
-	Group       Group        `json:"-" gorm:"foreignKey:GroupID"`
+	ID           string            `json:"id" gorm:"primaryKey;type:text"`
+	GroupID      string            `json:"group_id" gorm:"index;type:text;not null"`
+	Title        string            `json:"title" gorm:"type:text;not null"`
+	Description  string            `json:"description" gorm:"type:text"`
+	Type         PoolType          `json:"type" gorm:"type:text;not null;default:standard"`
+	Status       PoolStatus        `json:"status" gorm:"type:text;not null;default:open"`
+	CreatedBy    string            `json:"created_by" gorm:"type:text;not null"`
+	Restricted   bool              `json:"restricted" gorm:"not null;default:false"` // only PoolParticipants can see and bet
+	LockAt       *time.Time        `json:"lock_at"`
+	ResolvedAt   *time.Time        `json:"resolved_at"`
+	CreatedAt    time.Time         `json:"created_at"`
+	Creator      User              `json:"creator,omitempty" gorm:"foreignKey:CreatedBy"`
+	Options      []PoolOption      `json:"options,omitempty" gorm:"foreignKey:PoolID"`
+	Participants []PoolParticipant `json:"participants,omitempty" gorm:"foreignKey:PoolID"`
+	Raffle       *Raffle           `json:"raffle,omitempty" gorm:"foreignKey:PoolID"`
+	Spread       *PoolSpread       `json:"spread,omitempty" gorm:"foreignKey:PoolID"`
+	Seeds        []PoolSeed        `json:"seeds,omitempty" gorm:"foreignKey:PoolID"`
+	Bets         []Bet             `json:"bets,omitempty" gorm:"foreignKey:PoolID"`
+	Group        Group             `json:"-" gorm:"foreignKey:GroupID"`
 
 	// Virtual fields populated by handlers
-	WinningOptionID string `json:"winning_option_id,omitempty" gorm:"-"`
-	TotalPot        int    `json:"total_pot" gorm:"-"`
-	BetCount        int    `json:"bet_count" gorm:"-"`
+	WinningOptionID string         `json:"winning_option_id,omitempty" gorm:"-"`
+	TotalPot        int            `json:"total_pot" gorm:"-"`
+	Bounty          int            `json:"bounty" gorm:"-"` // seeded bonus points still held for winners
+	BetCount        int            `json:"bet_count" gorm:"-"`
+	VoteCounts      map[string]int `json:"vote_counts,omitempty" gorm:"-"` // option ID -> votes, polls only
+}
+
+// PoolParticipant is a member invited to a restricted pool. The creator is
+// always included.
+type PoolParticipant struct {
+	PoolID string `json:"pool_id" gorm:"primaryKey;type:text"`
+	UserID string `json:"user_id" gorm:"primaryKey;type:text"`
+	User   User   `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+type PoolSeedStatus string
+
+const (
+	PoolSeedHeld     PoolSeedStatus = "held"
+	PoolSeedPaid     PoolSeedStatus = "paid"
+	PoolSeedRefunded PoolSeedStatus = "refunded"
+)
+
+// PoolSeed is bonus points added to a pool's pot by its creator or from the
+// group treasury. Seeds are split among winners on top of their payout and go
+// back to the seeder if nobody wins. Treasury seeds come out of the group
+// treasury account rather than anyone's balance, and go back there if unused.
+type PoolSeed struct {
+	ID           string         `json:"id" gorm:"primaryKey;type:text"`
+	PoolID       string         `json:"pool_id" gorm:"index;type:text;not null"`
+	SeededBy     string         `json:"seeded_by" gorm:"type:text;not null"`
+	FromTreasury bool           `json:"from_treasury" gorm:"not null;default:false"`
+	Amount       int            `json:"amount" gorm:"not null"`
+	Status       PoolSeedStatus `json:"status" gorm:"type:text;not null;default:held"`
+	CreatedAt    time.Time      `json:"created_at"`
+	Seeder       User           `json:"seeder,omitempty" gorm:"foreignKey:SeededBy"`
 }
 
 type PoolOption struct {
@@ -36,6 +86,7 @@ type PoolOption struct {
 	PoolID      string `json:"pool_id" gorm:"index;type:text;not null"`
 	Label       string `json:"label" gorm:"type:text;not null"`
 	Description string `json:"description" gorm:"type:text"`
+	Push        bool   `json:"push,omitempty" gorm:"not null;default:false"` // set at resolution when this option's bets were refunded
 }
 
 type Bet struct {
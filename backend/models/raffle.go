@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Raffle holds the draw state for a raffle pool. The seed is committed to at
+// creation by publishing SeedHash (hex SHA-256 of Seed) and only revealed once
+// the winner is drawn, so anyone can check the draw wasn't rigged.
+type Raffle struct {
+	PoolID        string     `json:"pool_id" gorm:"primaryKey;type:text"`
+	TicketPrice   int        `json:"ticket_price" gorm:"not null"`
+	SeedHash      string     `json:"seed_hash" gorm:"type:text;not null"`
+	Seed          string     `json:"-" gorm:"type:text;not null"`
+	RevealedSeed  string     `json:"revealed_seed,omitempty" gorm:"type:text"` // copy of Seed, set at draw time
+	TotalTickets  int        `json:"total_tickets" gorm:"not null;default:0"`  // tickets in play when drawn
+	WinningTicket int        `json:"winning_ticket,omitempty"`                 // 1-based
+	WinnerID      *string    `json:"winner_id,omitempty" gorm:"type:text"`
+	DrawnAt       *time.Time `json:"drawn_at"`
+}
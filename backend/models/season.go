@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// Season is an archived stretch of a group's history. The current season is
+// implicit: it began when the last Season ended (or when the group was
+// created) and becomes a Season row when an admin closes it.
+type Season struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:text"`
+	GroupID   string    `json:"group_id" gorm:"uniqueIndex:idx_group_season;type:text;not null"`
+	Number    int       `json:"number" gorm:"uniqueIndex:idx_group_season;not null"`
+	Name      string    `json:"name" gorm:"type:text;not null"`
+	StartedAt time.Time `json:"started_at"`
+	EndedAt   time.Time `json:"ended_at"`
+	ClosedBy  string    `json:"closed_by" gorm:"type:text;not null"`
+
+	// Group stats as they stood when the season closed
+	TotalPools               int64 `json:"total_pools"`
+	ResolvedPools            int64 `json:"resolved_pools"`
+	TotalBets                int64 `json:"total_bets"`
+	TotalMembers             int64 `json:"total_members"`
+	TotalPointsInCirculation int64 `json:"total_points_in_circulation"`
+
+	Standings []SeasonStanding `json:"standings,omitempty" gorm:"foreignKey:SeasonID"`
+}
+
+// SeasonStanding is one member's final leaderboard row for a season.
+type SeasonStanding struct {
+	SeasonID      string `json:"season_id" gorm:"primaryKey;type:text"`
+	UserID        string `json:"user_id" gorm:"primaryKey;type:text"`
+	Rank          int    `json:"rank" gorm:"not null"`
+	PointsBalance int    `json:"points_balance" gorm:"not null"`
+	TotalWins     int64  `json:"total_wins"`
+	TotalLosses   int64  `json:"total_losses"`
+	TotalBets     int64  `json:"total_bets"`
+	Bailouts      int64  `json:"bailouts"`
+	User          User   `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// SeasonStart is what a member brought into and took out of a season, keyed
+// by season number since the season in progress has no Season row yet.
+// Points is the balance they started with (everyone's reset balance when the
+// season opened, or the starting points of someone who joined partway
+// through); Forfeited is any balance returned to the treasury when they were
+// removed. Settlements measure results from these, so changing the group's
+// default points or removing a member mid-season doesn't change what anyone
+// won or lost.
+type SeasonStart struct {
+	GroupID   string `json:"group_id" gorm:"primaryKey;type:text"`
+	Season    int    `json:"season" gorm:"primaryKey"`
+	UserID    string `json:"user_id" gorm:"primaryKey;type:text"`
+	Points    int    `json:"points" gorm:"not null"`
+	Forfeited int    `json:"forfeited" gorm:"not null;default:0"`
+}
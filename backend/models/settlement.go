@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Settlement turns a closed season's point results into cash. Each member's
+// net for the season (final balance minus what they started it with) is
+// converted at Group.CashRate, and the debts are netted into as few
+// payments as possible. The rate and nets are copied in when the settlement
+// is made, so changing the group's settings later doesn't rewrite it.
+type Settlement struct {
+	ID        string              `json:"id" gorm:"primaryKey;type:text"`
+	GroupID   string              `json:"group_id" gorm:"index;type:text;not null"`
+	SeasonID  string              `json:"season_id" gorm:"uniqueIndex;type:text"` // empty for a preview of the season in progress
+	CashRate  int                 `json:"cash_rate" gorm:"not null"`
+	Unmatched int                 `json:"unmatched_cents" gorm:"not null;default:0"` // winnings (or losses) left uncovered because grants, allowances or bailouts changed the points in play
+	CreatedBy string              `json:"created_by" gorm:"type:text"`
+	CreatedAt time.Time           `json:"created_at"`
+	Nets      []SettlementNet     `json:"nets" gorm:"foreignKey:SettlementID"`
+	Payments  []SettlementPayment `json:"payments" gorm:"foreignKey:SettlementID"`
+}
+
+// SettlementNet is one member's result for the settled season.
+type SettlementNet struct {
+	SettlementID string `json:"settlement_id" gorm:"primaryKey;type:text"`
+	UserID       string `json:"user_id" gorm:"primaryKey;type:text"`
+	Points       int    `json:"points" gorm:"not null"` // won (positive) or lost over the season
+	Cents        int    `json:"cents" gorm:"not null"`  // after the exchange rate, and any scaling to make the money balance
+	User         User   `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// SettlementPayment is one "A pays B" line of a settlement. Marking it paid
+// is bookkeeping only; no points move.
+type SettlementPayment struct {
+	ID           string     `json:"id" gorm:"primaryKey;type:text"`
+	SettlementID string     `json:"settlement_id" gorm:"index;type:text;not null"`
+	FromUserID   string     `json:"from_user_id" gorm:"type:text;not null"`
+	ToUserID     string     `json:"to_user_id" gorm:"type:text;not null"`
+	Cents        int        `json:"cents" gorm:"not null"`
+	PaidAt       *time.Time `json:"paid_at"`
+	MarkedBy     string     `json:"marked_by,omitempty" gorm:"type:text"`
+	FromUser     User       `json:"from_user,omitempty" gorm:"foreignKey:FromUserID"`
+	ToUser       User       `json:"to_user,omitempty" gorm:"foreignKey:ToUserID"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+type SideBetStatus string
+
+const (
+	SideBetStatusOpen     SideBetStatus = "open"
+	SideBetStatusSettled  SideBetStatus = "settled"
+	SideBetStatusRefunded SideBetStatus = "refunded"
+)
+
+// SideBet is a private wager among a few members that rides on a pool's
+// outcome. Only the invited members can see it. Each member backs one of the
+// pool's options with their own stake, escrowed when they join; the stakes
+// are split among those who backed the winner when the pool resolves and are
+// refunded if it's cancelled.
+type SideBet struct {
+	ID        string          `json:"id" gorm:"primaryKey;type:text"`
+	GroupID   string          `json:"group_id" gorm:"index;type:text;not null"`
+	PoolID    string          `json:"pool_id" gorm:"index;type:text;not null"`
+	CreatedBy string          `json:"created_by" gorm:"type:text;not null"`
+	Terms     string          `json:"terms" gorm:"type:text;not null"`
+	Status    SideBetStatus   `json:"status" gorm:"type:text;not null;default:open"`
+	SettledAt *time.Time      `json:"settled_at"`
+	CreatedAt time.Time       `json:"created_at"`
+	Creator   User            `json:"creator,omitempty" gorm:"foreignKey:CreatedBy"`
+	Pool      Pool            `json:"pool,omitempty" gorm:"foreignKey:PoolID"`
+	Members   []SideBetMember `json:"members,omitempty" gorm:"foreignKey:SideBetID"`
+}
+
+// SideBetMember is an invited member. OptionID and Stake are set once they
+// join; invitees who never join take no part in the settlement.
+type SideBetMember struct {
+	SideBetID string     `json:"side_bet_id" gorm:"primaryKey;type:text"`
+	UserID    string     `json:"user_id" gorm:"primaryKey;type:text"`
+	OptionID  string     `json:"option_id,omitempty" gorm:"type:text"`
+	Stake     int        `json:"stake" gorm:"not null;default:0"`
+	Payout    int        `json:"payout" gorm:"not null;default:0"` // points returned at settlement, refunds included
+	JoinedAt  *time.Time `json:"joined_at"`
+	User      User       `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
@@ -0,0 +1,15 @@
+package models
+
+// PoolSpread is the line on a spread pool. Line is added to TeamA's score, so
+// "Team A -3.5" is Line -3.5: Team A covers by winning by 4 or more. Whole
+// number lines can land exactly on the margin, in which case both sides push.
+type PoolSpread struct {
+	PoolID    string  `json:"pool_id" gorm:"primaryKey;type:text"`
+	TeamA     string  `json:"team_a" gorm:"type:text;not null"`
+	TeamB     string  `json:"team_b" gorm:"type:text;not null"`
+	Line      float64 `json:"line" gorm:"not null"`
+	OptionAID string  `json:"option_a_id" gorm:"type:text;not null"` // backs TeamA covering
+	OptionBID string  `json:"option_b_id" gorm:"type:text;not null"`
+	ScoreA    *int    `json:"score_a"` // final scores, set on resolve
+	ScoreB    *int    `json:"score_b"`
+}
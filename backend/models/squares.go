@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+type SquaresStatus string
+
+const (
+	SquaresStatusOpen      SquaresStatus = "open"  // squares still being claimed
+	SquaresStatusDrawn     SquaresStatus = "drawn" // grid full, digits assigned
+	SquaresStatusCompleted SquaresStatus = "completed"
+	SquaresStatusCancelled SquaresStatus = "cancelled"
+)
+
+const (
+	SquaresGridSize = 10
+	SquaresQuarters = 4
+)
+
+// SquaresGame is a 10x10 grid for a single game between two teams. Rows
+// belong to RowTeam and columns to ColTeam. Once every square is claimed each
+// axis gets a random permutation of 0-9, and at the end of every quarter the
+// square matching the last digit of each team's score takes that quarter's
+// share of the pot.
+type SquaresGame struct {
+	ID             string           `json:"id" gorm:"primaryKey;type:text"`
+	GroupID        string           `json:"group_id" gorm:"index;type:text;not null"`
+	Title          string           `json:"title" gorm:"type:text;not null"`
+	Description    string           `json:"description" gorm:"type:text"`
+	RowTeam        string           `json:"row_team" gorm:"type:text;not null"`
+	ColTeam        string           `json:"col_team" gorm:"type:text;not null"`
+	PricePerSquare int              `json:"price_per_square" gorm:"not null"`
+	Payouts        string           `json:"payouts" gorm:"type:text;not null"` // percent of the pot per quarter, e.g. "25,25,25,25"
+	Status         SquaresStatus    `json:"status" gorm:"type:text;not null;default:open"`
+	RowDigits      string           `json:"row_digits" gorm:"type:text"` // digit for each row index, e.g. "3709182456"
+	ColDigits      string           `json:"col_digits" gorm:"type:text"`
+	DrawnAt        *time.Time       `json:"drawn_at"`
+	CreatedBy      string           `json:"created_by" gorm:"type:text;not null"`
+	CreatedAt      time.Time        `json:"created_at"`
+	Creator        User             `json:"creator,omitempty" gorm:"foreignKey:CreatedBy"`
+	Claims         []SquaresClaim   `json:"claims,omitempty" gorm:"foreignKey:GameID"`
+	Quarters       []SquaresQuarter `json:"quarters,omitempty" gorm:"foreignKey:GameID"`
+}
+
+type SquaresClaim struct {
+	GameID    string    `json:"game_id" gorm:"primaryKey;type:text"`
+	Row       int       `json:"row" gorm:"primaryKey"`
+	Col       int       `json:"col" gorm:"primaryKey"`
+	UserID    string    `json:"user_id" gorm:"index;type:text;not null"`
+	CreatedAt time.Time `json:"created_at"`
+	User      User      `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// SquaresQuarter is the settled result for one quarter.
+type SquaresQuarter struct {
+	GameID    string    `json:"game_id" gorm:"primaryKey;type:text"`
+	Quarter   int       `json:"quarter" gorm:"primaryKey"`
+	RowScore  int       `json:"row_score" gorm:"not null"`
+	ColScore  int       `json:"col_score" gorm:"not null"`
+	Row       int       `json:"row" gorm:"not null"`
+	Col       int       `json:"col" gorm:"not null"`
+	WinnerID  string    `json:"winner_id" gorm:"type:text;not null"`
+	Amount    int       `json:"amount" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+	Winner    User      `json:"winner,omitempty" gorm:"foreignKey:WinnerID"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Transfer is points sent directly from one member to another, e.g. to settle
+// a side deal or tip someone. The matching transfer_sent and
+// transfer_received PointsLog entries reference it.
+type Transfer struct {
+	ID         string    `json:"id" gorm:"primaryKey;type:text"`
+	GroupID    string    `json:"group_id" gorm:"index;type:text;not null"`
+	FromUserID string    `json:"from_user_id" gorm:"index;type:text;not null"`
+	ToUserID   string    `json:"to_user_id" gorm:"index;type:text;not null"`
+	Amount     int       `json:"amount" gorm:"not null"`
+	Note       string    `json:"note" gorm:"type:text"`
+	CreatedAt  time.Time `json:"created_at"`
+	FromUser   User      `json:"from_user,omitempty" gorm:"foreignKey:FromUserID"`
+	ToUser     User      `json:"to_user,omitempty" gorm:"foreignKey:ToUserID"`
+}
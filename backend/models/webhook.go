@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// WebhookDelivery records a signed webhook that was accepted, so the same
+// signed request can't be replayed while its timestamp is still in the
+// allowed window. Rows older than the window are pruned as new ones arrive.
+type WebhookDelivery struct {
+	GroupID   string    `json:"group_id" gorm:"primaryKey;type:text"`
+	Signature string    `json:"signature" gorm:"primaryKey;type:text"`
+	Timestamp int64     `json:"timestamp" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
@@ -0,0 +1,288 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/codyseavey/bets/models"
+)
+
+const allowanceTickInterval = time.Minute
+
+type AllowanceService struct {
+	db  *gorm.DB
+	hub *Hub
+}
+
+func NewAllowanceService(db *gorm.DB, hub *Hub) *AllowanceService {
+	return &AllowanceService{db: db, hub: hub}
+}
+
+type SetAllowanceRequest struct {
+	Amount    int                      `json:"amount" binding:"required,gt=0"`
+	Schedule  models.AllowanceSchedule `json:"schedule" binding:"required,oneof=daily weekly"`
+	Weekday   *int                     `json:"weekday" binding:"omitempty,gte=0,lte=6"` // weekly only; defaults to Monday
+	Claimable bool                     `json:"claimable"`
+}
+
+// AllowanceStatus is a group's allowance as seen by one member.
+type AllowanceStatus struct {
+	models.Allowance
+	PeriodStart time.Time `json:"period_start"`
+	NextPeriod  time.Time `json:"next_period"`
+	Paid        bool      `json:"paid"` // whether the member already has this period's allowance
+}
+
+// periodStart returns the start of the allowance period containing t.
+func periodStart(a *models.Allowance, t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if a.Schedule == models.AllowanceWeekly {
+		back := (int(day.Weekday()) - int(a.Weekday) + 7) % 7
+		day = day.AddDate(0, 0, -back)
+	}
+	return day
+}
+
+func nextPeriod(a *models.Allowance, start time.Time) time.Time {
+	if a.Schedule == models.AllowanceWeekly {
+		return start.AddDate(0, 0, 7)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// SetAllowance creates or replaces the group's allowance. Every member is
+// paid it, so if the amount times the number of members is above the group's
+// ApproveAbove threshold it waits for a second admin, and comes back as a
+// pending approval.
+func (s *AllowanceService) SetAllowance(groupID, userID string, req SetAllowanceRequest) (*models.Allowance, *models.GrantApproval, error) {
+	var group models.Group
+	if err := s.db.First(&group, "id = ?", groupID).Error; err != nil {
+		return nil, nil, fmt.Errorf("group not found")
+	}
+	var members int64
+	s.db.Model(&models.GroupMember{}).Where("group_id = ?", groupID).Count(&members)
+	total := req.Amount * int(members)
+	if needsApproval(&group, total) {
+		settings, err := json.Marshal(req)
+		if err != nil {
+			return nil, nil, err
+		}
+		approval, err := createApproval(s.db, &models.GrantApproval{
+			GroupID:     groupID,
+			Kind:        models.GrantApprovalAllowance,
+			RequestedBy: userID,
+			Amount:      req.Amount,
+			Settings:    string(settings),
+			Total:       total,
+		})
+		return nil, approval, err
+	}
+
+	allowance, err := setAllowanceTx(s.db, groupID, userID, req)
+	return allowance, nil, err
+}
+
+func setAllowanceTx(tx *gorm.DB, groupID, userID string, req SetAllowanceRequest) (*models.Allowance, error) {
+	allowance := models.Allowance{
+		GroupID:   groupID,
+		Amount:    req.Amount,
+		Schedule:  req.Schedule,
+		Claimable: req.Claimable,
+		CreatedBy: userID,
+	}
+	if req.Schedule == models.AllowanceWeekly {
+		allowance.Weekday = time.Monday
+		if req.Weekday != nil {
+			allowance.Weekday = time.Weekday(*req.Weekday)
+		}
+	}
+
+	// Keep the original CreatedAt so changing the amount doesn't push the
+	// first payout back a period
+	var existing models.Allowance
+	if err := tx.First(&existing, "group_id = ?", groupID).Error; err == nil {
+		allowance.CreatedAt = existing.CreatedAt
+	}
+	if err := tx.Save(&allowance).Error; err != nil {
+		return nil, fmt.Errorf("failed to save allowance: %w", err)
+	}
+	return &allowance, nil
+}
+
+func (s *AllowanceService) DeleteAllowance(groupID string) error {
+	result := s.db.Delete(&models.Allowance{}, "group_id = ?", groupID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("group has no allowance")
+	}
+	return nil
+}
+
+func (s *AllowanceService) GetAllowance(groupID, userID string) (*AllowanceStatus, error) {
+	var allowance models.Allowance
+	if err := s.db.First(&allowance, "group_id = ?", groupID).Error; err != nil {
+		return nil, fmt.Errorf("group has no allowance")
+	}
+
+	start := periodStart(&allowance, time.Now())
+	var paid int64
+	s.db.Model(&models.AllowancePayment{}).
+		Where("group_id = ? AND user_id = ? AND period = ?", groupID, userID, start.Format(time.DateOnly)).
+		Count(&paid)
+
+	return &AllowanceStatus{
+		Allowance:   allowance,
+		PeriodStart: start,
+		NextPeriod:  nextPeriod(&allowance, start),
+		Paid:        paid > 0,
+	}, nil
+}
+
+// Claim pays a claimable allowance for the current period to the caller.
+func (s *AllowanceService) Claim(groupID, userID string) (*models.AllowancePayment, error) {
+	var allowance models.Allowance
+	if err := s.db.First(&allowance, "group_id = ?", groupID).Error; err != nil {
+		return nil, fmt.Errorf("group has no allowance")
+	}
+	if !allowance.Claimable {
+		return nil, fmt.Errorf("this group's allowance is paid automatically")
+	}
+
+	start := periodStart(&allowance, time.Now())
+	payment, err := s.pay(&allowance, userID, start)
+	if err != nil {
+		return nil, err
+	}
+	if payment == nil {
+		return nil, fmt.Errorf("already claimed, next one available %s", nextPeriod(&allowance, start).Format(time.RFC3339))
+	}
+	return payment, nil
+}
+
+// pay credits one member's allowance for a period. It returns nil without an
+// error if they've already been paid for it.
+func (s *AllowanceService) pay(allowance *models.Allowance, userID string, start time.Time) (*models.AllowancePayment, error) {
+	tx := s.db.Begin()
+
+	if !isMemberTx(tx, allowance.GroupID, userID) {
+		tx.Rollback()
+		return nil, fmt.Errorf("not a member of this group")
+	}
+
+	period := start.Format(time.DateOnly)
+	var count int64
+	tx.Model(&models.AllowancePayment{}).
+		Where("group_id = ? AND user_id = ? AND period = ?", allowance.GroupID, userID, period).
+		Count(&count)
+	if count > 0 {
+		tx.Rollback()
+		return nil, nil
+	}
+
+	payment := &models.AllowancePayment{
+		ID:      uuid.New().String(),
+		GroupID: allowance.GroupID,
+		UserID:  userID,
+		Period:  period,
+		Amount:  allowance.Amount,
+	}
+	if err := tx.Create(payment).Error; err != nil {
+		// Lost a race with another payer for the same period
+		tx.Rollback()
+		return nil, nil
+	}
+
+	note := fmt.Sprintf("Daily allowance for %s", period)
+	if allowance.Schedule == models.AllowanceWeekly {
+		note = fmt.Sprintf("Weekly allowance for the week of %s", period)
+	}
+	if err := creditMember(tx, allowance.GroupID, userID, allowance.Amount, TreasuryAccount,
+		models.PointsLogAllowance, payment.ID, note); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	return payment, nil
+}
+
+// Run pays scheduled allowances as their periods come due until ctx is
+// cancelled.
+func (s *AllowanceService) Run(ctx context.Context) {
+	s.PayDue(time.Now())
+	ticker := time.NewTicker(allowanceTickInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.PayDue(now)
+		}
+	}
+}
+
+// PayDue pays every scheduled (non-claimable) allowance for the period
+// containing now to members who haven't had it yet. Only members who had
+// joined by the start of the period are paid, and a new allowance starts with
+// the first period after it was set up. Missed periods (e.g. while the server
+// was down) are not made up. Returns how many payments were made.
+func (s *AllowanceService) PayDue(now time.Time) int {
+	var allowances []models.Allowance
+	if err := s.db.Where("claimable = ?", false).Find(&allowances).Error; err != nil {
+		log.Printf("Failed to load allowances: %v", err)
+		return 0
+	}
+
+	total := 0
+	for i := range allowances {
+		a := &allowances[i]
+		start := periodStart(a, now)
+		if start.Before(a.CreatedAt) {
+			continue
+		}
+
+		var userIDs []string
+		s.db.Model(&models.GroupMember{}).
+			Where("group_id = ? AND joined_at <= ?", a.GroupID, start).
+			Where("user_id NOT IN (?)", s.db.Model(&models.AllowancePayment{}).Select("user_id").
+				Where("group_id = ? AND period = ?", a.GroupID, start.Format(time.DateOnly))).
+			Pluck("user_id", &userIDs)
+
+		paid := 0
+		for _, userID := range userIDs {
+			payment, err := s.pay(a, userID, start)
+			if err != nil {
+				log.Printf("Allowance for %s in group %s failed: %v", userID, a.GroupID, err)
+				continue
+			}
+			if payment != nil {
+				paid++
+			}
+		}
+
+		if paid > 0 && s.hub != nil {
+			s.hub.BroadcastToGroup(a.GroupID, WSEvent{
+				Type: "allowance_paid",
+				Payload: map[string]interface{}{
+					"amount":  a.Amount,
+					"period":  start.Format(time.DateOnly),
+					"members": paid,
+				},
+			})
+		}
+		total += paid
+	}
+	return total
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/codyseavey/bets/models"
+)
+
+func TestAllowancePeriods(t *testing.T) {
+	weekly := &models.Allowance{Schedule: models.AllowanceWeekly, Weekday: time.Monday}
+	daily := &models.Allowance{Schedule: models.AllowanceDaily}
+
+	// Thursday 2026-10-15
+	thu := time.Date(2026, 10, 15, 18, 30, 0, 0, time.UTC)
+	if got := periodStart(weekly, thu); !got.Equal(time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the week to start Monday 10-12, got %s", got)
+	}
+	if got := periodStart(daily, thu); !got.Equal(time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the day to start at midnight, got %s", got)
+	}
+	mon := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	if got := periodStart(weekly, mon); !got.Equal(mon) {
+		t.Errorf("expected Monday to start its own week, got %s", got)
+	}
+	if got := nextPeriod(weekly, periodStart(weekly, thu)); !got.Equal(mon) {
+		t.Errorf("expected the next week to start 10-19, got %s", got)
+	}
+}
+
+func TestScheduledAllowancePaysOncePerPeriod(t *testing.T) {
+	db, poolSvc, groupSvc, group, alice, bob := setupPoolTest(t)
+	svc := NewAllowanceService(db, nil)
+
+	if _, _, err := svc.SetAllowance(group.ID, alice.ID, SetAllowanceRequest{Amount: 100, Schedule: models.AllowanceWeekly}); err != nil {
+		t.Fatalf("SetAllowance failed: %v", err)
+	}
+
+	// Set up a month ago by members who have been around even longer
+	monthAgo := time.Now().AddDate(0, -1, 0)
+	db.Model(&models.Allowance{}).Where("group_id = ?", group.ID).Update("created_at", monthAgo)
+	db.Model(&models.GroupMember{}).Where("group_id = ?", group.ID).Update("joined_at", monthAgo)
+
+	carol := createTestUser(t, db, "carol", "Carol")
+	groupSvc.JoinGroup(group.InviteCode, carol.ID)
+
+	now := time.Now()
+	if paid := svc.PayDue(now); paid != 2 {
+		t.Errorf("expected Alice and Bob paid, got %d payments", paid)
+	}
+	if paid := svc.PayDue(now); paid != 0 {
+		t.Errorf("expected a second run in the same period to pay nothing, got %d", paid)
+	}
+	if got := memberBalance(t, poolSvc, group.ID, bob.ID); got != 1100 {
+		t.Errorf("expected Bob at 1100, got %d", got)
+	}
+	if got := memberBalance(t, poolSvc, group.ID, carol.ID); got != 1000 {
+		t.Errorf("expected Carol, who joined mid-week, to wait for next week, got %d", got)
+	}
+
+	// Next week everyone is paid
+	if paid := svc.PayDue(now.AddDate(0, 0, 7)); paid != 3 {
+		t.Errorf("expected 3 payments next week, got %d", paid)
+	}
+	if got := memberBalance(t, poolSvc, group.ID, bob.ID); got != 1200 {
+		t.Errorf("expected Bob at 1200, got %d", got)
+	}
+
+	status, err := svc.GetAllowance(group.ID, alice.ID)
+	if err != nil || !status.Paid {
+		t.Errorf("expected Alice's current period marked paid, got %+v, %v", status, err)
+	}
+
+	assertReconciled(t, db, group.ID)
+}
+
+func TestNewAllowanceWaitsForNextPeriod(t *testing.T) {
+	db, _, _, group, alice, _ := setupPoolTest(t)
+	svc := NewAllowanceService(db, nil)
+
+	svc.SetAllowance(group.ID, alice.ID, SetAllowanceRequest{Amount: 50, Schedule: models.AllowanceDaily})
+	if paid := svc.PayDue(time.Now()); paid != 0 {
+		t.Errorf("expected nothing paid for the period the allowance was set up in, got %d", paid)
+	}
+	if paid := svc.PayDue(time.Now().AddDate(0, 0, 1)); paid != 2 {
+		t.Errorf("expected both members paid the next day, got %d", paid)
+	}
+}
+
+func TestClaimableAllowance(t *testing.T) {
+	db, poolSvc, _, group, alice, bob := setupPoolTest(t)
+	svc := NewAllowanceService(db, nil)
+
+	if _, err := svc.Claim(group.ID, alice.ID); err == nil {
+		t.Error("expected error claiming without an allowance")
+	}
+
+	svc.SetAllowance(group.ID, alice.ID, SetAllowanceRequest{Amount: 25, Schedule: models.AllowanceDaily, Claimable: true})
+	if paid := svc.PayDue(time.Now().AddDate(0, 0, 1)); paid != 0 {
+		t.Errorf("expected the scheduler to skip claimable allowances, got %d", paid)
+	}
+
+	if _, err := svc.Claim(group.ID, bob.ID); err != nil {
+		t.Fatalf("Claim failed: %v", err)
+	}
+	if _, err := svc.Claim(group.ID, bob.ID); err == nil {
+		t.Error("expected error claiming twice in a day")
+	}
+	if got := memberBalance(t, poolSvc, group.ID, bob.ID); got != 1025 {
+		t.Errorf("expected Bob at 1025, got %d", got)
+	}
+
+	outsider := createTestUser(t, db, "dave", "Dave")
+	if _, err := svc.Claim(group.ID, outsider.ID); err == nil {
+		t.Error("expected error claiming as a non-member")
+	}
+
+	if err := svc.DeleteAllowance(group.ID); err != nil {
+		t.Fatalf("DeleteAllowance failed: %v", err)
+	}
+	if _, err := svc.Claim(group.ID, alice.ID); err == nil {
+		t.Error("expected error claiming a removed allowance")
+	}
+
+	assertReconciled(t, db, group.ID)
+}
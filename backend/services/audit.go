@@ -0,0 +1,200 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/codyseavey/bets/models"
+)
+
+// AuditActor marks PointsLog entries written by the audit command.
+const AuditActor = "audit"
+
+// BalanceDiscrepancy is a member whose stored balance doesn't match the sum of
+// their PointsLog entries since they (last) joined the group.
+type BalanceDiscrepancy struct {
+	UserID   string `json:"user_id"`
+	Name     string `json:"name"`
+	Balance  int    `json:"balance"`  // GroupMember.PointsBalance
+	Expected int    `json:"expected"` // recomputed from PointsLog
+	Fixed    bool   `json:"fixed,omitempty"`
+}
+
+// EscrowDiscrepancy is a pool whose stakes, according to PointsLog, don't add
+// up: what was placed minus what was paid out or refunded should equal the
+// bets and seeds still held by an open pool, and zero once it's settled.
+type EscrowDiscrepancy struct {
+	PoolID   string            `json:"pool_id"`
+	Title    string            `json:"title"`
+	Status   models.PoolStatus `json:"status"`
+	Held     int               `json:"held"`     // placed minus paid out, from PointsLog
+	Expected int               `json:"expected"` // outstanding bets and seeds
+}
+
+type AuditReport struct {
+	GroupID   string               `json:"group_id"`
+	GroupName string               `json:"group_name"`
+	Members   int                  `json:"members"`
+	Pools     int                  `json:"pools"`
+	Balances  []BalanceDiscrepancy `json:"balances"`
+	Escrows   []EscrowDiscrepancy  `json:"escrows"`
+}
+
+func (r *AuditReport) Clean() bool {
+	return len(r.Balances) == 0 && len(r.Escrows) == 0
+}
+
+// AuditGroup walks a group's PointsLog and compares what it says every
+// member's balance and every pool's escrow should be with what's stored.
+func AuditGroup(db *gorm.DB, groupID string) (*AuditReport, error) {
+	var group models.Group
+	if err := db.First(&group, "id = ?", groupID).Error; err != nil {
+		return nil, fmt.Errorf("group not found")
+	}
+	report := &AuditReport{
+		GroupID:   group.ID,
+		GroupName: group.Name,
+		Balances:  []BalanceDiscrepancy{},
+		Escrows:   []EscrowDiscrepancy{},
+	}
+
+	var members []models.GroupMember
+	if err := db.Where("group_id = ?", groupID).Preload("User").Order("joined_at").Find(&members).Error; err != nil {
+		return nil, err
+	}
+	report.Members = len(members)
+	for _, m := range members {
+		// Anything before the latest join belongs to an earlier membership
+		// that was closed out when they left
+		var expected int
+		db.Model(&models.PointsLog{}).
+			Where("group_id = ? AND user_id = ? AND created_at >= ?", groupID, m.UserID, m.JoinedAt).
+			Select("COALESCE(SUM(amount), 0)").Scan(&expected)
+		if expected != m.PointsBalance {
+			report.Balances = append(report.Balances, BalanceDiscrepancy{
+				UserID:   m.UserID,
+				Name:     m.User.Name,
+				Balance:  m.PointsBalance,
+				Expected: expected,
+			})
+		}
+	}
+
+	var pools []models.Pool
+	if err := db.Where("group_id = ? AND type <> ?", groupID, models.PoolTypePoll).Order("created_at").Find(&pools).Error; err != nil {
+		return nil, err
+	}
+	report.Pools = len(pools)
+
+	held := map[string]int{}
+	var rows []struct {
+		PoolID string
+		Amount int
+	}
+	db.Model(&models.PointsLog{}).
+		Select("bets.pool_id AS pool_id, -SUM(points_logs.amount) AS amount").
+		Joins("JOIN bets ON bets.id = points_logs.reference_id").
+		Where("points_logs.group_id = ? AND points_logs.type IN ?", groupID, []models.PointsLogType{
+			models.PointsLogBetPlaced, models.PointsLogRaffleTopUp, models.PointsLogBetWon, models.PointsLogBetRefund, models.PointsLogBountyWon,
+		}).
+		Group("bets.pool_id").Scan(&rows)
+	for _, r := range rows {
+		held[r.PoolID] += r.Amount
+	}
+	rows = nil
+	db.Model(&models.PointsLog{}).
+		Select("pool_seeds.pool_id AS pool_id, -SUM(points_logs.amount) AS amount").
+		Joins("JOIN pool_seeds ON pool_seeds.id = points_logs.reference_id").
+		Where("points_logs.group_id = ? AND points_logs.type IN ?", groupID, []models.PointsLogType{
+			models.PointsLogPoolSeeded, models.PointsLogSeedRefund,
+		}).
+		Group("pool_seeds.pool_id").Scan(&rows)
+	for _, r := range rows {
+		held[r.PoolID] += r.Amount
+	}
+	// Treasury seeds are logged at zero, but their bounty is paid out like any
+	// other; count them in unless they went back to the treasury
+	rows = nil
+	db.Model(&models.PoolSeed{}).
+		Select("pool_seeds.pool_id AS pool_id, SUM(pool_seeds.amount) AS amount").
+		Joins("JOIN pools ON pools.id = pool_seeds.pool_id").
+		Where("pools.group_id = ? AND pool_seeds.from_treasury = ? AND pool_seeds.status <> ?", groupID, true, models.PoolSeedRefunded).
+		Group("pool_seeds.pool_id").Scan(&rows)
+	for _, r := range rows {
+		held[r.PoolID] += r.Amount
+	}
+
+	expected, err := expectedEscrows(db, groupID)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range pools {
+		want := expected[PoolEscrow(p.ID)]
+		if held[p.ID] != want {
+			report.Escrows = append(report.Escrows, EscrowDiscrepancy{
+				PoolID:   p.ID,
+				Title:    p.Title,
+				Status:   p.Status,
+				Held:     held[p.ID],
+				Expected: want,
+			})
+		}
+	}
+
+	return report, nil
+}
+
+// FixBalances sets every mismatched balance in the report to what PointsLog
+// says it should be. Each correction is posted to the ledger against the
+// treasury and noted with a zero-amount PointsLog entry, so the log total is
+// unchanged and the member's history shows the fix. Escrow discrepancies are
+// left for a person to sort out, since there's no telling who the missing
+// points belong to.
+func FixBalances(db *gorm.DB, report *AuditReport) error {
+	tx := db.Begin()
+
+	for i := range report.Balances {
+		d := &report.Balances[i]
+		logEntry := &models.PointsLog{
+			ID:      uuid.New().String(),
+			GroupID: report.GroupID,
+			UserID:  d.UserID,
+			Amount:  0,
+			Type:    models.PointsLogAuditFix,
+			Note:    fmt.Sprintf("Balance corrected from %d to %d to match the points history", d.Balance, d.Expected),
+			Actor:   AuditActor,
+		}
+		if err := tx.Create(logEntry).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		// Bring the ledger account in line too, whatever state it was in
+		var ledger int
+		tx.Model(&models.LedgerEntry{}).
+			Where("group_id = ? AND account = ?", report.GroupID, MemberAccount(d.UserID)).
+			Select("COALESCE(SUM(amount), 0)").Scan(&ledger)
+		if ledger != d.Expected {
+			if err := postEntriesTx(tx, report.GroupID, TreasuryAccount, MemberAccount(d.UserID), d.Expected-ledger, logEntry.ID); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+		if err := tx.Model(&models.GroupMember{}).
+			Where("group_id = ? AND user_id = ?", report.GroupID, d.UserID).
+			Update("points_balance", d.Expected).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	for i := range report.Balances {
+		report.Balances[i].Fixed = true
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/codyseavey/bets/models"
+)
+
+func TestAuditCleanHistory(t *testing.T) {
+	db, poolSvc, groupSvc, group, alice, bob := setupPoolTest(t)
+	carol := createTestUser(t, db, "carol", "Carol")
+	groupSvc.JoinGroup(group.InviteCode, carol.ID)
+
+	pool, _ := poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{
+		Title:   "Coin toss",
+		Options: []string{"Heads", "Tails"},
+	})
+	poolSvc.SeedPool(group.ID, pool.ID, alice.ID, true, SeedPoolRequest{Amount: 40, FromTreasury: true})
+	poolSvc.PlaceBet(pool.ID, alice.ID, PlaceBetRequest{OptionID: pool.Options[0].ID, Points: 100})
+	poolSvc.PlaceBet(pool.ID, bob.ID, PlaceBetRequest{OptionID: pool.Options[1].ID, Points: 300})
+	poolSvc.PlaceBet(pool.ID, carol.ID, PlaceBetRequest{OptionID: pool.Options[0].ID, Points: 100})
+
+	// Carol leaves mid-pool and comes back with fresh starting points
+	groupSvc.KickMember(group.ID, carol.ID)
+	groupSvc.JoinGroup(group.InviteCode, carol.ID)
+
+	open, _ := poolSvc.CreatePool(group.ID, bob.ID, CreatePoolRequest{
+		Title:   "Still open",
+		Options: []string{"Yes", "No"},
+	})
+	poolSvc.PlaceBet(open.ID, bob.ID, PlaceBetRequest{OptionID: open.Options[0].ID, Points: 50})
+
+	if err := poolSvc.ResolvePool(pool.ID, pool.Options[0].ID, alice.ID, false); err != nil {
+		t.Fatalf("ResolvePool failed: %v", err)
+	}
+
+	report, err := AuditGroup(db, group.ID)
+	if err != nil {
+		t.Fatalf("AuditGroup failed: %v", err)
+	}
+	if !report.Clean() {
+		t.Errorf("expected a clean audit, got balances %+v, escrows %+v", report.Balances, report.Escrows)
+	}
+	if report.Members != 3 || report.Pools != 2 {
+		t.Errorf("expected 3 members and 2 pools audited, got %d and %d", report.Members, report.Pools)
+	}
+}
+
+func TestAuditFindsAndFixesDrift(t *testing.T) {
+	db, poolSvc, _, group, alice, bob := setupPoolTest(t)
+
+	pool, _ := poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{
+		Title:   "Coin toss",
+		Options: []string{"Heads", "Tails"},
+	})
+	poolSvc.PlaceBet(pool.ID, alice.ID, PlaceBetRequest{OptionID: pool.Options[0].ID, Points: 100})
+	poolSvc.PlaceBet(pool.ID, bob.ID, PlaceBetRequest{OptionID: pool.Options[1].ID, Points: 100})
+	poolSvc.ResolvePool(pool.ID, pool.Options[0].ID, alice.ID, false)
+
+	// A hand-edited balance, and a payout whose log row went missing
+	db.Model(&models.GroupMember{}).Where("group_id = ? AND user_id = ?", group.ID, bob.ID).
+		Update("points_balance", 950)
+	db.Where("group_id = ? AND user_id = ? AND type = ?", group.ID, alice.ID, models.PointsLogBetWon).
+		Delete(&models.PointsLog{})
+
+	report, err := AuditGroup(db, group.ID)
+	if err != nil {
+		t.Fatalf("AuditGroup failed: %v", err)
+	}
+	if len(report.Balances) != 2 {
+		t.Fatalf("expected 2 balance discrepancies, got %+v", report.Balances)
+	}
+	if len(report.Escrows) != 1 || report.Escrows[0].PoolID != pool.ID || report.Escrows[0].Held != 200 {
+		t.Errorf("expected the resolved pool to still hold 200 by the log, got %+v", report.Escrows)
+	}
+
+	if err := FixBalances(db, report); err != nil {
+		t.Fatalf("FixBalances failed: %v", err)
+	}
+	if got := memberBalance(t, poolSvc, group.ID, bob.ID); got != 900 {
+		t.Errorf("expected Bob restored to 900, got %d", got)
+	}
+	if got := memberBalance(t, poolSvc, group.ID, alice.ID); got != 900 {
+		t.Errorf("expected Alice set to 900 per the log, got %d", got)
+	}
+
+	after, _ := AuditGroup(db, group.ID)
+	if len(after.Balances) != 0 {
+		t.Errorf("expected balances fixed, got %+v", after.Balances)
+	}
+	reconciled, _ := Reconcile(db, group.ID)
+	for _, d := range reconciled.Drift {
+		if d.Account == MemberAccount(alice.ID) || d.Account == MemberAccount(bob.ID) {
+			t.Errorf("expected the ledger to follow the fix, got %+v", d)
+		}
+	}
+}
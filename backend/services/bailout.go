@@ -0,0 +1,210 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/codyseavey/bets/models"
+)
+
+type BailoutService struct {
+	db *gorm.DB
+}
+
+func NewBailoutService(db *gorm.DB) *BailoutService {
+	return &BailoutService{db: db}
+}
+
+type SetBailoutPolicyRequest struct {
+	Threshold    int `json:"threshold" binding:"required,gt=0"`
+	ResetTo      int `json:"reset_to" binding:"required,gt=0"`
+	MaxPerSeason int `json:"max_per_season" binding:"required,gt=0"`
+}
+
+// BailoutStatus is a group's bailout policy as it applies to one member.
+type BailoutStatus struct {
+	models.BailoutPolicy
+	Used      int64 `json:"used"` // bailouts claimed this season
+	Remaining int64 `json:"remaining"`
+	Balance   int   `json:"balance"`
+	AtStake   int   `json:"at_stake"` // points on unsettled bets, loans and seeds or sent in the last day, which count toward the threshold
+	Eligible  bool  `json:"eligible"`
+}
+
+// SetPolicy creates or replaces the group's bailout policy. At most it could
+// pay every member reset_to for each bailout they're allowed a season; if
+// that's above the group's ApproveAbove threshold it waits for a second admin,
+// and comes back as a pending approval.
+func (s *BailoutService) SetPolicy(groupID, userID string, req SetBailoutPolicyRequest) (*models.BailoutPolicy, *models.GrantApproval, error) {
+	if req.ResetTo <= req.Threshold {
+		return nil, nil, fmt.Errorf("reset amount must be above the threshold")
+	}
+
+	var group models.Group
+	if err := s.db.First(&group, "id = ?", groupID).Error; err != nil {
+		return nil, nil, fmt.Errorf("group not found")
+	}
+	var members int64
+	s.db.Model(&models.GroupMember{}).Where("group_id = ?", groupID).Count(&members)
+	total := req.ResetTo * req.MaxPerSeason * int(members)
+	if needsApproval(&group, total) {
+		settings, err := json.Marshal(req)
+		if err != nil {
+			return nil, nil, err
+		}
+		approval, err := createApproval(s.db, &models.GrantApproval{
+			GroupID:     groupID,
+			Kind:        models.GrantApprovalBailout,
+			RequestedBy: userID,
+			Amount:      req.ResetTo,
+			Settings:    string(settings),
+			Total:       total,
+		})
+		return nil, approval, err
+	}
+
+	policy, err := setBailoutPolicyTx(s.db, groupID, userID, req)
+	return policy, nil, err
+}
+
+func setBailoutPolicyTx(tx *gorm.DB, groupID, userID string, req SetBailoutPolicyRequest) (*models.BailoutPolicy, error) {
+	policy := models.BailoutPolicy{
+		GroupID:      groupID,
+		Threshold:    req.Threshold,
+		ResetTo:      req.ResetTo,
+		MaxPerSeason: req.MaxPerSeason,
+		CreatedBy:    userID,
+	}
+	var existing models.BailoutPolicy
+	if err := tx.First(&existing, "group_id = ?", groupID).Error; err == nil {
+		policy.CreatedAt = existing.CreatedAt
+	}
+	if err := tx.Save(&policy).Error; err != nil {
+		return nil, fmt.Errorf("failed to save bailout policy: %w", err)
+	}
+	return &policy, nil
+}
+
+func (s *BailoutService) DeletePolicy(groupID string) error {
+	result := s.db.Delete(&models.BailoutPolicy{}, "group_id = ?", groupID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("group has no bailout policy")
+	}
+	return nil
+}
+
+func (s *BailoutService) GetStatus(groupID, userID string) (*BailoutStatus, error) {
+	return bailoutStatusTx(s.db, groupID, userID)
+}
+
+func bailoutStatusTx(tx *gorm.DB, groupID, userID string) (*BailoutStatus, error) {
+	var policy models.BailoutPolicy
+	if err := tx.First(&policy, "group_id = ?", groupID).Error; err != nil {
+		return nil, fmt.Errorf("group has no bailout policy")
+	}
+	var member models.GroupMember
+	if err := tx.Where("group_id = ? AND user_id = ?", groupID, userID).First(&member).Error; err != nil {
+		return nil, fmt.Errorf("not a member of this group")
+	}
+	_, seasonStart, err := CurrentSeason(tx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &BailoutStatus{
+		BailoutPolicy: policy,
+		Balance:       member.PointsBalance,
+		AtStake:       pointsAtStakeTx(tx, groupID, userID),
+	}
+	tx.Model(&models.PointsLog{}).
+		Where("group_id = ? AND user_id = ? AND type = ? AND created_at >= ?", groupID, userID, models.PointsLogBailout, seasonStart).
+		Count(&status.Used)
+	status.Remaining = max(int64(policy.MaxPerSeason)-status.Used, 0)
+	status.Eligible = status.Remaining > 0 && status.Balance+status.AtStake < policy.Threshold
+	return status, nil
+}
+
+// pointsAtStakeTx sums what a member has riding on anything not yet settled:
+// bets on open or locked pools, side bets, bracket entries and squares. It
+// also counts points parked where they'll come back to the member (loans
+// still owed to them, their own seeds on unsettled pools) or handed to
+// someone else in the last day, so nobody can empty their balance on purpose
+// to qualify for a bailout.
+func pointsAtStakeTx(tx *gorm.DB, groupID, userID string) int {
+	var total, n int
+	sum := func(q *gorm.DB) {
+		n = 0
+		q.Scan(&n)
+		total += n
+	}
+
+	sum(tx.Model(&models.Bet{}).Select("COALESCE(SUM(bets.points_wagered), 0)").
+		Joins("JOIN pools ON pools.id = bets.pool_id").
+		Where("bets.user_id = ? AND pools.group_id = ? AND pools.type <> ? AND pools.status IN ?", userID, groupID,
+			models.PoolTypePoll, []models.PoolStatus{models.PoolStatusOpen, models.PoolStatusLocked}))
+	sum(tx.Model(&models.SideBetMember{}).Select("COALESCE(SUM(side_bet_members.stake), 0)").
+		Joins("JOIN side_bets ON side_bets.id = side_bet_members.side_bet_id").
+		Where("side_bet_members.user_id = ? AND side_bets.group_id = ? AND side_bets.status = ? AND side_bet_members.joined_at IS NOT NULL",
+			userID, groupID, models.SideBetStatusOpen))
+	sum(tx.Model(&models.BracketEntry{}).Select("COALESCE(SUM(brackets.entry_fee), 0)").
+		Joins("JOIN brackets ON brackets.id = bracket_entries.bracket_id").
+		Where("bracket_entries.user_id = ? AND brackets.group_id = ? AND brackets.status IN ?", userID, groupID,
+			[]models.BracketStatus{models.BracketStatusOpen, models.BracketStatusLocked}))
+	sum(tx.Model(&models.SquaresClaim{}).Select("COALESCE(SUM(squares_games.price_per_square), 0)").
+		Joins("JOIN squares_games ON squares_games.id = squares_claims.game_id").
+		Where("squares_claims.user_id = ? AND squares_games.group_id = ? AND squares_games.status IN ?", userID, groupID,
+			[]models.SquaresStatus{models.SquaresStatusOpen, models.SquaresStatusDrawn}))
+	sum(tx.Model(&models.Loan{}).Select("COALESCE(SUM(owed - repaid), 0)").
+		Where("lender_id = ? AND group_id = ? AND status = ?", userID, groupID, models.LoanStatusActive))
+	sum(tx.Model(&models.PoolSeed{}).Select("COALESCE(SUM(pool_seeds.amount), 0)").
+		Joins("JOIN pools ON pools.id = pool_seeds.pool_id").
+		Where("pool_seeds.seeded_by = ? AND pools.group_id = ? AND pool_seeds.from_treasury = ? AND pool_seeds.status = ?", userID, groupID,
+			false, models.PoolSeedHeld))
+	sum(tx.Model(&models.Transfer{}).Select("COALESCE(SUM(amount), 0)").
+		Where("from_user_id = ? AND group_id = ? AND created_at > ?", userID, groupID, time.Now().Add(-transferWindow)))
+
+	return total
+}
+
+// Claim resets a busted member's balance to the policy's ResetTo, paid from
+// the treasury.
+func (s *BailoutService) Claim(groupID, userID string) (*BailoutStatus, error) {
+	tx := s.db.Begin()
+
+	status, err := bailoutStatusTx(tx, groupID, userID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if status.Remaining == 0 {
+		tx.Rollback()
+		return nil, fmt.Errorf("you've used all %d bailouts for this season", status.MaxPerSeason)
+	}
+	if status.Balance+status.AtStake >= status.Threshold {
+		tx.Rollback()
+		if status.AtStake > 0 {
+			return nil, fmt.Errorf("bailouts are for balances under %d; you have %d plus %d on unsettled bets, loans, seeds or recent transfers",
+				status.Threshold, status.Balance, status.AtStake)
+		}
+		return nil, fmt.Errorf("bailouts are for balances under %d; you have %d", status.Threshold, status.Balance)
+	}
+
+	note := fmt.Sprintf("Bailout %d of %d this season, balance reset from %d to %d",
+		status.Used+1, status.MaxPerSeason, status.Balance, status.ResetTo)
+	if err := creditMember(tx, groupID, userID, status.ResetTo-status.Balance, TreasuryAccount,
+		models.PointsLogBailout, "", note); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	return s.GetStatus(groupID, userID)
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"testing"
+)
+
+func TestBailout(t *testing.T) {
+	db, poolSvc, groupSvc, group, alice, bob := setupPoolTest(t)
+	svc := NewBailoutService(db)
+
+	if _, err := svc.Claim(group.ID, bob.ID); err == nil {
+		t.Error("expected error without a bailout policy")
+	}
+	if _, _, err := svc.SetPolicy(group.ID, alice.ID, SetBailoutPolicyRequest{Threshold: 100, ResetTo: 50, MaxPerSeason: 2}); err == nil {
+		t.Error("expected error resetting to less than the threshold")
+	}
+	if _, _, err := svc.SetPolicy(group.ID, alice.ID, SetBailoutPolicyRequest{Threshold: 100, ResetTo: 500, MaxPerSeason: 2}); err != nil {
+		t.Fatalf("SetPolicy failed: %v", err)
+	}
+
+	if _, err := svc.Claim(group.ID, bob.ID); err == nil {
+		t.Error("expected error claiming with a healthy balance")
+	}
+
+	// Bob goes nearly all in; while it's unsettled the stake still counts
+	pool, _ := poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{
+		Title:   "Long shot",
+		Options: []string{"Yes", "No"},
+	})
+	poolSvc.PlaceBet(pool.ID, bob.ID, PlaceBetRequest{OptionID: pool.Options[0].ID, Points: 950})
+	poolSvc.PlaceBet(pool.ID, alice.ID, PlaceBetRequest{OptionID: pool.Options[1].ID, Points: 10})
+	if _, err := svc.Claim(group.ID, bob.ID); err == nil {
+		t.Error("expected error claiming with points still riding on an open pool")
+	}
+
+	poolSvc.ResolvePool(pool.ID, pool.Options[1].ID, alice.ID, false)
+	status, err := svc.GetStatus(group.ID, bob.ID)
+	if err != nil || !status.Eligible || status.Balance != 50 {
+		t.Fatalf("expected Bob eligible at 50, got %+v, %v", status, err)
+	}
+
+	status, err = svc.Claim(group.ID, bob.ID)
+	if err != nil {
+		t.Fatalf("Claim failed: %v", err)
+	}
+	if status.Balance != 500 || status.Used != 1 || status.Remaining != 1 {
+		t.Errorf("expected Bob reset to 500 with one bailout left, got %+v", status)
+	}
+	if _, err := svc.Claim(group.ID, bob.ID); err == nil {
+		t.Error("expected error claiming again right after a reset")
+	}
+
+	// Use up the second bailout, then the third is refused
+	groupSvc.GrantPoints(group.ID, bob.ID, -450, "test")
+	if _, err := svc.Claim(group.ID, bob.ID); err != nil {
+		t.Fatalf("second Claim failed: %v", err)
+	}
+	groupSvc.GrantPoints(group.ID, bob.ID, -450, "test")
+	if _, err := svc.Claim(group.ID, bob.ID); err == nil {
+		t.Error("expected error past the per-season limit")
+	}
+
+	rec := MemberRecordSince(db, group.ID, bob.ID, group.CreatedAt)
+	if rec.Bailouts != 2 {
+		t.Errorf("expected 2 bailouts on Bob's record, got %d", rec.Bailouts)
+	}
+
+	// A new season brings the allowance back
+	season, err := NewSeasonService(db).CloseSeason(group.ID, alice.ID, CloseSeasonRequest{})
+	if err != nil {
+		t.Fatalf("CloseSeason failed: %v", err)
+	}
+	for _, s := range season.Standings {
+		if s.UserID == bob.ID && s.Bailouts != 2 {
+			t.Errorf("expected Bob's 2 bailouts archived, got %d", s.Bailouts)
+		}
+	}
+	status, _ = svc.GetStatus(group.ID, bob.ID)
+	if status.Used != 0 || status.Remaining != 2 {
+		t.Errorf("expected bailouts reset for the new season, got %+v", status)
+	}
+
+	assertReconciled(t, db, group.ID)
+}
+
+func TestBailoutCountsParkedPoints(t *testing.T) {
+	db, poolSvc, groupSvc, group, alice, bob := setupPoolTest(t)
+	svc := NewBailoutService(db)
+	if _, _, err := svc.SetPolicy(group.ID, alice.ID, SetBailoutPolicyRequest{Threshold: 100, ResetTo: 500, MaxPerSeason: 5}); err != nil {
+		t.Fatalf("SetPolicy failed: %v", err)
+	}
+	refused := func(what string) {
+		t.Helper()
+		status, _ := svc.GetStatus(group.ID, bob.ID)
+		if status.Balance >= 100 {
+			t.Fatalf("expected Bob's balance emptied by the %s, got %d", what, status.Balance)
+		}
+		if _, err := svc.Claim(group.ID, bob.ID); err == nil {
+			t.Errorf("expected error claiming with points parked in a %s", what)
+		}
+	}
+
+	// A loan Alice still owes Bob
+	loanSvc := NewLoanService(db)
+	loan, err := loanSvc.OfferLoan(group.ID, bob.ID, OfferLoanRequest{BorrowerID: alice.ID, Principal: 950})
+	if err != nil {
+		t.Fatalf("OfferLoan failed: %v", err)
+	}
+	if _, err := loanSvc.AcceptLoan(loan.ID, alice.ID); err != nil {
+		t.Fatalf("AcceptLoan failed: %v", err)
+	}
+	refused("loan")
+	loanSvc.RepayLoan(loan.ID, alice.ID, RepayLoanRequest{Amount: 950})
+
+	// A seed on Bob's own pool that comes back if nobody wins
+	pool, _ := poolSvc.CreatePool(group.ID, bob.ID, CreatePoolRequest{Title: "Seeded", Options: []string{"Yes", "No"}})
+	if _, _, err := poolSvc.SeedPool(group.ID, pool.ID, bob.ID, false, SeedPoolRequest{Amount: 950}); err != nil {
+		t.Fatalf("SeedPool failed: %v", err)
+	}
+	refused("seed")
+	poolSvc.CancelPool(pool.ID, bob.ID, false)
+
+	// Points handed to a friend today
+	limit := 1000
+	groupSvc.UpdateGroup(group.ID, group.Name, group.DefaultPoints, &limit, nil)
+	if _, err := NewTransferService(db).SendPoints(group.ID, bob.ID, SendTransferRequest{ToUserID: alice.ID, Amount: 950}); err != nil {
+		t.Fatalf("SendPoints failed: %v", err)
+	}
+	refused("transfer")
+
+	assertReconciled(t, db, group.ID)
+}
@@ -0,0 +1,604 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/codyseavey/bets/models"
+)
+
+const maxBracketTeams = 128
+
+type BracketService struct {
+	db *gorm.DB
+}
+
+func NewBracketService(db *gorm.DB) *BracketService {
+	return &BracketService{db: db}
+}
+
+type CreateBracketRequest struct {
+	Title       string     `json:"title" binding:"required"`
+	Description string     `json:"description"`
+	Teams       []string   `json:"teams" binding:"required,min=2"`
+	LockAt      *time.Time `json:"lock_at"`
+	EntryFee    int        `json:"entry_fee" binding:"gte=0"`
+	ScoringBase int        `json:"scoring_base" binding:"gte=0"`
+}
+
+// CreateBracket sets up a single-elimination bracket. Teams are listed in
+// seeded order and must number a power of two.
+func (s *BracketService) CreateBracket(groupID, userID string, req CreateBracketRequest) (*models.Bracket, error) {
+	n := len(req.Teams)
+	if n < 2 || n > maxBracketTeams || n&(n-1) != 0 {
+		return nil, fmt.Errorf("number of teams must be a power of two between 2 and %d", maxBracketTeams)
+	}
+	seen := make(map[string]bool, n)
+	for i, t := range req.Teams {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			return nil, fmt.Errorf("team names can't be empty")
+		}
+		if seen[strings.ToLower(t)] {
+			return nil, fmt.Errorf("duplicate team %q", t)
+		}
+		seen[strings.ToLower(t)] = true
+		req.Teams[i] = t
+	}
+	if req.ScoringBase == 0 {
+		req.ScoringBase = 1
+	}
+
+	rounds := 0
+	for size := n; size > 1; size /= 2 {
+		rounds++
+	}
+
+	bracket := &models.Bracket{
+		ID:          uuid.New().String(),
+		GroupID:     groupID,
+		Title:       req.Title,
+		Description: req.Description,
+		Status:      models.BracketStatusOpen,
+		Rounds:      rounds,
+		EntryFee:    req.EntryFee,
+		ScoringBase: req.ScoringBase,
+		LockAt:      req.LockAt,
+		CreatedBy:   userID,
+	}
+
+	tx := s.db.Begin()
+	if err := tx.Create(bracket).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to create bracket: %w", err)
+	}
+
+	games := n / 2
+	for round := 1; round <= rounds; round++ {
+		for pos := 0; pos < games; pos++ {
+			game := models.BracketGame{BracketID: bracket.ID, Round: round, Position: pos}
+			if round == 1 {
+				game.TeamA = req.Teams[2*pos]
+				game.TeamB = req.Teams[2*pos+1]
+			}
+			if err := tx.Create(&game).Error; err != nil {
+				tx.Rollback()
+				return nil, fmt.Errorf("failed to create bracket game: %w", err)
+			}
+			bracket.Games = append(bracket.Games, game)
+		}
+		games /= 2
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return bracket, nil
+}
+
+func (s *BracketService) GetGroupBrackets(groupID string) ([]models.Bracket, error) {
+	var brackets []models.Bracket
+	err := s.db.Where("group_id = ?", groupID).
+		Preload("Creator").
+		Order("created_at DESC").
+		Find(&brackets).Error
+	return brackets, err
+}
+
+func (s *BracketService) GetBracket(bracketID string) (*models.Bracket, error) {
+	var bracket models.Bracket
+	err := s.db.
+		Preload("Creator").
+		Preload("Games", func(db *gorm.DB) *gorm.DB {
+			return db.Order("round ASC, position ASC")
+		}).
+		First(&bracket, "id = ?", bracketID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &bracket, nil
+}
+
+func (s *BracketService) GetBracketGroupID(bracketID string) (string, error) {
+	var bracket models.Bracket
+	if err := s.db.Select("group_id").First(&bracket, "id = ?", bracketID).Error; err != nil {
+		return "", err
+	}
+	return bracket.GroupID, nil
+}
+
+type BracketPickInput struct {
+	Round    int    `json:"round" binding:"required,gt=0"`
+	Position int    `json:"position" binding:"gte=0"`
+	Team     string `json:"team" binding:"required"`
+}
+
+type SubmitBracketRequest struct {
+	Picks []BracketPickInput `json:"picks" binding:"required,dive"`
+}
+
+// SubmitEntry records (or replaces) a member's full bracket prediction. The
+// entry fee, if any, is only charged on the first submission.
+func (s *BracketService) SubmitEntry(bracketID, userID string, req SubmitBracketRequest) (*models.BracketEntry, error) {
+	tx := s.db.Begin()
+
+	var bracket models.Bracket
+	if err := tx.Preload("Games").First(&bracket, "id = ?", bracketID).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("bracket not found")
+	}
+	if bracket.Status != models.BracketStatusOpen {
+		tx.Rollback()
+		return nil, fmt.Errorf("bracket is not open for entries")
+	}
+	if bracket.LockAt != nil && !time.Now().Before(*bracket.LockAt) {
+		tx.Rollback()
+		return nil, fmt.Errorf("bracket closed for entries at %s", bracket.LockAt.Format(time.RFC3339))
+	}
+
+	picks, err := validateBracketPicks(&bracket, req.Picks)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	var entry models.BracketEntry
+	err = tx.Where("bracket_id = ? AND user_id = ?", bracketID, userID).First(&entry).Error
+	switch {
+	case err == nil:
+		if err := tx.Where("entry_id = ?", entry.ID).Delete(&models.BracketPick{}).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		entry = models.BracketEntry{
+			ID:        uuid.New().String(),
+			BracketID: bracketID,
+			UserID:    userID,
+		}
+		if err := tx.Create(&entry).Error; err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to create entry: %w", err)
+		}
+		if bracket.EntryFee > 0 {
+			if err := debitMember(tx, bracket.GroupID, userID, bracket.EntryFee, BracketEscrow(bracket.ID), models.PointsLogBracketEntry, entry.ID,
+				fmt.Sprintf("Entered bracket \"%s\"", bracket.Title)); err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		}
+	default:
+		tx.Rollback()
+		return nil, err
+	}
+
+	for i := range picks {
+		picks[i].EntryID = entry.ID
+		if err := tx.Create(&picks[i]).Error; err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to save pick: %w", err)
+		}
+	}
+	entry.Picks = picks
+	entry.Score, entry.PossibleScore = scoreBracketEntry(&bracket, picks)
+	if err := tx.Model(&entry).Updates(map[string]interface{}{
+		"score":          entry.Score,
+		"possible_score": entry.PossibleScore,
+	}).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// validateBracketPicks checks that a prediction covers every game exactly once
+// and is internally consistent: each pick must be a team that was itself
+// picked to win the feeder game.
+func validateBracketPicks(bracket *models.Bracket, input []BracketPickInput) ([]models.BracketPick, error) {
+	type key struct{ round, pos int }
+
+	games := make(map[key]models.BracketGame, len(bracket.Games))
+	for _, g := range bracket.Games {
+		games[key{g.Round, g.Position}] = g
+	}
+	if len(input) != len(games) {
+		return nil, fmt.Errorf("expected %d picks (one per game), got %d", len(games), len(input))
+	}
+
+	byGame := make(map[key]string, len(input))
+	for _, p := range input {
+		k := key{p.Round, p.Position}
+		if _, ok := games[k]; !ok {
+			return nil, fmt.Errorf("no game at round %d position %d", p.Round, p.Position)
+		}
+		if _, dup := byGame[k]; dup {
+			return nil, fmt.Errorf("duplicate pick for round %d position %d", p.Round, p.Position)
+		}
+		byGame[k] = strings.TrimSpace(p.Team)
+	}
+
+	picks := make([]models.BracketPick, 0, len(input))
+	for round := 1; round <= bracket.Rounds; round++ {
+		for pos := 0; ; pos++ {
+			k := key{round, pos}
+			game, ok := games[k]
+			if !ok {
+				break
+			}
+			team := byGame[k]
+
+			var candidates []string
+			if round == 1 {
+				candidates = []string{game.TeamA, game.TeamB}
+			} else {
+				candidates = []string{byGame[key{round - 1, 2 * pos}], byGame[key{round - 1, 2*pos + 1}]}
+			}
+			if !strings.EqualFold(team, candidates[0]) && !strings.EqualFold(team, candidates[1]) {
+				return nil, fmt.Errorf("round %d position %d: %q must be %q or %q", round, pos, team, candidates[0], candidates[1])
+			}
+			// Normalize to the canonical spelling so scoring can compare exactly
+			if strings.EqualFold(team, candidates[0]) {
+				team = candidates[0]
+			} else {
+				team = candidates[1]
+			}
+			byGame[k] = team
+
+			picks = append(picks, models.BracketPick{Round: round, Position: pos, Team: team})
+		}
+	}
+
+	return picks, nil
+}
+
+// scoreBracketEntry returns the points an entry has earned from decided games
+// and the most it can still reach if every surviving pick wins.
+func scoreBracketEntry(bracket *models.Bracket, picks []models.BracketPick) (score, possible int) {
+	type key struct{ round, pos int }
+
+	games := make(map[key]models.BracketGame, len(bracket.Games))
+	eliminated := make(map[string]bool)
+	for _, g := range bracket.Games {
+		games[key{g.Round, g.Position}] = g
+		if g.Winner != "" {
+			if g.Winner == g.TeamA {
+				eliminated[g.TeamB] = true
+			} else {
+				eliminated[g.TeamA] = true
+			}
+		}
+	}
+
+	for _, p := range picks {
+		points := bracket.ScoringBase << (p.Round - 1)
+		game := games[key{p.Round, p.Position}]
+		switch {
+		case game.Winner == "":
+			if !eliminated[p.Team] {
+				possible += points
+			}
+		case game.Winner == p.Team:
+			score += points
+			possible += points
+		}
+	}
+	return score, possible
+}
+
+func (s *BracketService) LockBracket(bracketID string) error {
+	result := s.db.Model(&models.Bracket{}).
+		Where("id = ? AND status = ?", bracketID, models.BracketStatusOpen).
+		Update("status", models.BracketStatusLocked)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("bracket is not open")
+	}
+	return nil
+}
+
+type RecordBracketResultRequest struct {
+	Round    int    `json:"round" binding:"required,gt=0"`
+	Position int    `json:"position" binding:"gte=0"`
+	Winner   string `json:"winner" binding:"required"`
+}
+
+// RecordResult sets the winner of one game, advances them into the next
+// round and rescores every entry. Recording the final completes the bracket
+// and pays out the entry fees to the top scorer(s). A result can be corrected
+// until the game it feeds into has been decided.
+func (s *BracketService) RecordResult(bracketID string, req RecordBracketResultRequest) (*models.Bracket, error) {
+	tx := s.db.Begin()
+
+	var bracket models.Bracket
+	if err := tx.Preload("Games").First(&bracket, "id = ?", bracketID).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("bracket not found")
+	}
+	if bracket.Status != models.BracketStatusOpen && bracket.Status != models.BracketStatusLocked {
+		tx.Rollback()
+		return nil, fmt.Errorf("bracket is already %s", bracket.Status)
+	}
+
+	var game *models.BracketGame
+	var next *models.BracketGame
+	for i := range bracket.Games {
+		g := &bracket.Games[i]
+		if g.Round == req.Round && g.Position == req.Position {
+			game = g
+		}
+		if g.Round == req.Round+1 && g.Position == req.Position/2 {
+			next = g
+		}
+	}
+	if game == nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("no game at round %d position %d", req.Round, req.Position)
+	}
+	if game.TeamA == "" || game.TeamB == "" {
+		tx.Rollback()
+		return nil, fmt.Errorf("both teams for this game aren't known yet")
+	}
+	winner := strings.TrimSpace(req.Winner)
+	switch {
+	case strings.EqualFold(winner, game.TeamA):
+		winner = game.TeamA
+	case strings.EqualFold(winner, game.TeamB):
+		winner = game.TeamB
+	default:
+		tx.Rollback()
+		return nil, fmt.Errorf("winner must be %q or %q", game.TeamA, game.TeamB)
+	}
+	if next != nil && next.Winner != "" {
+		tx.Rollback()
+		return nil, fmt.Errorf("can't change a result once the next round's game is decided")
+	}
+
+	// Results coming in means the tournament has started
+	if bracket.Status == models.BracketStatusOpen {
+		bracket.Status = models.BracketStatusLocked
+		if err := tx.Model(&bracket).Update("status", bracket.Status).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	game.Winner = winner
+	if err := tx.Model(game).Update("winner", winner).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if next != nil {
+		column := "team_a"
+		if req.Position%2 == 1 {
+			column = "team_b"
+			next.TeamB = winner
+		} else {
+			next.TeamA = winner
+		}
+		if err := tx.Model(next).Update(column, winner).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	entries, err := s.rescoreTx(tx, &bracket)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if game.Round == bracket.Rounds {
+		if err := s.completeTx(tx, &bracket, entries); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetBracket(bracketID)
+}
+
+func (s *BracketService) rescoreTx(tx *gorm.DB, bracket *models.Bracket) ([]models.BracketEntry, error) {
+	var entries []models.BracketEntry
+	if err := tx.Preload("Picks").Where("bracket_id = ?", bracket.ID).Order("created_at ASC").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		entries[i].Score, entries[i].PossibleScore = scoreBracketEntry(bracket, entries[i].Picks)
+		if err := tx.Model(&entries[i]).Updates(map[string]interface{}{
+			"score":          entries[i].Score,
+			"possible_score": entries[i].PossibleScore,
+		}).Error; err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// completeTx splits the entry fees evenly among the highest-scoring entries,
+// giving any indivisible remainder to the earliest entry.
+func (s *BracketService) completeTx(tx *gorm.DB, bracket *models.Bracket, entries []models.BracketEntry) error {
+	now := time.Now()
+	if err := tx.Model(bracket).Updates(map[string]interface{}{
+		"status":       models.BracketStatusCompleted,
+		"completed_at": now,
+	}).Error; err != nil {
+		return err
+	}
+	bracket.Status = models.BracketStatusCompleted
+
+	pot := bracket.EntryFee * len(entries)
+	if pot == 0 {
+		return nil
+	}
+
+	best := -1
+	var winners []models.BracketEntry
+	for _, e := range entries {
+		switch {
+		case e.Score > best:
+			best = e.Score
+			winners = []models.BracketEntry{e}
+		case e.Score == best:
+			winners = append(winners, e)
+		}
+	}
+
+	share := pot / len(winners)
+	remainder := pot - share*len(winners)
+	for i, w := range winners {
+		amount := share
+		if i == 0 {
+			amount += remainder
+		}
+		if err := creditMember(tx, bracket.GroupID, w.UserID, amount, BracketEscrow(bracket.ID), models.PointsLogBracketWon, w.ID,
+			fmt.Sprintf("Won %d points from bracket \"%s\"", amount, bracket.Title)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CancelBracket calls off a bracket that hasn't finished and refunds entry fees.
+func (s *BracketService) CancelBracket(bracketID string) error {
+	tx := s.db.Begin()
+
+	var bracket models.Bracket
+	if err := tx.First(&bracket, "id = ?", bracketID).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("bracket not found")
+	}
+	if bracket.Status == models.BracketStatusCompleted || bracket.Status == models.BracketStatusCancelled {
+		tx.Rollback()
+		return fmt.Errorf("bracket is already %s", bracket.Status)
+	}
+
+	if bracket.EntryFee > 0 {
+		var entries []models.BracketEntry
+		if err := tx.Where("bracket_id = ?", bracketID).Find(&entries).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+		for _, e := range entries {
+			if err := creditMember(tx, bracket.GroupID, e.UserID, bracket.EntryFee, BracketEscrow(bracket.ID), models.PointsLogBracketRefund, e.ID,
+				"Bracket cancelled, entry fee refunded"); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+	}
+
+	if err := tx.Model(&bracket).Update("status", models.BracketStatusCancelled).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+func (s *BracketService) GetEntry(bracketID, userID string) (*models.BracketEntry, error) {
+	var entry models.BracketEntry
+	err := s.db.Preload("Picks", func(db *gorm.DB) *gorm.DB {
+		return db.Order("round ASC, position ASC")
+	}).Where("bracket_id = ? AND user_id = ?", bracketID, userID).First(&entry).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+type BracketStanding struct {
+	Rank          int                  `json:"rank"`
+	UserID        string               `json:"user_id"`
+	Name          string               `json:"name"`
+	AvatarURL     string               `json:"avatar_url"`
+	Score         int                  `json:"score"`
+	PossibleScore int                  `json:"possible_score"`
+	Picks         []models.BracketPick `json:"picks,omitempty"`
+}
+
+// GetLeaderboard ranks entries by score, then by remaining potential. Other
+// members' picks are hidden while the bracket is still open so nobody can
+// copy them.
+func (s *BracketService) GetLeaderboard(bracketID, viewerID string) ([]BracketStanding, error) {
+	var bracket models.Bracket
+	if err := s.db.First(&bracket, "id = ?", bracketID).Error; err != nil {
+		return nil, err
+	}
+
+	var entries []models.BracketEntry
+	if err := s.db.Where("bracket_id = ?", bracketID).
+		Preload("User").
+		Preload("Picks", func(db *gorm.DB) *gorm.DB {
+			return db.Order("round ASC, position ASC")
+		}).
+		Find(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Score != entries[j].Score {
+			return entries[i].Score > entries[j].Score
+		}
+		return entries[i].PossibleScore > entries[j].PossibleScore
+	})
+
+	standings := make([]BracketStanding, 0, len(entries))
+	for i, e := range entries {
+		rank := i + 1
+		if i > 0 && e.Score == entries[i-1].Score {
+			rank = standings[i-1].Rank
+		}
+		st := BracketStanding{
+			Rank:          rank,
+			UserID:        e.UserID,
+			Name:          e.User.Name,
+			AvatarURL:     e.User.AvatarURL,
+			Score:         e.Score,
+			PossibleScore: e.PossibleScore,
+		}
+		if bracket.Status != models.BracketStatusOpen || e.UserID == viewerID {
+			st.Picks = e.Picks
+		}
+		standings = append(standings, st)
+	}
+
+	return standings, nil
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/codyseavey/bets/models"
+)
+
+func fourTeamPicks(semi1, semi2, champ string) SubmitBracketRequest {
+	return SubmitBracketRequest{Picks: []BracketPickInput{
+		{Round: 1, Position: 0, Team: semi1},
+		{Round: 1, Position: 1, Team: semi2},
+		{Round: 2, Position: 0, Team: champ},
+	}}
+}
+
+func TestBracket_CreateValidatesTeams(t *testing.T) {
+	db, _, _, group, alice, _ := setupPoolTest(t)
+	svc := NewBracketService(db)
+
+	if _, err := svc.CreateBracket(group.ID, alice.ID, CreateBracketRequest{
+		Title: "Three teams", Teams: []string{"A", "B", "C"},
+	}); err == nil {
+		t.Error("expected error for non power-of-two team count")
+	}
+	if _, err := svc.CreateBracket(group.ID, alice.ID, CreateBracketRequest{
+		Title: "Dupes", Teams: []string{"A", "a"},
+	}); err == nil {
+		t.Error("expected error for duplicate teams")
+	}
+
+	bracket, err := svc.CreateBracket(group.ID, alice.ID, CreateBracketRequest{
+		Title: "Eight", Teams: []string{"A", "B", "C", "D", "E", "F", "G", "H"},
+	})
+	if err != nil {
+		t.Fatalf("CreateBracket failed: %v", err)
+	}
+	if bracket.Rounds != 3 || len(bracket.Games) != 7 {
+		t.Errorf("expected 3 rounds and 7 games, got %d and %d", bracket.Rounds, len(bracket.Games))
+	}
+}
+
+func TestBracket_PicksMustBeConsistent(t *testing.T) {
+	db, _, _, group, alice, _ := setupPoolTest(t)
+	svc := NewBracketService(db)
+
+	bracket, _ := svc.CreateBracket(group.ID, alice.ID, CreateBracketRequest{
+		Title: "Final Four", Teams: []string{"Duke", "UNC", "Kansas", "UCLA"},
+	})
+
+	// Champion must be one of the picked semifinal winners
+	if _, err := svc.SubmitEntry(bracket.ID, alice.ID, fourTeamPicks("Duke", "Kansas", "UNC")); err == nil {
+		t.Error("expected error picking an eliminated team to win the final")
+	}
+	if _, err := svc.SubmitEntry(bracket.ID, alice.ID, SubmitBracketRequest{Picks: []BracketPickInput{
+		{Round: 1, Position: 0, Team: "Duke"},
+	}}); err == nil {
+		t.Error("expected error for incomplete bracket")
+	}
+
+	entry, err := svc.SubmitEntry(bracket.ID, alice.ID, fourTeamPicks("duke", "Kansas", "DUKE"))
+	if err != nil {
+		t.Fatalf("SubmitEntry failed: %v", err)
+	}
+	if entry.Picks[2].Team != "Duke" {
+		t.Errorf("expected pick normalized to Duke, got %q", entry.Picks[2].Team)
+	}
+	if entry.PossibleScore != 4 {
+		t.Errorf("expected possible score 4 (1+1+2), got %d", entry.PossibleScore)
+	}
+}
+
+func TestBracket_ScoringAndPayout(t *testing.T) {
+	db, _, _, group, alice, bob := setupPoolTest(t)
+	svc := NewBracketService(db)
+
+	bracket, _ := svc.CreateBracket(group.ID, alice.ID, CreateBracketRequest{
+		Title: "Final Four", Teams: []string{"Duke", "UNC", "Kansas", "UCLA"}, EntryFee: 50,
+	})
+
+	if _, err := svc.SubmitEntry(bracket.ID, alice.ID, fourTeamPicks("Duke", "Kansas", "Duke")); err != nil {
+		t.Fatalf("alice SubmitEntry failed: %v", err)
+	}
+	// Resubmitting replaces picks without charging again
+	if _, err := svc.SubmitEntry(bracket.ID, alice.ID, fourTeamPicks("Duke", "Kansas", "Kansas")); err != nil {
+		t.Fatalf("alice resubmit failed: %v", err)
+	}
+	if _, err := svc.SubmitEntry(bracket.ID, bob.ID, fourTeamPicks("UNC", "Kansas", "UNC")); err != nil {
+		t.Fatalf("bob SubmitEntry failed: %v", err)
+	}
+
+	var aliceMember models.GroupMember
+	db.Where("group_id = ? AND user_id = ?", group.ID, alice.ID).First(&aliceMember)
+	if aliceMember.PointsBalance != 950 {
+		t.Errorf("expected Alice charged once (950), got %d", aliceMember.PointsBalance)
+	}
+
+	if _, err := svc.RecordResult(bracket.ID, RecordBracketResultRequest{Round: 2, Position: 0, Winner: "Duke"}); err == nil {
+		t.Error("expected error recording final before semifinals")
+	}
+	if _, err := svc.RecordResult(bracket.ID, RecordBracketResultRequest{Round: 1, Position: 0, Winner: "Duke"}); err != nil {
+		t.Fatalf("RecordResult failed: %v", err)
+	}
+
+	// First result locks the bracket
+	if _, err := svc.SubmitEntry(bracket.ID, bob.ID, fourTeamPicks("Duke", "Kansas", "Duke")); err == nil {
+		t.Error("expected error submitting after results started")
+	}
+
+	standings, _ := svc.GetLeaderboard(bracket.ID, bob.ID)
+	if standings[0].UserID != alice.ID || standings[0].Score != 1 {
+		t.Errorf("expected Alice leading with 1, got %+v", standings[0])
+	}
+	if standings[1].PossibleScore != 1 {
+		t.Errorf("expected Bob's champion pick eliminated (possible 1), got %d", standings[1].PossibleScore)
+	}
+
+	svc.RecordResult(bracket.ID, RecordBracketResultRequest{Round: 1, Position: 1, Winner: "Kansas"})
+	final, err := svc.RecordResult(bracket.ID, RecordBracketResultRequest{Round: 2, Position: 0, Winner: "Kansas"})
+	if err != nil {
+		t.Fatalf("RecordResult final failed: %v", err)
+	}
+	if final.Status != models.BracketStatusCompleted {
+		t.Errorf("expected bracket completed, got %s", final.Status)
+	}
+
+	db.Where("group_id = ? AND user_id = ?", group.ID, alice.ID).First(&aliceMember)
+	if aliceMember.PointsBalance != 1050 {
+		t.Errorf("expected Alice to win the 100 pot (1050), got %d", aliceMember.PointsBalance)
+	}
+
+	assertReconciled(t, db, group.ID)
+}
+
+func TestBracket_CancelRefundsFees(t *testing.T) {
+	db, _, _, group, alice, bob := setupPoolTest(t)
+	svc := NewBracketService(db)
+
+	bracket, _ := svc.CreateBracket(group.ID, alice.ID, CreateBracketRequest{
+		Title: "Pair", Teams: []string{"A", "B"}, EntryFee: 30,
+	})
+	svc.SubmitEntry(bracket.ID, bob.ID, SubmitBracketRequest{Picks: []BracketPickInput{{Round: 1, Position: 0, Team: "A"}}})
+
+	if err := svc.CancelBracket(bracket.ID); err != nil {
+		t.Fatalf("CancelBracket failed: %v", err)
+	}
+
+	var bobMember models.GroupMember
+	db.Where("group_id = ? AND user_id = ?", group.ID, bob.ID).First(&bobMember)
+	if bobMember.PointsBalance != 1000 {
+		t.Errorf("expected Bob refunded to 1000, got %d", bobMember.PointsBalance)
+	}
+	if err := svc.CancelBracket(bracket.ID); err == nil {
+		t.Error("expected error cancelling twice")
+	}
+
+	assertReconciled(t, db, group.ID)
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/codyseavey/bets/models"
+)
+
+// ChainProblem is one place where a group's PointsLog hash chain doesn't hold
+// together.
+type ChainProblem struct {
+	Seq     int64  `json:"seq"`
+	LogID   string `json:"log_id,omitempty"`
+	Problem string `json:"problem"`
+}
+
+// ChainReport is the result of walking a group's PointsLog chain. HeadHash
+// vouches for every entry before it: keep a copy somewhere else and a later
+// report with a different hash at the same Seq means history was rewritten.
+type ChainReport struct {
+	GroupID  string         `json:"group_id"`
+	Entries  int            `json:"entries"`
+	HeadSeq  int64          `json:"head_seq"`
+	HeadHash string         `json:"head_hash"`
+	Problems []ChainProblem `json:"problems"`
+}
+
+func (r *ChainReport) OK() bool {
+	return len(r.Problems) == 0
+}
+
+func (s *GroupService) VerifyChain(groupID string) (*ChainReport, error) {
+	return VerifyChain(s.db, groupID)
+}
+
+// VerifyChain rechecks every hash in a group's PointsLog chain. Edited rows
+// fail their own hash, rows deleted from the middle leave a gap in the
+// sequence, and rows deleted from the end leave the chain head pointing past
+// the last entry.
+func VerifyChain(db *gorm.DB, groupID string) (*ChainReport, error) {
+	var logs []models.PointsLog
+	if err := db.Where("group_id = ?", groupID).Order("seq, created_at").Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	var head models.PointsChainHead
+	if err := db.Where("group_id = ?", groupID).Limit(1).Find(&head).Error; err != nil {
+		return nil, err
+	}
+
+	report := &ChainReport{
+		GroupID:  groupID,
+		Entries:  len(logs),
+		HeadSeq:  head.Seq,
+		HeadHash: head.Hash,
+		Problems: []ChainProblem{},
+	}
+	problem := func(seq int64, logID, format string, args ...interface{}) {
+		report.Problems = append(report.Problems, ChainProblem{Seq: seq, LogID: logID, Problem: fmt.Sprintf(format, args...)})
+	}
+
+	next := int64(1)
+	prevHash := ""
+	for _, l := range logs {
+		switch {
+		case l.Seq == 0:
+			problem(0, l.ID, "entry isn't part of the chain")
+			continue
+		case l.Seq < next:
+			problem(l.Seq, l.ID, "sequence number used twice")
+		case l.Seq > next:
+			problem(next, "", "entries %d to %d are missing", next, l.Seq-1)
+		default:
+			if l.PrevHash != prevHash {
+				problem(l.Seq, l.ID, "entry doesn't link to the one before it")
+			}
+		}
+		if l.ChainHash() != l.Hash {
+			problem(l.Seq, l.ID, "entry was changed after it was written")
+		}
+		prevHash = l.Hash
+		next = l.Seq + 1
+	}
+
+	last := next - 1
+	switch {
+	case head.Seq > last:
+		problem(last+1, "", "entries %d to %d are missing from the end", last+1, head.Seq)
+	case head.Seq < last:
+		problem(head.Seq, "", "chain head is behind the last entry (%d)", last)
+	case head.Hash != prevHash:
+		problem(head.Seq, "", "chain head doesn't match the last entry")
+	}
+	return report, nil
+}
+
+// BackfillHashChain chains PointsLog entries written before the hash chain
+// existed, oldest first. Groups that already have a chain head are left
+// alone, so anything unchained in them shows up in VerifyChain.
+func BackfillHashChain(db *gorm.DB) error {
+	var groupIDs []string
+	if err := db.Model(&models.PointsLog{}).
+		Where("group_id NOT IN (?)", db.Model(&models.PointsChainHead{}).Select("group_id")).
+		Distinct().Pluck("group_id", &groupIDs).Error; err != nil {
+		return err
+	}
+
+	for _, groupID := range groupIDs {
+		var logs []models.PointsLog
+		if err := db.Where("group_id = ?", groupID).Order("created_at, id").Find(&logs).Error; err != nil {
+			return err
+		}
+
+		tx := db.Begin()
+		prevHash := ""
+		for i := range logs {
+			l := &logs[i]
+			l.Seq = int64(i + 1)
+			l.PrevHash = prevHash
+			l.Hash = l.ChainHash()
+			if err := tx.Model(&models.PointsLog{}).Where("id = ?", l.ID).Updates(map[string]interface{}{
+				"seq":       l.Seq,
+				"prev_hash": l.PrevHash,
+				"hash":      l.Hash,
+			}).Error; err != nil {
+				tx.Rollback()
+				return err
+			}
+			prevHash = l.Hash
+		}
+		if err := tx.Create(&models.PointsChainHead{GroupID: groupID, Seq: int64(len(logs)), Hash: prevHash}).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit().Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
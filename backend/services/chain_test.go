@@ -0,0 +1,106 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/codyseavey/bets/models"
+)
+
+func chainTestGroup(t *testing.T) (*PoolService, *models.Group, []models.PointsLog) {
+	t.Helper()
+	db, poolSvc, _, group, alice, bob := setupPoolTest(t)
+
+	pool, _ := poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{
+		Title:   "Coin toss",
+		Options: []string{"Heads", "Tails"},
+	})
+	poolSvc.PlaceBet(pool.ID, alice.ID, PlaceBetRequest{OptionID: pool.Options[0].ID, Points: 100})
+	poolSvc.PlaceBet(pool.ID, bob.ID, PlaceBetRequest{OptionID: pool.Options[1].ID, Points: 100})
+	if err := poolSvc.ResolvePool(pool.ID, pool.Options[0].ID, alice.ID, false); err != nil {
+		t.Fatalf("ResolvePool failed: %v", err)
+	}
+
+	var logs []models.PointsLog
+	db.Where("group_id = ?", group.ID).Order("seq").Find(&logs)
+	if len(logs) < 4 {
+		t.Fatalf("expected at least 4 log entries, got %d", len(logs))
+	}
+	return poolSvc, group, logs
+}
+
+func TestChainVerifiesCleanHistory(t *testing.T) {
+	poolSvc, group, logs := chainTestGroup(t)
+
+	for i, l := range logs {
+		if l.Seq != int64(i+1) {
+			t.Errorf("expected entry %d to have seq %d, got %d", i, i+1, l.Seq)
+		}
+		if i > 0 && l.PrevHash != logs[i-1].Hash {
+			t.Errorf("entry %d doesn't link to the one before it", l.Seq)
+		}
+	}
+
+	report, err := VerifyChain(poolSvc.db, group.ID)
+	if err != nil {
+		t.Fatalf("VerifyChain failed: %v", err)
+	}
+	if !report.OK() {
+		t.Errorf("expected a clean chain, got %+v", report.Problems)
+	}
+	if report.Entries != len(logs) || report.HeadSeq != int64(len(logs)) || report.HeadHash != logs[len(logs)-1].Hash {
+		t.Errorf("expected head at %d, got %+v", len(logs), report)
+	}
+}
+
+func TestChainDetectsEditedEntry(t *testing.T) {
+	poolSvc, group, logs := chainTestGroup(t)
+
+	poolSvc.db.Model(&models.PointsLog{}).Where("id = ?", logs[1].ID).Update("amount", -1)
+
+	report, _ := VerifyChain(poolSvc.db, group.ID)
+	if len(report.Problems) != 1 || report.Problems[0].LogID != logs[1].ID {
+		t.Errorf("expected the edited entry to be flagged, got %+v", report.Problems)
+	}
+}
+
+func TestChainDetectsDeletedEntries(t *testing.T) {
+	poolSvc, group, logs := chainTestGroup(t)
+
+	poolSvc.db.Delete(&models.PointsLog{}, "id = ?", logs[1].ID)
+	report, _ := VerifyChain(poolSvc.db, group.ID)
+	if len(report.Problems) != 1 || report.Problems[0].Seq != 2 {
+		t.Errorf("expected a gap at seq 2, got %+v", report.Problems)
+	}
+
+	last := logs[len(logs)-1]
+	poolSvc.db.Delete(&models.PointsLog{}, "id = ?", last.ID)
+	report, _ = VerifyChain(poolSvc.db, group.ID)
+	if len(report.Problems) != 2 || report.Problems[1].Seq != last.Seq {
+		t.Errorf("expected the missing tail to be flagged too, got %+v", report.Problems)
+	}
+}
+
+func TestBackfillHashChain(t *testing.T) {
+	poolSvc, group, logs := chainTestGroup(t)
+	db := poolSvc.db
+
+	// History from before the chain existed
+	db.Model(&models.PointsLog{}).Where("group_id = ?", group.ID).
+		Updates(map[string]interface{}{"seq": 0, "prev_hash": "", "hash": ""})
+	db.Delete(&models.PointsChainHead{}, "group_id = ?", group.ID)
+
+	if err := BackfillHashChain(db); err != nil {
+		t.Fatalf("BackfillHashChain failed: %v", err)
+	}
+	report, _ := VerifyChain(db, group.ID)
+	if !report.OK() || report.HeadSeq != int64(len(logs)) {
+		t.Errorf("expected a clean backfilled chain of %d, got %+v", len(logs), report)
+	}
+
+	// New entries carry on from the backfilled head
+	poolSvc.db.Create(&models.PointsLog{ID: "extra", GroupID: group.ID, UserID: logs[0].UserID, Type: models.PointsLogAdminGrant})
+	report, _ = VerifyChain(db, group.ID)
+	if !report.OK() || report.HeadSeq != int64(len(logs)+1) {
+		t.Errorf("expected the chain to extend past the backfill, got %+v", report)
+	}
+}
@@ -0,0 +1,349 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/codyseavey/bets/models"
+)
+
+type ChallengeService struct {
+	db    *gorm.DB
+	pools *PoolService
+}
+
+func NewChallengeService(db *gorm.DB, pools *PoolService) *ChallengeService {
+	return &ChallengeService{db: db, pools: pools}
+}
+
+type CreateChallengeRequest struct {
+	OpponentID      string `json:"opponent_id" binding:"required"`
+	Title           string `json:"title" binding:"required"`
+	Description     string `json:"description"`
+	ChallengerPick  string `json:"challenger_pick" binding:"required"`
+	OpponentPick    string `json:"opponent_pick" binding:"required"`
+	ChallengerStake int    `json:"challenger_stake" binding:"required,gt=0"`
+	OpponentStake   int    `json:"opponent_stake" binding:"required,gt=0"`
+}
+
+// CreateChallenge proposes a 1v1 bet and escrows the challenger's stake. The
+// opponent's stake is only taken if they accept.
+func (s *ChallengeService) CreateChallenge(groupID, challengerID string, req CreateChallengeRequest) (*models.Challenge, error) {
+	if req.OpponentID == challengerID {
+		return nil, fmt.Errorf("you can't challenge yourself")
+	}
+	if req.ChallengerPick == req.OpponentPick {
+		return nil, fmt.Errorf("both sides can't pick the same outcome")
+	}
+
+	var opponentCount int64
+	s.db.Model(&models.GroupMember{}).Where("group_id = ? AND user_id = ?", groupID, req.OpponentID).Count(&opponentCount)
+	if opponentCount == 0 {
+		return nil, fmt.Errorf("opponent is not a member of this group")
+	}
+
+	tx := s.db.Begin()
+
+	pool, err := createPoolTx(tx, groupID, challengerID, models.PoolTypeChallenge, CreatePoolRequest{
+		Title:       req.Title,
+		Description: req.Description,
+		Options:     []string{req.ChallengerPick, req.OpponentPick},
+		// Only the two sides can see a 1v1
+		ParticipantIDs: []string{req.OpponentID},
+	})
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if _, err := s.pools.placeBetTx(tx, pool, challengerID, pool.Options[0].ID, req.ChallengerStake); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	challenge := &models.Challenge{
+		ID:                 uuid.New().String(),
+		GroupID:            groupID,
+		PoolID:             pool.ID,
+		ChallengerID:       challengerID,
+		OpponentID:         req.OpponentID,
+		ChallengerOptionID: pool.Options[0].ID,
+		OpponentOptionID:   pool.Options[1].ID,
+		ChallengerStake:    req.ChallengerStake,
+		OpponentStake:      req.OpponentStake,
+		Status:             models.ChallengeStatusPending,
+	}
+	if err := tx.Create(challenge).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to create challenge: %w", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetChallenge(challenge.ID)
+}
+
+func (s *ChallengeService) GetChallenge(challengeID string) (*models.Challenge, error) {
+	var challenge models.Challenge
+	err := s.db.
+		Preload("Pool.Options").
+		Preload("Challenger").
+		Preload("Opponent").
+		First(&challenge, "id = ?", challengeID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// GetChallengeFor returns a challenge to one of its two sides or a group
+// admin. Anyone else gets "not found", the same as a challenge in another group.
+func (s *ChallengeService) GetChallengeFor(groupID, challengeID, userID string, isAdmin bool) (*models.Challenge, error) {
+	challenge, err := s.GetChallenge(challengeID)
+	if err != nil || challenge.GroupID != groupID {
+		return nil, fmt.Errorf("challenge not found")
+	}
+	if !isAdmin && userID != challenge.ChallengerID && userID != challenge.OpponentID {
+		return nil, fmt.Errorf("challenge not found")
+	}
+	return challenge, nil
+}
+
+// GetUserChallenges lists challenges in a group that the user sent or received.
+func (s *ChallengeService) GetUserChallenges(groupID, userID, status string) ([]models.Challenge, error) {
+	query := s.db.
+		Where("group_id = ? AND (challenger_id = ? OR opponent_id = ?)", groupID, userID, userID).
+		Preload("Pool.Options").
+		Preload("Challenger").
+		Preload("Opponent").
+		Order("created_at DESC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var challenges []models.Challenge
+	if err := query.Find(&challenges).Error; err != nil {
+		return nil, err
+	}
+	return challenges, nil
+}
+
+// AcceptChallenge escrows the opponent's stake and locks the pool so the
+// matchup is fixed until it's resolved.
+func (s *ChallengeService) AcceptChallenge(challengeID, userID string) (*models.Challenge, error) {
+	tx := s.db.Begin()
+
+	challenge, pool, err := s.pendingChallengeTx(tx, challengeID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if challenge.OpponentID != userID {
+		tx.Rollback()
+		return nil, fmt.Errorf("only the challenged member can accept")
+	}
+
+	if _, err := s.pools.placeBetTx(tx, pool, userID, challenge.OpponentOptionID, challenge.OpponentStake); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := tx.Model(challenge).Updates(map[string]interface{}{
+		"status":       models.ChallengeStatusAccepted,
+		"responded_at": now,
+	}).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Model(pool).Update("status", models.PoolStatusLocked).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetChallenge(challengeID)
+}
+
+// DeclineChallenge is the opponent turning the challenge down. The
+// challenger's stake is refunded and the pool cancelled.
+func (s *ChallengeService) DeclineChallenge(challengeID, userID string) (*models.Challenge, error) {
+	return s.closePending(challengeID, userID, models.ChallengeStatusDeclined)
+}
+
+// WithdrawChallenge lets the challenger take back a challenge that hasn't been
+// answered yet.
+func (s *ChallengeService) WithdrawChallenge(challengeID, userID string) (*models.Challenge, error) {
+	return s.closePending(challengeID, userID, models.ChallengeStatusWithdrawn)
+}
+
+func (s *ChallengeService) closePending(challengeID, userID string, status models.ChallengeStatus) (*models.Challenge, error) {
+	tx := s.db.Begin()
+
+	challenge, pool, err := s.pendingChallengeTx(tx, challengeID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	note := "Challenge declined, stake refunded"
+	switch status {
+	case models.ChallengeStatusDeclined:
+		if challenge.OpponentID != userID {
+			tx.Rollback()
+			return nil, fmt.Errorf("only the challenged member can decline")
+		}
+	case models.ChallengeStatusWithdrawn:
+		if challenge.ChallengerID != userID {
+			tx.Rollback()
+			return nil, fmt.Errorf("only the challenger can withdraw")
+		}
+		note = "Challenge withdrawn, stake refunded"
+	}
+
+	if err := s.pools.refundPoolTx(tx, pool, note); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := tx.Model(challenge).Updates(map[string]interface{}{
+		"status":       status,
+		"responded_at": now,
+	}).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetChallenge(challengeID)
+}
+
+func (s *ChallengeService) pendingChallengeTx(tx *gorm.DB, challengeID string) (*models.Challenge, *models.Pool, error) {
+	var challenge models.Challenge
+	if err := tx.First(&challenge, "id = ?", challengeID).Error; err != nil {
+		return nil, nil, fmt.Errorf("challenge not found")
+	}
+	if challenge.Status != models.ChallengeStatusPending {
+		return nil, nil, fmt.Errorf("challenge is already %s", challenge.Status)
+	}
+
+	var pool models.Pool
+	if err := tx.First(&pool, "id = ?", challenge.PoolID).Error; err != nil {
+		return nil, nil, fmt.Errorf("pool not found")
+	}
+	if pool.Status != models.PoolStatusOpen {
+		// Pool was cancelled or resolved out from under the challenge
+		return nil, nil, fmt.Errorf("challenge pool is %s", pool.Status)
+	}
+
+	return &challenge, &pool, nil
+}
+
+type ReportResultRequest struct {
+	WinningOptionID string `json:"winning_option_id"`
+	Cancel          bool   `json:"cancel"`
+}
+
+// ReportResult records one side's view of how an accepted challenge ended.
+// Once both sides report the same result the pool is settled (or refunded, if
+// they both called it off). Until then either side can change their report.
+func (s *ChallengeService) ReportResult(challengeID, userID string, req ReportResultRequest) (*models.Challenge, error) {
+	if (req.WinningOptionID == "") == !req.Cancel {
+		return nil, fmt.Errorf("report either a winning option or cancel")
+	}
+
+	tx := s.db.Begin()
+
+	var challenge models.Challenge
+	if err := tx.First(&challenge, "id = ?", challengeID).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("challenge not found")
+	}
+	if challenge.Status != models.ChallengeStatusAccepted {
+		tx.Rollback()
+		return nil, fmt.Errorf("challenge is %s, not accepted", challenge.Status)
+	}
+	var pool models.Pool
+	if err := tx.First(&pool, "id = ?", challenge.PoolID).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("pool not found")
+	}
+	if pool.Status != models.PoolStatusLocked {
+		tx.Rollback()
+		return nil, fmt.Errorf("challenge pool is already %s", pool.Status)
+	}
+
+	result := models.ChallengeResultCancel
+	if !req.Cancel {
+		if req.WinningOptionID != challenge.ChallengerOptionID && req.WinningOptionID != challenge.OpponentOptionID {
+			tx.Rollback()
+			return nil, fmt.Errorf("invalid winning option")
+		}
+		result = req.WinningOptionID
+	}
+
+	column := "challenger_result"
+	switch userID {
+	case challenge.ChallengerID:
+		challenge.ChallengerResult = result
+	case challenge.OpponentID:
+		column = "opponent_result"
+		challenge.OpponentResult = result
+	default:
+		tx.Rollback()
+		return nil, fmt.Errorf("only the two sides can report a result")
+	}
+	if err := tx.Model(&challenge).Update(column, result).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if challenge.ChallengerResult == challenge.OpponentResult {
+		var err error
+		if result == models.ChallengeResultCancel {
+			err = s.pools.refundPoolTx(tx, &pool, "Challenge called off, stake refunded")
+		} else {
+			err = settlePoolTx(tx, &pool, result, nil, userID, "")
+		}
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetChallenge(challengeID)
+}
+
+// checkChallengeRefereeTx stops either side of a challenge settling it alone.
+// Being the pool's creator isn't enough, since that's the challenger: it takes
+// an admin who isn't one of the two sides, otherwise both sides go through
+// ReportResult. Other pool types pass.
+func checkChallengeRefereeTx(tx *gorm.DB, pool *models.Pool, userID string, isAdmin bool) error {
+	if pool.Type != models.PoolTypeChallenge {
+		return nil
+	}
+	var challenge models.Challenge
+	if err := tx.First(&challenge, "pool_id = ?", pool.ID).Error; err != nil {
+		return fmt.Errorf("challenge not found")
+	}
+	if !isAdmin || userID == challenge.ChallengerID || userID == challenge.OpponentID {
+		return fmt.Errorf("a challenge is settled by an admin who isn't part of it, or by both sides reporting the same result")
+	}
+	return nil
+}
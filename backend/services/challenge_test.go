@@ -0,0 +1,274 @@
+package services
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/codyseavey/bets/models"
+)
+
+func setupChallengeTest(t *testing.T) (*ChallengeService, *PoolService, func(userID string) int, *models.Group, *models.User, *models.User) {
+	t.Helper()
+	db, poolSvc, _, group, alice, bob := setupPoolTest(t)
+	balance := func(userID string) int {
+		var m models.GroupMember
+		db.Where("group_id = ? AND user_id = ?", group.ID, userID).First(&m)
+		return m.PointsBalance
+	}
+	return NewChallengeService(db, poolSvc), poolSvc, balance, group, alice, bob
+}
+
+func newTestChallenge(opponentID string) CreateChallengeRequest {
+	return CreateChallengeRequest{
+		OpponentID:      opponentID,
+		Title:           "Who wins the derby?",
+		ChallengerPick:  "Reds",
+		OpponentPick:    "Blues",
+		ChallengerStake: 200,
+		OpponentStake:   100,
+	}
+}
+
+func TestChallenge_AcceptAndResolve(t *testing.T) {
+	svc, poolSvc, balance, group, alice, bob := setupChallengeTest(t)
+
+	challenge, err := svc.CreateChallenge(group.ID, alice.ID, newTestChallenge(bob.ID))
+	if err != nil {
+		t.Fatalf("CreateChallenge failed: %v", err)
+	}
+	if challenge.Status != models.ChallengeStatusPending {
+		t.Errorf("expected pending, got %s", challenge.Status)
+	}
+	if challenge.Pool.Type != models.PoolTypeChallenge {
+		t.Errorf("expected challenge pool type, got %s", challenge.Pool.Type)
+	}
+	if balance(alice.ID) != 800 {
+		t.Errorf("expected challenger stake escrowed (800), got %d", balance(alice.ID))
+	}
+
+	// Can't be resolved before the opponent accepts
+	if err := poolSvc.ResolvePool(challenge.PoolID, challenge.ChallengerOptionID, alice.ID, false); err == nil {
+		t.Error("expected error resolving a pending challenge")
+	}
+
+	// Only the opponent can accept
+	if _, err := svc.AcceptChallenge(challenge.ID, alice.ID); err == nil {
+		t.Error("expected error when challenger accepts own challenge")
+	}
+
+	accepted, err := svc.AcceptChallenge(challenge.ID, bob.ID)
+	if err != nil {
+		t.Fatalf("AcceptChallenge failed: %v", err)
+	}
+	if accepted.Status != models.ChallengeStatusAccepted || accepted.Pool.Status != models.PoolStatusLocked {
+		t.Errorf("expected accepted challenge with locked pool, got %s/%s", accepted.Status, accepted.Pool.Status)
+	}
+	if balance(bob.ID) != 900 {
+		t.Errorf("expected opponent stake escrowed (900), got %d", balance(bob.ID))
+	}
+
+	// The challenger is the pool's creator and an admin, but that isn't enough
+	if err := poolSvc.ResolvePool(challenge.PoolID, challenge.ChallengerOptionID, alice.ID, true); err == nil {
+		t.Error("expected error when the challenger resolves their own challenge")
+	}
+	if err := poolSvc.CancelPool(challenge.PoolID, alice.ID, true); err == nil {
+		t.Error("expected error when the challenger cancels an accepted challenge")
+	}
+
+	if _, err := svc.ReportResult(challenge.ID, bob.ID, ReportResultRequest{WinningOptionID: challenge.OpponentOptionID}); err != nil {
+		t.Fatalf("ReportResult failed: %v", err)
+	}
+	if balance(bob.ID) != 900 {
+		t.Errorf("expected nothing paid on one report, got %d", balance(bob.ID))
+	}
+	resolved, err := svc.ReportResult(challenge.ID, alice.ID, ReportResultRequest{WinningOptionID: challenge.OpponentOptionID})
+	if err != nil {
+		t.Fatalf("ReportResult failed: %v", err)
+	}
+	if resolved.Pool.Status != models.PoolStatusResolved {
+		t.Errorf("expected the pool resolved once both sides agree, got %s", resolved.Pool.Status)
+	}
+	if balance(bob.ID) != 1200 {
+		t.Errorf("expected opponent to win both stakes (1200), got %d", balance(bob.ID))
+	}
+	if balance(alice.ID) != 800 {
+		t.Errorf("expected challenger to lose stake (800), got %d", balance(alice.ID))
+	}
+}
+
+func TestChallenge_DeclineRefundsChallenger(t *testing.T) {
+	svc, _, balance, group, alice, bob := setupChallengeTest(t)
+
+	challenge, _ := svc.CreateChallenge(group.ID, alice.ID, newTestChallenge(bob.ID))
+
+	declined, err := svc.DeclineChallenge(challenge.ID, bob.ID)
+	if err != nil {
+		t.Fatalf("DeclineChallenge failed: %v", err)
+	}
+	if declined.Status != models.ChallengeStatusDeclined || declined.Pool.Status != models.PoolStatusCancelled {
+		t.Errorf("expected declined challenge with cancelled pool, got %s/%s", declined.Status, declined.Pool.Status)
+	}
+	if balance(alice.ID) != 1000 {
+		t.Errorf("expected challenger refunded to 1000, got %d", balance(alice.ID))
+	}
+
+	if _, err := svc.AcceptChallenge(challenge.ID, bob.ID); err == nil {
+		t.Error("expected error accepting a declined challenge")
+	}
+}
+
+func TestChallenge_NoOutsideBets(t *testing.T) {
+	svc, poolSvc, _, group, alice, bob := setupChallengeTest(t)
+
+	challenge, _ := svc.CreateChallenge(group.ID, alice.ID, newTestChallenge(bob.ID))
+
+	_, err := poolSvc.PlaceBet(challenge.PoolID, bob.ID, PlaceBetRequest{
+		OptionID: challenge.OpponentOptionID,
+		Points:   100,
+	})
+	if err == nil {
+		t.Error("expected error placing a regular bet on a challenge pool")
+	}
+
+	if _, err := svc.CreateChallenge(group.ID, alice.ID, newTestChallenge(alice.ID)); err == nil {
+		t.Error("expected error challenging yourself")
+	}
+}
+
+func TestChallenge_HiddenFromRestOfGroup(t *testing.T) {
+	db, poolSvc, groupSvc, group, alice, bob := setupPoolTest(t)
+	svc := NewChallengeService(db, poolSvc)
+	carol := createTestUser(t, db, "carol", "Carol")
+	if _, err := groupSvc.JoinGroup(group.InviteCode, carol.ID); err != nil {
+		t.Fatalf("JoinGroup failed: %v", err)
+	}
+
+	challenge, err := svc.CreateChallenge(group.ID, alice.ID, newTestChallenge(bob.ID))
+	if err != nil {
+		t.Fatalf("CreateChallenge failed: %v", err)
+	}
+
+	for _, userID := range []string{alice.ID, bob.ID} {
+		if _, err := poolSvc.GetPool(challenge.PoolID, userID); err != nil {
+			t.Errorf("expected %s to see the challenge pool, got %v", userID, err)
+		}
+	}
+	if _, err := poolSvc.GetPool(challenge.PoolID, carol.ID); err == nil {
+		t.Error("expected the challenge pool to be hidden from the rest of the group")
+	}
+	if _, err := svc.GetChallengeFor(group.ID, challenge.ID, carol.ID, false); err == nil {
+		t.Error("expected the challenge to be hidden from the rest of the group")
+	}
+	for _, userID := range []string{alice.ID, bob.ID} {
+		if _, err := svc.GetChallengeFor(group.ID, challenge.ID, userID, false); err != nil {
+			t.Errorf("expected %s to see the challenge, got %v", userID, err)
+		}
+	}
+	if _, err := svc.GetChallengeFor(group.ID, challenge.ID, carol.ID, true); err != nil {
+		t.Errorf("expected an admin to see the challenge, got %v", err)
+	}
+}
+
+func TestChallenge_SettledByNeutralAdmin(t *testing.T) {
+	db, poolSvc, groupSvc, group, alice, bob := setupPoolTest(t)
+	svc := NewChallengeService(db, poolSvc)
+	carol := createTestUser(t, db, "carol", "Carol")
+	groupSvc.JoinGroup(group.InviteCode, carol.ID)
+
+	// Bob challenges Alice, who is the group's only admin
+	challenge, _ := svc.CreateChallenge(group.ID, bob.ID, newTestChallenge(alice.ID))
+	svc.AcceptChallenge(challenge.ID, alice.ID)
+
+	if err := poolSvc.ResolvePool(challenge.PoolID, challenge.OpponentOptionID, alice.ID, true); err == nil {
+		t.Error("expected error when an admin resolves a challenge they're part of")
+	}
+	if err := poolSvc.ResolvePool(challenge.PoolID, challenge.ChallengerOptionID, carol.ID, false); err == nil {
+		t.Error("expected error when a non-admin resolves someone else's challenge")
+	}
+
+	// Sides that disagree settle nothing
+	svc.ReportResult(challenge.ID, bob.ID, ReportResultRequest{WinningOptionID: challenge.ChallengerOptionID})
+	disputed, _ := svc.ReportResult(challenge.ID, alice.ID, ReportResultRequest{WinningOptionID: challenge.OpponentOptionID})
+	if disputed.Pool.Status != models.PoolStatusLocked {
+		t.Errorf("expected a disputed challenge to stay locked, got %s", disputed.Pool.Status)
+	}
+
+	if err := poolSvc.ResolvePool(challenge.PoolID, challenge.ChallengerOptionID, carol.ID, true); err != nil {
+		t.Fatalf("expected a neutral admin to resolve the challenge: %v", err)
+	}
+	assertReconciled(t, db, group.ID)
+}
+
+func TestChallenge_BothSidesCallItOff(t *testing.T) {
+	svc, _, balance, group, alice, bob := setupChallengeTest(t)
+
+	challenge, _ := svc.CreateChallenge(group.ID, alice.ID, newTestChallenge(bob.ID))
+	svc.AcceptChallenge(challenge.ID, bob.ID)
+
+	if _, err := svc.ReportResult(challenge.ID, alice.ID, ReportResultRequest{}); err == nil {
+		t.Error("expected error reporting neither a winner nor cancel")
+	}
+	svc.ReportResult(challenge.ID, alice.ID, ReportResultRequest{Cancel: true})
+	cancelled, err := svc.ReportResult(challenge.ID, bob.ID, ReportResultRequest{Cancel: true})
+	if err != nil {
+		t.Fatalf("ReportResult failed: %v", err)
+	}
+	if cancelled.Pool.Status != models.PoolStatusCancelled {
+		t.Errorf("expected the pool cancelled, got %s", cancelled.Pool.Status)
+	}
+	if balance(alice.ID) != 1000 || balance(bob.ID) != 1000 {
+		t.Errorf("expected both stakes refunded, got %d/%d", balance(alice.ID), balance(bob.ID))
+	}
+}
+
+func TestChallenge_CancellingPendingPoolCancelsChallenge(t *testing.T) {
+	svc, poolSvc, balance, group, alice, bob := setupChallengeTest(t)
+
+	challenge, _ := svc.CreateChallenge(group.ID, alice.ID, newTestChallenge(bob.ID))
+	if err := poolSvc.CancelPool(challenge.PoolID, alice.ID, false); err != nil {
+		t.Fatalf("CancelPool failed: %v", err)
+	}
+
+	cancelled, _ := svc.GetChallenge(challenge.ID)
+	if cancelled.Status != models.ChallengeStatusCancelled || cancelled.RespondedAt == nil {
+		t.Errorf("expected the challenge cancelled, got %s", cancelled.Status)
+	}
+	if balance(alice.ID) != 1000 {
+		t.Errorf("expected challenger refunded (1000), got %d", balance(alice.ID))
+	}
+	if _, err := svc.AcceptChallenge(challenge.ID, bob.ID); err == nil {
+		t.Error("expected error accepting a cancelled challenge")
+	}
+}
+
+func TestChallenge_ChallengerCannotUnbindRefereeOracle(t *testing.T) {
+	db, poolSvc, groupSvc, group, alice, bob := setupPoolTest(t)
+	svc := NewChallengeService(db, poolSvc)
+	oracleSvc := NewOracleService(db, poolSvc, nil)
+	oracleSvc.AllowHosts("127.0.0.1")
+	carol := createTestUser(t, db, "carol", "Carol")
+	groupSvc.JoinGroup(group.InviteCode, carol.ID)
+
+	feed := httptest.NewServer(&fakeFeed{body: `{}`})
+	defer feed.Close()
+
+	challenge, _ := svc.CreateChallenge(group.ID, bob.ID, newTestChallenge(carol.ID))
+	svc.AcceptChallenge(challenge.ID, carol.ID)
+
+	req := BindOracleRequest{URL: feed.URL + "/game.json", Selector: "winner"}
+	if _, err := oracleSvc.BindOracle(group.ID, challenge.PoolID, bob.ID, false, req); err == nil {
+		t.Error("expected error when the challenger binds an oracle")
+	}
+	if _, err := oracleSvc.BindOracle(group.ID, challenge.PoolID, alice.ID, true, req); err != nil {
+		t.Fatalf("expected a neutral admin to bind an oracle: %v", err)
+	}
+	if err := oracleSvc.UnbindOracle(group.ID, challenge.PoolID, bob.ID, false); err == nil {
+		t.Error("expected error when the challenger removes the referee's oracle")
+	}
+	if _, err := oracleSvc.GetOracle(group.ID, challenge.PoolID); err != nil {
+		t.Errorf("expected the oracle still bound, got %v", err)
+	}
+	if err := oracleSvc.UnbindOracle(group.ID, challenge.PoolID, alice.ID, true); err != nil {
+		t.Errorf("expected the neutral admin to remove it: %v", err)
+	}
+}
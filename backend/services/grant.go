@@ -0,0 +1,156 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/codyseavey/bets/models"
+)
+
+type BulkGrantRequest struct {
+	Amount       int    `json:"amount" binding:"required"`
+	Note         string `json:"note"`
+	Role         string `json:"role" binding:"omitempty,oneof=admin member"` // empty means every role
+	BalanceBelow *int   `json:"balance_below"`                               // only members with fewer points than this
+}
+
+// BulkGrant grants the same amount to every member matching the request's
+// filters in one transaction. Each member's admin_grant entry references the
+// returned batch so the whole thing can be undone.
+func (s *GroupService) BulkGrant(groupID, adminID string, req BulkGrantRequest) (*models.GrantBatch, error) {
+	tx := s.db.Begin()
+
+	batch, err := bulkGrantTx(tx, groupID, adminID, req)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	return batch, nil
+}
+
+// bulkGrantRecipientsTx returns the members a bulk grant would go to.
+func bulkGrantRecipientsTx(tx *gorm.DB, groupID string, req BulkGrantRequest) ([]string, error) {
+	query := tx.Model(&models.GroupMember{}).Where("group_id = ?", groupID)
+	if req.Role != "" {
+		query = query.Where("role = ?", req.Role)
+	}
+	if req.BalanceBelow != nil {
+		query = query.Where("points_balance < ?", *req.BalanceBelow)
+	}
+	var userIDs []string
+	if err := query.Order("joined_at").Pluck("user_id", &userIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(userIDs) == 0 {
+		return nil, fmt.Errorf("no members match")
+	}
+	return userIDs, nil
+}
+
+func bulkGrantTx(tx *gorm.DB, groupID, adminID string, req BulkGrantRequest) (*models.GrantBatch, error) {
+	userIDs, err := bulkGrantRecipientsTx(tx, groupID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := &models.GrantBatch{
+		ID:           uuid.New().String(),
+		GroupID:      groupID,
+		CreatedBy:    adminID,
+		Amount:       req.Amount,
+		Note:         req.Note,
+		Role:         req.Role,
+		BalanceBelow: req.BalanceBelow,
+		Recipients:   len(userIDs),
+		Total:        req.Amount * len(userIDs),
+	}
+	if err := tx.Create(batch).Error; err != nil {
+		return nil, fmt.Errorf("failed to create grant batch: %w", err)
+	}
+
+	for _, userID := range userIDs {
+		if err := creditMember(tx, groupID, userID, req.Amount, TreasuryAccount, models.PointsLogAdminGrant, batch.ID, req.Note); err != nil {
+			return nil, err
+		}
+	}
+	return batch, nil
+}
+
+func (s *GroupService) GetGrantBatches(groupID string) ([]models.GrantBatch, error) {
+	var batches []models.GrantBatch
+	err := s.db.Where("group_id = ?", groupID).Preload("Creator").Order("created_at DESC").Find(&batches).Error
+	return batches, err
+}
+
+// UndoGrantBatch takes a bulk grant back from everyone who got it. Points a
+// member has already spent can't be reclaimed, so nobody is taken below
+// zero; the batch records how much actually came back. Members who have left
+// since are skipped, as their balance already went back to the treasury.
+func (s *GroupService) UndoGrantBatch(groupID, batchID, adminID string) (*models.GrantBatch, error) {
+	tx := s.db.Begin()
+
+	batch, err := undoGrantBatchTx(tx, groupID, batchID, adminID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	return batch, nil
+}
+
+func undoGrantBatchTx(tx *gorm.DB, groupID, batchID, adminID string) (*models.GrantBatch, error) {
+	var batch models.GrantBatch
+	if err := tx.First(&batch, "id = ? AND group_id = ?", batchID, groupID).Error; err != nil {
+		return nil, fmt.Errorf("grant batch not found")
+	}
+	if batch.UndoneAt != nil {
+		return nil, fmt.Errorf("grant batch was already undone")
+	}
+
+	var grants []models.PointsLog
+	if err := tx.Where("group_id = ? AND type = ? AND reference_id = ?", groupID, models.PointsLogAdminGrant, batch.ID).
+		Find(&grants).Error; err != nil {
+		return nil, err
+	}
+
+	reclaimed := 0
+	for _, g := range grants {
+		var member models.GroupMember
+		if err := tx.Where("group_id = ? AND user_id = ?", groupID, g.UserID).First(&member).Error; err != nil {
+			continue
+		}
+		amount := g.Amount
+		note := "Grant undone"
+		if batch.Note != "" {
+			note = fmt.Sprintf("Grant undone: %s", batch.Note)
+		}
+		if amount > member.PointsBalance {
+			amount = max(member.PointsBalance, 0)
+			note += fmt.Sprintf(" (only %d of %d reclaimed, the rest was already spent)", amount, g.Amount)
+		}
+		if err := creditMember(tx, groupID, g.UserID, -amount, TreasuryAccount, models.PointsLogGrantUndo, batch.ID, note); err != nil {
+			return nil, err
+		}
+		reclaimed += amount
+	}
+
+	now := time.Now()
+	if err := tx.Model(&batch).Updates(map[string]interface{}{
+		"undone_by": adminID,
+		"undone_at": now,
+		"reclaimed": reclaimed,
+	}).Error; err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
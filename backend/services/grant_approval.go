@@ -0,0 +1,322 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/codyseavey/bets/models"
+)
+
+// Grants that mint or destroy more points than the group's ApproveAbove
+// threshold (and any grant that takes points away) become GrantApproval
+// requests instead of touching the ledger. A second admin has to approve
+// them. The Request* methods below apply the grant straight away when the
+// rule doesn't apply and return a pending approval when it does. Treasury
+// pool seeds, allowances, bailout policies and promotions to admin go through
+// the same approvals from their own services.
+
+// needsApproval reports whether a grant that changes the points in circulation
+// by total needs a second admin.
+func needsApproval(group *models.Group, total int) bool {
+	return group.ApproveAbove > 0 && (total < 0 || total > group.ApproveAbove)
+}
+
+func (s *GroupService) RequestGrant(groupID, adminID, targetUserID string, amount int, note string) (*models.GrantApproval, error) {
+	var group models.Group
+	if err := s.db.First(&group, "id = ?", groupID).Error; err != nil {
+		return nil, fmt.Errorf("group not found")
+	}
+	if !needsApproval(&group, amount) {
+		return nil, s.GrantPoints(groupID, targetUserID, amount, note)
+	}
+	if !isMemberTx(s.db, groupID, targetUserID) {
+		return nil, fmt.Errorf("member not found")
+	}
+
+	return createApproval(s.db, &models.GrantApproval{
+		GroupID:     groupID,
+		Kind:        models.GrantApprovalGrant,
+		RequestedBy: adminID,
+		UserID:      targetUserID,
+		Amount:      amount,
+		Note:        note,
+		Total:       amount,
+	})
+}
+
+func (s *GroupService) RequestBulkGrant(groupID, adminID string, req BulkGrantRequest) (*models.GrantBatch, *models.GrantApproval, error) {
+	var group models.Group
+	if err := s.db.First(&group, "id = ?", groupID).Error; err != nil {
+		return nil, nil, fmt.Errorf("group not found")
+	}
+	recipients, err := bulkGrantRecipientsTx(s.db, groupID, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	total := req.Amount * len(recipients)
+	if !needsApproval(&group, total) {
+		batch, err := s.BulkGrant(groupID, adminID, req)
+		return batch, nil, err
+	}
+
+	// Who matches the filters is worked out again when it's approved, and
+	// the approval fails if that no longer adds up to this total
+	approval, err := createApproval(s.db, &models.GrantApproval{
+		GroupID:      groupID,
+		Kind:         models.GrantApprovalBulk,
+		RequestedBy:  adminID,
+		Amount:       req.Amount,
+		Note:         req.Note,
+		Role:         req.Role,
+		BalanceBelow: req.BalanceBelow,
+		Total:        total,
+	})
+	return nil, approval, err
+}
+
+func (s *GroupService) RequestUndoGrantBatch(groupID, batchID, adminID string) (*models.GrantBatch, *models.GrantApproval, error) {
+	var group models.Group
+	if err := s.db.First(&group, "id = ?", groupID).Error; err != nil {
+		return nil, nil, fmt.Errorf("group not found")
+	}
+	var batch models.GrantBatch
+	if err := s.db.First(&batch, "id = ? AND group_id = ?", batchID, groupID).Error; err != nil {
+		return nil, nil, fmt.Errorf("grant batch not found")
+	}
+	if batch.UndoneAt != nil {
+		return nil, nil, fmt.Errorf("grant batch was already undone")
+	}
+	if !needsApproval(&group, -batch.Total) {
+		undone, err := s.UndoGrantBatch(groupID, batchID, adminID)
+		return undone, nil, err
+	}
+
+	approval, err := createApproval(s.db, &models.GrantApproval{
+		GroupID:     groupID,
+		Kind:        models.GrantApprovalUndo,
+		RequestedBy: adminID,
+		BatchID:     batch.ID,
+		Note:        batch.Note,
+		Total:       -batch.Total,
+	})
+	return nil, approval, err
+}
+
+// SetApproveAbove changes the group's approval threshold. Tightening the rule
+// (turning it on or lowering the threshold) takes effect at once, but needs
+// two admins to be workable; loosening it is itself held for approval, or a
+// lone admin could just switch it off first.
+func (s *GroupService) SetApproveAbove(groupID, adminID string, threshold int) (*models.GrantApproval, error) {
+	var group models.Group
+	if err := s.db.First(&group, "id = ?", groupID).Error; err != nil {
+		return nil, fmt.Errorf("group not found")
+	}
+	if threshold < 0 {
+		return nil, fmt.Errorf("threshold can't be negative")
+	}
+
+	current := group.ApproveAbove
+	loosening := current > 0 && (threshold == 0 || threshold > current)
+	if !loosening {
+		if threshold > 0 {
+			var admins int64
+			s.db.Model(&models.GroupMember{}).Where("group_id = ? AND role = ?", groupID, "admin").Count(&admins)
+			if admins < 2 {
+				return nil, fmt.Errorf("approvals need at least two admins; promote another member first")
+			}
+		}
+		return nil, s.db.Model(&models.Group{}).Where("id = ?", groupID).Update("approve_above", threshold).Error
+	}
+
+	return createApproval(s.db, &models.GrantApproval{
+		GroupID:     groupID,
+		Kind:        models.GrantApprovalThreshold,
+		RequestedBy: adminID,
+		Amount:      threshold,
+	})
+}
+
+// GetAdminIDs lists the group's admins, who are the ones told about approval
+// requests.
+func (s *GroupService) GetAdminIDs(groupID string) []string {
+	var ids []string
+	s.db.Model(&models.GroupMember{}).Where("group_id = ? AND role = ?", groupID, "admin").Pluck("user_id", &ids)
+	return ids
+}
+
+func createApproval(db *gorm.DB, approval *models.GrantApproval) (*models.GrantApproval, error) {
+	approval.ID = uuid.New().String()
+	approval.Status = models.GrantApprovalPending
+	if err := db.Create(approval).Error; err != nil {
+		return nil, fmt.Errorf("failed to create approval request: %w", err)
+	}
+	return getGrantApproval(db, approval.GroupID, approval.ID)
+}
+
+func (s *GroupService) GetGrantApproval(groupID, approvalID string) (*models.GrantApproval, error) {
+	return getGrantApproval(s.db, groupID, approvalID)
+}
+
+func getGrantApproval(db *gorm.DB, groupID, approvalID string) (*models.GrantApproval, error) {
+	var approval models.GrantApproval
+	if err := db.Preload("Requester").First(&approval, "id = ? AND group_id = ?", approvalID, groupID).Error; err != nil {
+		return nil, fmt.Errorf("approval request not found")
+	}
+	return &approval, nil
+}
+
+func (s *GroupService) GetGrantApprovals(groupID, status string) ([]models.GrantApproval, error) {
+	query := s.db.Where("group_id = ?", groupID).Preload("Requester").Order("created_at DESC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var approvals []models.GrantApproval
+	err := query.Find(&approvals).Error
+	return approvals, err
+}
+
+// ApproveGrant carries out a pending request. The approving admin must not be
+// the one who asked for it.
+func (s *GroupService) ApproveGrant(groupID, approvalID, adminID string) (*models.GrantApproval, error) {
+	tx := s.db.Begin()
+
+	var approval models.GrantApproval
+	if err := tx.First(&approval, "id = ? AND group_id = ?", approvalID, groupID).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("approval request not found")
+	}
+	if approval.Status != models.GrantApprovalPending {
+		tx.Rollback()
+		return nil, fmt.Errorf("approval request is %s", approval.Status)
+	}
+	if approval.RequestedBy == adminID {
+		tx.Rollback()
+		return nil, fmt.Errorf("a different admin has to approve this")
+	}
+
+	var refID string
+	switch approval.Kind {
+	case models.GrantApprovalGrant:
+		if err := grantTx(tx, groupID, approval.UserID, approval.Amount, approval.ID, approval.Note); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	case models.GrantApprovalBulk:
+		batch, err := bulkGrantTx(tx, groupID, approval.RequestedBy, BulkGrantRequest{
+			Amount:       approval.Amount,
+			Note:         approval.Note,
+			Role:         approval.Role,
+			BalanceBelow: approval.BalanceBelow,
+		})
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if batch.Total != approval.Total {
+			// Different members match the filters now than when it was asked for
+			tx.Rollback()
+			return nil, fmt.Errorf("this grant would now total %d points, not the %d requested; reject it and ask again", batch.Total, approval.Total)
+		}
+		refID = batch.ID
+	case models.GrantApprovalUndo:
+		if _, err := undoGrantBatchTx(tx, groupID, approval.BatchID, approval.RequestedBy); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		refID = approval.BatchID
+	case models.GrantApprovalThreshold:
+		if err := tx.Model(&models.Group{}).Where("id = ?", groupID).Update("approve_above", approval.Amount).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	case models.GrantApprovalSeed:
+		seed, err := seedPoolTx(tx, groupID, approval.PoolID, approval.RequestedBy, true, SeedPoolRequest{
+			Amount:       approval.Amount,
+			FromTreasury: true,
+		})
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		refID = seed.ID
+	case models.GrantApprovalAllowance:
+		var req SetAllowanceRequest
+		if err := json.Unmarshal([]byte(approval.Settings), &req); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if _, err := setAllowanceTx(tx, groupID, approval.RequestedBy, req); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	case models.GrantApprovalBailout:
+		var req SetBailoutPolicyRequest
+		if err := json.Unmarshal([]byte(approval.Settings), &req); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if _, err := setBailoutPolicyTx(tx, groupID, approval.RequestedBy, req); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	case models.GrantApprovalPromote:
+		if err := setMemberRoleTx(tx, groupID, approval.UserID, approval.Role); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := reviewApprovalTx(tx, &approval, models.GrantApprovalApproved, adminID, refID); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	return s.GetGrantApproval(groupID, approvalID)
+}
+
+// RejectGrant turns a pending request down. If the admin who asked for it
+// rejects it, it's recorded as cancelled instead.
+func (s *GroupService) RejectGrant(groupID, approvalID, adminID string) (*models.GrantApproval, error) {
+	tx := s.db.Begin()
+
+	var approval models.GrantApproval
+	if err := tx.First(&approval, "id = ? AND group_id = ?", approvalID, groupID).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("approval request not found")
+	}
+	if approval.Status != models.GrantApprovalPending {
+		tx.Rollback()
+		return nil, fmt.Errorf("approval request is %s", approval.Status)
+	}
+
+	status := models.GrantApprovalRejected
+	if approval.RequestedBy == adminID {
+		status = models.GrantApprovalCancelled
+	}
+	if err := reviewApprovalTx(tx, &approval, status, adminID, ""); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	return s.GetGrantApproval(groupID, approvalID)
+}
+
+func reviewApprovalTx(tx *gorm.DB, approval *models.GrantApproval, status models.GrantApprovalStatus, adminID, refID string) error {
+	now := time.Now()
+	return tx.Model(approval).Updates(map[string]interface{}{
+		"status":       status,
+		"reviewed_by":  adminID,
+		"reviewed_at":  now,
+		"reference_id": refID,
+	}).Error
+}
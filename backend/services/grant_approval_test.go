@@ -0,0 +1,271 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/codyseavey/bets/models"
+)
+
+func TestGrantApprovalRule(t *testing.T) {
+	db, poolSvc, groupSvc, group, alice, bob := setupPoolTest(t)
+	carol := createTestUser(t, db, "carol", "Carol")
+	groupSvc.JoinGroup(group.InviteCode, carol.ID)
+
+	if _, err := groupSvc.SetApproveAbove(group.ID, alice.ID, 500); err == nil {
+		t.Error("expected error turning approvals on with a single admin")
+	}
+	if _, err := groupSvc.SetMemberRole(group.ID, alice.ID, bob.ID, "admin"); err != nil {
+		t.Fatalf("SetMemberRole failed: %v", err)
+	}
+	if approval, err := groupSvc.SetApproveAbove(group.ID, alice.ID, 500); err != nil || approval != nil {
+		t.Fatalf("expected the rule turned on at once, got %+v, %v", approval, err)
+	}
+	if _, err := groupSvc.SetMemberRole(group.ID, alice.ID, bob.ID, "member"); err == nil {
+		t.Error("expected error dropping to one admin while approvals are on")
+	}
+
+	// Small grants go straight through
+	if approval, err := groupSvc.RequestGrant(group.ID, alice.ID, carol.ID, 500, "small"); err != nil || approval != nil {
+		t.Errorf("expected a grant at the threshold to apply, got %+v, %v", approval, err)
+	}
+
+	// Large and negative ones wait
+	big, err := groupSvc.RequestGrant(group.ID, alice.ID, carol.ID, 501, "big")
+	if err != nil || big == nil || big.Status != models.GrantApprovalPending {
+		t.Fatalf("expected a pending approval, got %+v, %v", big, err)
+	}
+	negative, _ := groupSvc.RequestGrant(group.ID, alice.ID, carol.ID, -10, "fine")
+	if negative == nil {
+		t.Fatal("expected a negative grant to need approval")
+	}
+	if got := memberBalance(t, poolSvc, group.ID, carol.ID); got != 1500 {
+		t.Errorf("expected the ledger untouched by pending grants, got %d", got)
+	}
+
+	if _, err := groupSvc.ApproveGrant(group.ID, big.ID, alice.ID); err == nil {
+		t.Error("expected error approving your own request")
+	}
+	approved, err := groupSvc.ApproveGrant(group.ID, big.ID, bob.ID)
+	if err != nil {
+		t.Fatalf("ApproveGrant failed: %v", err)
+	}
+	if approved.Status != models.GrantApprovalApproved || approved.ReviewedBy != bob.ID || approved.ReviewedAt == nil {
+		t.Errorf("expected the approval trail recorded, got %+v", approved)
+	}
+	if got := memberBalance(t, poolSvc, group.ID, carol.ID); got != 2001 {
+		t.Errorf("expected Carol at 2001 after approval, got %d", got)
+	}
+	var logEntry models.PointsLog
+	db.Where("reference_id = ?", big.ID).First(&logEntry)
+	if logEntry.Amount != 501 || logEntry.Type != models.PointsLogAdminGrant {
+		t.Errorf("expected the grant entry to reference the approval, got %+v", logEntry)
+	}
+	if _, err := groupSvc.ApproveGrant(group.ID, big.ID, bob.ID); err == nil {
+		t.Error("expected error approving twice")
+	}
+
+	rejected, err := groupSvc.RejectGrant(group.ID, negative.ID, bob.ID)
+	if err != nil || rejected.Status != models.GrantApprovalRejected {
+		t.Errorf("expected rejected, got %+v, %v", rejected, err)
+	}
+	cancelled, _ := groupSvc.RequestGrant(group.ID, alice.ID, carol.ID, 9000, "typo")
+	cancelled, err = groupSvc.RejectGrant(group.ID, cancelled.ID, alice.ID)
+	if err != nil || cancelled.Status != models.GrantApprovalCancelled {
+		t.Errorf("expected the requester's own rejection recorded as cancelled, got %+v, %v", cancelled, err)
+	}
+
+	pending, _ := groupSvc.GetGrantApprovals(group.ID, string(models.GrantApprovalPending))
+	if len(pending) != 0 {
+		t.Errorf("expected nothing pending, got %d", len(pending))
+	}
+
+	assertReconciled(t, db, group.ID)
+}
+
+func TestGrantApprovalCoversBulkUndoAndThreshold(t *testing.T) {
+	db, poolSvc, groupSvc, group, alice, bob := setupPoolTest(t)
+	groupSvc.SetMemberRole(group.ID, alice.ID, bob.ID, "admin")
+	groupSvc.SetApproveAbove(group.ID, alice.ID, 150)
+
+	// 2 x 50 is under the threshold
+	batch, approval, err := groupSvc.RequestBulkGrant(group.ID, alice.ID, BulkGrantRequest{Amount: 50})
+	if err != nil || batch == nil || approval != nil {
+		t.Fatalf("expected a small bulk grant to apply, got %+v, %+v, %v", batch, approval, err)
+	}
+
+	// Undoing it destroys points, so it waits
+	_, undo, err := groupSvc.RequestUndoGrantBatch(group.ID, batch.ID, alice.ID)
+	if err != nil || undo == nil {
+		t.Fatalf("expected the undo to need approval, got %+v, %v", undo, err)
+	}
+	if _, err := groupSvc.ApproveGrant(group.ID, undo.ID, bob.ID); err != nil {
+		t.Fatalf("ApproveGrant for undo failed: %v", err)
+	}
+	if got := memberBalance(t, poolSvc, group.ID, alice.ID); got != 1000 {
+		t.Errorf("expected Alice back at 1000, got %d", got)
+	}
+
+	// 2 x 100 is over it
+	_, bulk, _ := groupSvc.RequestBulkGrant(group.ID, bob.ID, BulkGrantRequest{Amount: 100, Note: "Holiday"})
+	if bulk == nil || bulk.Total != 200 {
+		t.Fatalf("expected a pending bulk grant of 200, got %+v", bulk)
+	}
+	approved, err := groupSvc.ApproveGrant(group.ID, bulk.ID, alice.ID)
+	if err != nil || approved.ReferenceID == "" {
+		t.Fatalf("expected the approval to point at the new batch, got %+v, %v", approved, err)
+	}
+	if got := memberBalance(t, poolSvc, group.ID, bob.ID); got != 1100 {
+		t.Errorf("expected Bob at 1100, got %d", got)
+	}
+
+	// Loosening the rule is itself held; tightening isn't
+	loosen, err := groupSvc.SetApproveAbove(group.ID, alice.ID, 0)
+	if err != nil || loosen == nil {
+		t.Fatalf("expected turning the rule off to need approval, got %+v, %v", loosen, err)
+	}
+	if tighten, err := groupSvc.SetApproveAbove(group.ID, alice.ID, 100); err != nil || tighten != nil {
+		t.Errorf("expected lowering the threshold to apply at once, got %+v, %v", tighten, err)
+	}
+	groupSvc.ApproveGrant(group.ID, loosen.ID, bob.ID)
+	updated, _ := groupSvc.GetGroup(group.ID)
+	if updated.ApproveAbove != 0 {
+		t.Errorf("expected the rule off after approval, got %d", updated.ApproveAbove)
+	}
+
+	assertReconciled(t, db, group.ID)
+}
+
+func TestSetMemberRole(t *testing.T) {
+	_, _, groupSvc, group, alice, bob := setupPoolTest(t)
+
+	if _, err := groupSvc.SetMemberRole(group.ID, alice.ID, alice.ID, "member"); err == nil {
+		t.Error("expected error demoting the only admin")
+	}
+	if _, err := groupSvc.SetMemberRole(group.ID, alice.ID, bob.ID, "owner"); err == nil {
+		t.Error("expected error with an unknown role")
+	}
+	if _, err := groupSvc.SetMemberRole(group.ID, alice.ID, "nobody", "admin"); err == nil {
+		t.Error("expected error for a non-member")
+	}
+	groupSvc.SetMemberRole(group.ID, alice.ID, bob.ID, "admin")
+	if _, err := groupSvc.SetMemberRole(group.ID, alice.ID, alice.ID, "member"); err != nil {
+		t.Errorf("expected demotion to work with another admin around, got %v", err)
+	}
+	if ids := groupSvc.GetAdminIDs(group.ID); len(ids) != 1 || ids[0] != bob.ID {
+		t.Errorf("expected Bob as the only admin, got %v", ids)
+	}
+}
+
+func TestApprovalCoversOtherWaysToMintPoints(t *testing.T) {
+	db, poolSvc, groupSvc, group, alice, bob := setupPoolTest(t)
+	carol := createTestUser(t, db, "carol", "Carol")
+	groupSvc.JoinGroup(group.InviteCode, carol.ID)
+	groupSvc.SetMemberRole(group.ID, alice.ID, bob.ID, "admin")
+	groupSvc.SetApproveAbove(group.ID, alice.ID, 500)
+
+	// Treasury seeds
+	pool, _ := poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{Title: "Rigged", Options: []string{"Me", "You"}})
+	if seed, approval, err := poolSvc.SeedPool(group.ID, pool.ID, alice.ID, true, SeedPoolRequest{Amount: 100, FromTreasury: true}); err != nil || seed == nil || approval != nil {
+		t.Errorf("expected a small treasury seed to apply, got %+v, %+v, %v", seed, approval, err)
+	}
+	_, seedApproval, err := poolSvc.SeedPool(group.ID, pool.ID, alice.ID, true, SeedPoolRequest{Amount: 1000000, FromTreasury: true})
+	if err != nil || seedApproval == nil || seedApproval.Kind != models.GrantApprovalSeed {
+		t.Fatalf("expected a large treasury seed to wait, got %+v, %v", seedApproval, err)
+	}
+	approved, err := groupSvc.ApproveGrant(group.ID, seedApproval.ID, bob.ID)
+	if err != nil {
+		t.Fatalf("ApproveGrant failed: %v", err)
+	}
+	var seed models.PoolSeed
+	if err := db.First(&seed, "id = ?", approved.ReferenceID).Error; err != nil || seed.Amount != 1000000 || seed.SeededBy != alice.ID {
+		t.Errorf("expected the approved seed linked from the approval, got %+v, %v", seed, err)
+	}
+
+	// Allowances
+	allowanceSvc := NewAllowanceService(db, nil)
+	_, allowanceApproval, err := allowanceSvc.SetAllowance(group.ID, alice.ID, SetAllowanceRequest{Amount: 10000, Schedule: models.AllowanceDaily, Claimable: true})
+	if err != nil || allowanceApproval == nil {
+		t.Fatalf("expected a large allowance to wait, got %+v, %v", allowanceApproval, err)
+	}
+	if _, err := allowanceSvc.GetAllowance(group.ID, carol.ID); err == nil {
+		t.Error("expected no allowance before approval")
+	}
+	groupSvc.ApproveGrant(group.ID, allowanceApproval.ID, bob.ID)
+	if status, err := allowanceSvc.GetAllowance(group.ID, carol.ID); err != nil || status.Amount != 10000 || !status.Claimable {
+		t.Errorf("expected the allowance set once approved, got %+v, %v", status, err)
+	}
+
+	// Bailouts
+	bailoutSvc := NewBailoutService(db)
+	_, bailoutApproval, err := bailoutSvc.SetPolicy(group.ID, alice.ID, SetBailoutPolicyRequest{Threshold: 100, ResetTo: 50000, MaxPerSeason: 9})
+	if err != nil || bailoutApproval == nil {
+		t.Fatalf("expected a large bailout reset to wait, got %+v, %v", bailoutApproval, err)
+	}
+	groupSvc.ApproveGrant(group.ID, bailoutApproval.ID, bob.ID)
+	if status, err := bailoutSvc.GetStatus(group.ID, carol.ID); err != nil || status.ResetTo != 50000 {
+		t.Errorf("expected the bailout policy set once approved, got %+v, %v", status, err)
+	}
+
+	// Promotions
+	promotion, err := groupSvc.SetMemberRole(group.ID, alice.ID, carol.ID, "admin")
+	if err != nil || promotion == nil || promotion.Kind != models.GrantApprovalPromote {
+		t.Fatalf("expected a promotion to wait, got %+v, %v", promotion, err)
+	}
+	if ids := groupSvc.GetAdminIDs(group.ID); len(ids) != 2 {
+		t.Errorf("expected Carol not yet an admin, got %v", ids)
+	}
+	if _, err := groupSvc.ApproveGrant(group.ID, promotion.ID, alice.ID); err == nil {
+		t.Error("expected error approving your own promotion request")
+	}
+	groupSvc.ApproveGrant(group.ID, promotion.ID, bob.ID)
+	if ids := groupSvc.GetAdminIDs(group.ID); len(ids) != 3 {
+		t.Errorf("expected Carol an admin once approved, got %v", ids)
+	}
+
+	assertReconciled(t, db, group.ID)
+}
+
+func TestApprovalCountsEveryMemberPaid(t *testing.T) {
+	db, _, groupSvc, group, alice, bob := setupPoolTest(t)
+	carol := createTestUser(t, db, "carol", "Carol")
+	groupSvc.JoinGroup(group.InviteCode, carol.ID)
+	groupSvc.SetMemberRole(group.ID, alice.ID, bob.ID, "admin")
+	groupSvc.SetApproveAbove(group.ID, alice.ID, 500)
+
+	// 200 a day is under the threshold, but three members get it
+	_, allowanceApproval, err := NewAllowanceService(db, nil).SetAllowance(group.ID, alice.ID, SetAllowanceRequest{Amount: 200, Schedule: models.AllowanceDaily})
+	if err != nil || allowanceApproval == nil || allowanceApproval.Total != 600 {
+		t.Errorf("expected the allowance to wait with a total of 600, got %+v, %v", allowanceApproval, err)
+	}
+
+	// Likewise a bailout any member could claim twice
+	_, bailoutApproval, err := NewBailoutService(db).SetPolicy(group.ID, alice.ID, SetBailoutPolicyRequest{Threshold: 50, ResetTo: 100, MaxPerSeason: 2})
+	if err != nil || bailoutApproval == nil || bailoutApproval.Total != 600 {
+		t.Errorf("expected the bailout policy to wait with a total of 600, got %+v, %v", bailoutApproval, err)
+	}
+}
+
+func TestBulkApprovalRefusesChangedRecipients(t *testing.T) {
+	db, _, groupSvc, group, alice, bob := setupPoolTest(t)
+	groupSvc.SetMemberRole(group.ID, alice.ID, bob.ID, "admin")
+	groupSvc.SetApproveAbove(group.ID, alice.ID, 500)
+
+	_, approval, err := groupSvc.RequestBulkGrant(group.ID, alice.ID, BulkGrantRequest{Amount: 300, BalanceBelow: intPtr(2000)})
+	if err != nil || approval == nil || approval.Total != 600 {
+		t.Fatalf("expected a pending bulk grant of 600, got %+v, %v", approval, err)
+	}
+
+	// Two more members match the filter by the time it's approved
+	for _, name := range []string{"carol", "dave"} {
+		u := createTestUser(t, db, name, name)
+		groupSvc.JoinGroup(group.InviteCode, u.ID)
+	}
+	if _, err := groupSvc.ApproveGrant(group.ID, approval.ID, bob.ID); err == nil {
+		t.Error("expected error approving a bulk grant that now mints more than requested")
+	}
+	var minted int64
+	db.Model(&models.PointsLog{}).Where("group_id = ? AND type = ?", group.ID, models.PointsLogAdminGrant).Count(&minted)
+	if minted != 0 {
+		t.Errorf("expected nothing granted, got %d entries", minted)
+	}
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/codyseavey/bets/models"
+)
+
+func TestBulkGrant(t *testing.T) {
+	db, poolSvc, groupSvc, group, alice, bob := setupPoolTest(t)
+	carol := createTestUser(t, db, "carol", "Carol")
+	groupSvc.JoinGroup(group.InviteCode, carol.ID)
+	groupSvc.GrantPoints(group.ID, carol.ID, -800, "rough week")
+
+	// Everyone
+	batch, err := groupSvc.BulkGrant(group.ID, alice.ID, BulkGrantRequest{Amount: 100, Note: "Holiday bonus"})
+	if err != nil {
+		t.Fatalf("BulkGrant failed: %v", err)
+	}
+	if batch.Recipients != 3 || batch.Total != 300 {
+		t.Errorf("expected 3 recipients and 300 total, got %+v", batch)
+	}
+	var logged int64
+	db.Model(&models.PointsLog{}).Where("reference_id = ? AND type = ?", batch.ID, models.PointsLogAdminGrant).Count(&logged)
+	if logged != 3 {
+		t.Errorf("expected 3 grant entries referencing the batch, got %d", logged)
+	}
+
+	// Only regular members
+	byRole, err := groupSvc.BulkGrant(group.ID, alice.ID, BulkGrantRequest{Amount: 10, Role: "member"})
+	if err != nil || byRole.Recipients != 2 {
+		t.Errorf("expected Bob and Carol granted by role, got %+v, %v", byRole, err)
+	}
+
+	// Only the broke
+	below := 500
+	broke, err := groupSvc.BulkGrant(group.ID, alice.ID, BulkGrantRequest{Amount: 50, BalanceBelow: &below})
+	if err != nil || broke.Recipients != 1 {
+		t.Errorf("expected only Carol under 500, got %+v, %v", broke, err)
+	}
+	if got := memberBalance(t, poolSvc, group.ID, carol.ID); got != 360 {
+		t.Errorf("expected Carol at 360, got %d", got)
+	}
+
+	none := 0
+	if _, err := groupSvc.BulkGrant(group.ID, alice.ID, BulkGrantRequest{Amount: 50, BalanceBelow: &none}); err == nil {
+		t.Error("expected error when no members match")
+	}
+
+	if got := memberBalance(t, poolSvc, group.ID, bob.ID); got != 1110 {
+		t.Errorf("expected Bob at 1110, got %d", got)
+	}
+
+	batches, _ := groupSvc.GetGrantBatches(group.ID)
+	if len(batches) != 3 {
+		t.Errorf("expected 3 batches listed, got %d", len(batches))
+	}
+
+	assertReconciled(t, db, group.ID)
+}
+
+func TestUndoGrantBatch(t *testing.T) {
+	db, poolSvc, groupSvc, group, alice, bob := setupPoolTest(t)
+
+	batch, _ := groupSvc.BulkGrant(group.ID, alice.ID, BulkGrantRequest{Amount: 200, Note: "Oops, wrong amount"})
+
+	// Bob spends most of his balance before the undo
+	groupSvc.GrantPoints(group.ID, bob.ID, -1100, "spent")
+
+	undone, err := groupSvc.UndoGrantBatch(group.ID, batch.ID, alice.ID)
+	if err != nil {
+		t.Fatalf("UndoGrantBatch failed: %v", err)
+	}
+	if undone.UndoneAt == nil || undone.UndoneBy != alice.ID || undone.Reclaimed != 300 {
+		t.Errorf("expected the batch marked undone with 300 reclaimed, got %+v", undone)
+	}
+	if got := memberBalance(t, poolSvc, group.ID, alice.ID); got != 1000 {
+		t.Errorf("expected Alice back at 1000, got %d", got)
+	}
+	if got := memberBalance(t, poolSvc, group.ID, bob.ID); got != 0 {
+		t.Errorf("expected Bob taken to 0 but not below, got %d", got)
+	}
+
+	var partial models.PointsLog
+	db.Where("user_id = ? AND type = ?", bob.ID, models.PointsLogGrantUndo).First(&partial)
+	if partial.Amount != -100 || partial.Note != "Grant undone: Oops, wrong amount (only 100 of 200 reclaimed, the rest was already spent)" {
+		t.Errorf("unexpected undo entry %+v", partial)
+	}
+
+	if _, err := groupSvc.UndoGrantBatch(group.ID, batch.ID, alice.ID); err == nil {
+		t.Error("expected error undoing twice")
+	}
+	if _, err := groupSvc.UndoGrantBatch("other-group", batch.ID, alice.ID); err == nil {
+		t.Error("expected error undoing a batch from another group")
+	}
+
+	assertReconciled(t, db, group.ID)
+}
@@ -41,29 +41,24 @@ func (s *GroupService) CreateGroup(name string, defaultPoints int, userID string
 	}
 
 	member := &models.GroupMember{
-		GroupID:       group.ID,
-		UserID:        userID,
-		Role:          "admin",
-		PointsBalance: defaultPoints,
-		JoinedAt:      time.Now(),
+		GroupID:  group.ID,
+		UserID:   userID,
+		Role:     "admin",
+		JoinedAt: time.Now(),
 	}
 	if err := tx.Create(member).Error; err != nil {
 		tx.Rollback()
 		return nil, fmt.Errorf("failed to add creator as member: %w", err)
 	}
 
-	pointsLog := &models.PointsLog{
-		ID:      uuid.New().String(),
-		GroupID: group.ID,
-		UserID:  userID,
-		Amount:  defaultPoints,
-		Type:    models.PointsLogInitial,
-		Note:    "Initial points on group creation",
-	}
-	if err := tx.Create(pointsLog).Error; err != nil {
+	if err := creditMember(tx, group.ID, userID, defaultPoints, TreasuryAccount, models.PointsLogInitial, "", "Initial points on group creation"); err != nil {
 		tx.Rollback()
 		return nil, fmt.Errorf("failed to log initial points: %w", err)
 	}
+	if err := addSeasonStartTx(tx, group.ID, userID, defaultPoints); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
 
 	if err := tx.Commit().Error; err != nil {
 		return nil, fmt.Errorf("failed to commit: %w", err)
@@ -110,29 +105,24 @@ func (s *GroupService) JoinGroup(inviteCode, userID string) (*models.Group, erro
 	tx := s.db.Begin()
 
 	member := &models.GroupMember{
-		GroupID:       group.ID,
-		UserID:        userID,
-		Role:          "member",
-		PointsBalance: group.DefaultPoints,
-		JoinedAt:      time.Now(),
+		GroupID:  group.ID,
+		UserID:   userID,
+		Role:     "member",
+		JoinedAt: time.Now(),
 	}
 	if err := tx.Create(member).Error; err != nil {
 		tx.Rollback()
 		return nil, fmt.Errorf("failed to join group: %w", err)
 	}
 
-	pointsLog := &models.PointsLog{
-		ID:      uuid.New().String(),
-		GroupID: group.ID,
-		UserID:  userID,
-		Amount:  group.DefaultPoints,
-		Type:    models.PointsLogInitial,
-		Note:    "Initial points on joining group",
-	}
-	if err := tx.Create(pointsLog).Error; err != nil {
+	if err := creditMember(tx, group.ID, userID, group.DefaultPoints, TreasuryAccount, models.PointsLogInitial, "", "Initial points on joining group"); err != nil {
 		tx.Rollback()
 		return nil, fmt.Errorf("failed to log initial points: %w", err)
 	}
+	if err := addSeasonStartTx(tx, group.ID, userID, group.DefaultPoints); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
 
 	if err := tx.Commit().Error; err != nil {
 		return nil, fmt.Errorf("failed to commit: %w", err)
@@ -141,37 +131,60 @@ func (s *GroupService) JoinGroup(inviteCode, userID string) (*models.Group, erro
 	return &group, nil
 }
 
-func (s *GroupService) UpdateGroup(groupID, name string, defaultPoints int) error {
-	return s.db.Model(&models.Group{}).Where("id = ?", groupID).Updates(map[string]interface{}{
+// UpdateGroup saves the group settings. A nil transferLimit leaves the
+// current limit alone.
+func (s *GroupService) UpdateGroup(groupID, name string, defaultPoints int, transferLimit, cashRate *int) error {
+	updates := map[string]interface{}{
 		"name":           name,
 		"default_points": defaultPoints,
-	}).Error
+	}
+	if transferLimit != nil {
+		updates["transfer_limit"] = *transferLimit
+	}
+	if cashRate != nil {
+		updates["cash_rate"] = *cashRate
+	}
+	return s.db.Model(&models.Group{}).Where("id = ?", groupID).Updates(updates).Error
 }
 
 func (s *GroupService) GrantPoints(groupID, targetUserID string, amount int, note string) error {
 	tx := s.db.Begin()
 
-	result := tx.Model(&models.GroupMember{}).
-		Where("group_id = ? AND user_id = ?", groupID, targetUserID).
-		Update("points_balance", gorm.Expr("points_balance + ?", amount))
-	if result.Error != nil {
+	if err := grantTx(tx, groupID, targetUserID, amount, "", note); err != nil {
 		tx.Rollback()
-		return result.Error
+		return err
 	}
-	if result.RowsAffected == 0 {
-		tx.Rollback()
+
+	return tx.Commit().Error
+}
+
+func grantTx(tx *gorm.DB, groupID, targetUserID string, amount int, refID, note string) error {
+	if !isMemberTx(tx, groupID, targetUserID) {
 		return fmt.Errorf("member not found")
 	}
+	return creditMember(tx, groupID, targetUserID, amount, TreasuryAccount, models.PointsLogAdminGrant, refID, note)
+}
 
-	logEntry := &models.PointsLog{
-		ID:      uuid.New().String(),
-		GroupID: groupID,
-		UserID:  targetUserID,
-		Amount:  amount,
-		Type:    models.PointsLogAdminGrant,
-		Note:    note,
+// KickMember removes a member from the group. Their remaining balance goes
+// back to the treasury so their ledger account is empty if they rejoin.
+func (s *GroupService) KickMember(groupID, targetUserID string) error {
+	tx := s.db.Begin()
+
+	var member models.GroupMember
+	if err := tx.Where("group_id = ? AND user_id = ?", groupID, targetUserID).First(&member).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("member not found")
+	}
+	if err := creditMember(tx, groupID, targetUserID, -member.PointsBalance, TreasuryAccount, models.PointsLogForfeit, "",
+		"Removed from the group, balance returned to the treasury"); err != nil {
+		tx.Rollback()
+		return err
 	}
-	if err := tx.Create(logEntry).Error; err != nil {
+	if err := addSeasonForfeitTx(tx, groupID, targetUserID, member.PointsBalance); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Delete(&member).Error; err != nil {
 		tx.Rollback()
 		return err
 	}
@@ -179,8 +192,61 @@ func (s *GroupService) GrantPoints(groupID, targetUserID string, amount int, not
 	return tx.Commit().Error
 }
 
-func (s *GroupService) KickMember(groupID, targetUserID string) error {
-	return s.db.Where("group_id = ? AND user_id = ?", groupID, targetUserID).Delete(&models.GroupMember{}).Error
+// SetMemberRole promotes a member to admin or demotes an admin. A group always
+// keeps at least one admin, and two while grants need a second admin's
+// approval. While that rule is on, promotions need approval too, or one admin
+// could promote a second account and approve their own requests with it.
+func (s *GroupService) SetMemberRole(groupID, adminID, targetUserID, role string) (*models.GrantApproval, error) {
+	if role != "admin" && role != "member" {
+		return nil, fmt.Errorf("role must be admin or member")
+	}
+
+	var group models.Group
+	if err := s.db.First(&group, "id = ?", groupID).Error; err != nil {
+		return nil, fmt.Errorf("group not found")
+	}
+	var member models.GroupMember
+	if err := s.db.Where("group_id = ? AND user_id = ?", groupID, targetUserID).First(&member).Error; err != nil {
+		return nil, fmt.Errorf("member not found")
+	}
+	if role == "admin" && member.Role != "admin" && group.ApproveAbove > 0 {
+		return createApproval(s.db, &models.GrantApproval{
+			GroupID:     groupID,
+			Kind:        models.GrantApprovalPromote,
+			RequestedBy: adminID,
+			UserID:      targetUserID,
+			Role:        role,
+		})
+	}
+
+	return nil, setMemberRoleTx(s.db, groupID, targetUserID, role)
+}
+
+func setMemberRoleTx(tx *gorm.DB, groupID, targetUserID, role string) error {
+	var member models.GroupMember
+	if err := tx.Where("group_id = ? AND user_id = ?", groupID, targetUserID).First(&member).Error; err != nil {
+		return fmt.Errorf("member not found")
+	}
+	if member.Role == "admin" && role == "member" {
+		var group models.Group
+		if err := tx.First(&group, "id = ?", groupID).Error; err != nil {
+			return fmt.Errorf("group not found")
+		}
+		var admins int64
+		tx.Model(&models.GroupMember{}).Where("group_id = ? AND role = ?", groupID, "admin").Count(&admins)
+		if admins <= 1 {
+			return fmt.Errorf("a group needs at least one admin")
+		}
+		if group.ApproveAbove > 0 && admins <= 2 {
+			return fmt.Errorf("grant approvals need at least two admins; turn them off first")
+		}
+	}
+
+	return tx.Model(&member).Update("role", role).Error
+}
+
+func (s *GroupService) Reconcile(groupID string) (*ReconcileReport, error) {
+	return Reconcile(s.db, groupID)
 }
 
 func (s *GroupService) RegenerateInviteCode(groupID string) (string, error) {
@@ -205,6 +271,144 @@ func (s *GroupService) DeleteGroup(groupID string) error {
 		return fmt.Errorf("failed to find pools: %w", err)
 	}
 
+	if err := tx.Where("group_id = ?", groupID).Delete(&models.Challenge{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete challenges: %w", err)
+	}
+
+	var bracketIDs []string
+	if err := tx.Model(&models.Bracket{}).Where("group_id = ?", groupID).Pluck("id", &bracketIDs).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to find brackets: %w", err)
+	}
+	if len(bracketIDs) > 0 {
+		entries := tx.Model(&models.BracketEntry{}).Select("id").Where("bracket_id IN ?", bracketIDs)
+		if err := tx.Where("entry_id IN (?)", entries).Delete(&models.BracketPick{}).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to delete bracket picks: %w", err)
+		}
+		if err := tx.Where("bracket_id IN ?", bracketIDs).Delete(&models.BracketEntry{}).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to delete bracket entries: %w", err)
+		}
+		if err := tx.Where("bracket_id IN ?", bracketIDs).Delete(&models.BracketGame{}).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to delete bracket games: %w", err)
+		}
+		if err := tx.Where("id IN ?", bracketIDs).Delete(&models.Bracket{}).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to delete brackets: %w", err)
+		}
+	}
+
+	var leagueIDs []string
+	if err := tx.Model(&models.League{}).Where("group_id = ?", groupID).Pluck("id", &leagueIDs).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to find leagues: %w", err)
+	}
+	if len(leagueIDs) > 0 {
+		if err := tx.Where("league_id IN ?", leagueIDs).Delete(&models.LeaguePick{}).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to delete league picks: %w", err)
+		}
+		rounds := tx.Model(&models.LeagueRound{}).Select("id").Where("league_id IN ?", leagueIDs)
+		if err := tx.Where("round_id IN (?)", rounds).Delete(&models.LeagueRoundPool{}).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to delete league round pools: %w", err)
+		}
+		if err := tx.Where("league_id IN ?", leagueIDs).Delete(&models.LeagueRound{}).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to delete league rounds: %w", err)
+		}
+		if err := tx.Where("id IN ?", leagueIDs).Delete(&models.League{}).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to delete leagues: %w", err)
+		}
+	}
+
+	sideBets := tx.Model(&models.SideBet{}).Select("id").Where("group_id = ?", groupID)
+	if err := tx.Where("side_bet_id IN (?)", sideBets).Delete(&models.SideBetMember{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete side bet members: %w", err)
+	}
+	if err := tx.Where("group_id = ?", groupID).Delete(&models.SideBet{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete side bets: %w", err)
+	}
+
+	if err := tx.Where("group_id = ?", groupID).Delete(&models.GrantApproval{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete grant approvals: %w", err)
+	}
+	if err := tx.Where("group_id = ?", groupID).Delete(&models.GrantBatch{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete grant batches: %w", err)
+	}
+	if err := tx.Where("group_id = ?", groupID).Delete(&models.Transfer{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete transfers: %w", err)
+	}
+
+	if err := tx.Where("group_id = ?", groupID).Delete(&models.Loan{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete loans: %w", err)
+	}
+	if err := tx.Where("group_id = ?", groupID).Delete(&models.AllowancePayment{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete allowance payments: %w", err)
+	}
+	if err := tx.Where("group_id = ?", groupID).Delete(&models.Allowance{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete allowance: %w", err)
+	}
+
+	if err := tx.Where("group_id = ?", groupID).Delete(&models.BailoutPolicy{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete bailout policy: %w", err)
+	}
+
+	settlements := tx.Model(&models.Settlement{}).Select("id").Where("group_id = ?", groupID)
+	if err := tx.Where("settlement_id IN (?)", settlements).Delete(&models.SettlementPayment{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete settlement payments: %w", err)
+	}
+	if err := tx.Where("settlement_id IN (?)", settlements).Delete(&models.SettlementNet{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete settlement nets: %w", err)
+	}
+	if err := tx.Where("group_id = ?", groupID).Delete(&models.Settlement{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete settlements: %w", err)
+	}
+
+	if err := tx.Where("group_id = ?", groupID).Delete(&models.SeasonStart{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete season starts: %w", err)
+	}
+	seasons := tx.Model(&models.Season{}).Select("id").Where("group_id = ?", groupID)
+	if err := tx.Where("season_id IN (?)", seasons).Delete(&models.SeasonStanding{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete season standings: %w", err)
+	}
+	if err := tx.Where("group_id = ?", groupID).Delete(&models.Season{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete seasons: %w", err)
+	}
+
+	squares := tx.Model(&models.SquaresGame{}).Select("id").Where("group_id = ?", groupID)
+	if err := tx.Where("game_id IN (?)", squares).Delete(&models.SquaresQuarter{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete squares quarters: %w", err)
+	}
+	if err := tx.Where("game_id IN (?)", squares).Delete(&models.SquaresClaim{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete squares claims: %w", err)
+	}
+	if err := tx.Where("group_id = ?", groupID).Delete(&models.SquaresGame{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete squares games: %w", err)
+	}
+
 	if len(poolIDs) > 0 {
 		if err := tx.Where("pool_id IN ?", poolIDs).Delete(&models.Bet{}).Error; err != nil {
 			tx.Rollback()
@@ -214,16 +418,48 @@ func (s *GroupService) DeleteGroup(groupID string) error {
 			tx.Rollback()
 			return fmt.Errorf("failed to delete pool options: %w", err)
 		}
+		if err := tx.Where("pool_id IN ?", poolIDs).Delete(&models.PoolParticipant{}).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to delete pool participants: %w", err)
+		}
+		if err := tx.Where("pool_id IN ?", poolIDs).Delete(&models.PoolOracle{}).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to delete pool oracles: %w", err)
+		}
+		if err := tx.Where("pool_id IN ?", poolIDs).Delete(&models.Raffle{}).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to delete raffles: %w", err)
+		}
+		if err := tx.Where("pool_id IN ?", poolIDs).Delete(&models.PoolSpread{}).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to delete pool spreads: %w", err)
+		}
+		if err := tx.Where("pool_id IN ?", poolIDs).Delete(&models.PoolSeed{}).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to delete pool seeds: %w", err)
+		}
 	}
 
 	if err := tx.Where("group_id = ?", groupID).Delete(&models.Pool{}).Error; err != nil {
 		tx.Rollback()
 		return fmt.Errorf("failed to delete pools: %w", err)
 	}
+	if err := tx.Where("group_id = ?", groupID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete webhook deliveries: %w", err)
+	}
+	if err := tx.Where("group_id = ?", groupID).Delete(&models.LedgerEntry{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete ledger entries: %w", err)
+	}
 	if err := tx.Where("group_id = ?", groupID).Delete(&models.PointsLog{}).Error; err != nil {
 		tx.Rollback()
 		return fmt.Errorf("failed to delete points logs: %w", err)
 	}
+	if err := tx.Where("group_id = ?", groupID).Delete(&models.PointsChainHead{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete points chain head: %w", err)
+	}
 	if err := tx.Where("group_id = ?", groupID).Delete(&models.GroupMember{}).Error; err != nil {
 		tx.Rollback()
 		return fmt.Errorf("failed to delete members: %w", err)
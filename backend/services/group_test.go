@@ -24,8 +24,43 @@ func setupTestDB(t *testing.T) *gorm.DB {
 		&models.GroupMember{},
 		&models.Pool{},
 		&models.PoolOption{},
+		&models.PoolParticipant{},
 		&models.Bet{},
 		&models.PointsLog{},
+		&models.PointsChainHead{},
+		&models.LedgerEntry{},
+		&models.GrantBatch{},
+		&models.GrantApproval{},
+		&models.PoolOracle{},
+		&models.WebhookDelivery{},
+		&models.Raffle{},
+		&models.PoolSpread{},
+		&models.SideBet{},
+		&models.SideBetMember{},
+		&models.Transfer{},
+		&models.Loan{},
+		&models.Allowance{},
+		&models.AllowancePayment{},
+		&models.BailoutPolicy{},
+		&models.PoolSeed{},
+		&models.Challenge{},
+		&models.Bracket{},
+		&models.BracketGame{},
+		&models.BracketEntry{},
+		&models.BracketPick{},
+		&models.League{},
+		&models.LeagueRound{},
+		&models.LeagueRoundPool{},
+		&models.LeaguePick{},
+		&models.SquaresGame{},
+		&models.SquaresClaim{},
+		&models.SquaresQuarter{},
+		&models.Season{},
+		&models.SeasonStanding{},
+		&models.SeasonStart{},
+		&models.Settlement{},
+		&models.SettlementNet{},
+		&models.SettlementPayment{},
 	); err != nil {
 		t.Fatalf("failed to migrate: %v", err)
 	}
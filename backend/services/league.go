@@ -0,0 +1,518 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/codyseavey/bets/models"
+)
+
+type LeagueService struct {
+	db *gorm.DB
+}
+
+func NewLeagueService(db *gorm.DB) *LeagueService {
+	return &LeagueService{db: db}
+}
+
+type CreateLeagueRequest struct {
+	Title       string            `json:"title" binding:"required"`
+	Description string            `json:"description"`
+	Mode        models.LeagueMode `json:"mode" binding:"required,oneof=pickem survivor"`
+}
+
+func (s *LeagueService) CreateLeague(groupID, userID string, req CreateLeagueRequest) (*models.League, error) {
+	league := &models.League{
+		ID:          uuid.New().String(),
+		GroupID:     groupID,
+		Title:       req.Title,
+		Description: req.Description,
+		Mode:        req.Mode,
+		CreatedBy:   userID,
+	}
+	if err := s.db.Create(league).Error; err != nil {
+		return nil, fmt.Errorf("failed to create league: %w", err)
+	}
+	return league, nil
+}
+
+func (s *LeagueService) GetGroupLeagues(groupID string) ([]models.League, error) {
+	var leagues []models.League
+	err := s.db.Where("group_id = ?", groupID).
+		Preload("Creator").
+		Order("created_at DESC").
+		Find(&leagues).Error
+	return leagues, err
+}
+
+// GetLeague loads a league with its rounds and their pools. Each pool's
+// WinningOptionID is filled in so clients can mark picks without fetching
+// every pool separately.
+func (s *LeagueService) GetLeague(leagueID string) (*models.League, error) {
+	var league models.League
+	err := s.db.
+		Preload("Creator").
+		Preload("Rounds", func(db *gorm.DB) *gorm.DB {
+			return db.Order("number ASC")
+		}).
+		Preload("Rounds.Pools.Pool.Options").
+		First(&league, "id = ?", leagueID).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var poolIDs []string
+	for _, r := range league.Rounds {
+		for _, rp := range r.Pools {
+			poolIDs = append(poolIDs, rp.PoolID)
+		}
+	}
+	winners := winningOptions(s.db, poolIDs)
+	for i := range league.Rounds {
+		for j := range league.Rounds[i].Pools {
+			pool := &league.Rounds[i].Pools[j].Pool
+			pool.WinningOptionID = winners[pool.ID]
+		}
+	}
+	return &league, nil
+}
+
+func (s *LeagueService) GetLeagueGroupID(leagueID string) (string, error) {
+	var league models.League
+	if err := s.db.Select("group_id").First(&league, "id = ?", leagueID).Error; err != nil {
+		return "", err
+	}
+	return league.GroupID, nil
+}
+
+// winningOptions maps each resolved pool to its winning option, read from the
+// pool_resolved ledger entries (whose reference is the winning option ID).
+func winningOptions(db *gorm.DB, poolIDs []string) map[string]string {
+	winners := make(map[string]string, len(poolIDs))
+	if len(poolIDs) == 0 {
+		return winners
+	}
+
+	var rows []struct {
+		PoolID   string
+		OptionID string
+	}
+	db.Table("points_logs").
+		Select("pool_options.pool_id AS pool_id, points_logs.reference_id AS option_id").
+		Joins("JOIN pool_options ON pool_options.id = points_logs.reference_id").
+		Where("points_logs.type = ? AND pool_options.pool_id IN ?", "pool_resolved", poolIDs).
+		Scan(&rows)
+	for _, r := range rows {
+		winners[r.PoolID] = r.OptionID
+	}
+	return winners
+}
+
+type AddLeagueRoundRequest struct {
+	Title   string   `json:"title"`
+	PoolIDs []string `json:"pool_ids" binding:"required,min=1"`
+}
+
+// AddRound appends the next round to a league, made up of existing pools from
+// the same group. Restricted pools can't be used since not every member could
+// pick in them.
+func (s *LeagueService) AddRound(leagueID string, req AddLeagueRoundRequest) (*models.LeagueRound, error) {
+	tx := s.db.Begin()
+
+	var league models.League
+	if err := tx.First(&league, "id = ?", leagueID).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("league not found")
+	}
+
+	seen := make(map[string]bool, len(req.PoolIDs))
+	for _, id := range req.PoolIDs {
+		if seen[id] {
+			tx.Rollback()
+			return nil, fmt.Errorf("pool %s listed twice", id)
+		}
+		seen[id] = true
+	}
+
+	var pools []models.Pool
+	if err := tx.Where("id IN ? AND group_id = ?", req.PoolIDs, league.GroupID).Find(&pools).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if len(pools) != len(req.PoolIDs) {
+		tx.Rollback()
+		return nil, fmt.Errorf("all pools must belong to this group")
+	}
+	for _, p := range pools {
+		switch {
+		case p.Type != models.PoolTypeStandard && p.Type != models.PoolTypeSpread:
+			tx.Rollback()
+			return nil, fmt.Errorf("pool %q isn't a standard or spread pool", p.Title)
+		case p.Restricted:
+			tx.Rollback()
+			return nil, fmt.Errorf("pool %q is restricted", p.Title)
+		case p.Status == models.PoolStatusCancelled:
+			tx.Rollback()
+			return nil, fmt.Errorf("pool %q is cancelled", p.Title)
+		}
+	}
+
+	var used int64
+	tx.Model(&models.LeagueRoundPool{}).
+		Joins("JOIN league_rounds ON league_rounds.id = league_round_pools.round_id").
+		Where("league_rounds.league_id = ? AND league_round_pools.pool_id IN ?", leagueID, req.PoolIDs).
+		Count(&used)
+	if used > 0 {
+		tx.Rollback()
+		return nil, fmt.Errorf("a pool can only be in one round of a league")
+	}
+
+	var last int
+	tx.Model(&models.LeagueRound{}).Where("league_id = ?", leagueID).Select("COALESCE(MAX(number), 0)").Scan(&last)
+
+	round := &models.LeagueRound{
+		ID:       uuid.New().String(),
+		LeagueID: leagueID,
+		Number:   last + 1,
+		Title:    req.Title,
+	}
+	if round.Title == "" {
+		round.Title = fmt.Sprintf("Round %d", round.Number)
+	}
+	if err := tx.Create(round).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to create round: %w", err)
+	}
+	for _, id := range req.PoolIDs {
+		rp := models.LeagueRoundPool{RoundID: round.ID, PoolID: id}
+		if err := tx.Create(&rp).Error; err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to add pool to round: %w", err)
+		}
+		round.Pools = append(round.Pools, rp)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return round, nil
+}
+
+type LeaguePickInput struct {
+	PoolID   string `json:"pool_id" binding:"required"`
+	OptionID string `json:"option_id" binding:"required"`
+}
+
+type SubmitLeaguePicksRequest struct {
+	Picks []LeaguePickInput `json:"picks" binding:"required,min=1,dive"`
+}
+
+// SubmitPicks records a member's picks for a round, replacing any earlier
+// pick for the same pool. Picks can only be made or changed while the pool is
+// still open. Survivor leagues take exactly one pick per round, can't reuse a
+// team picked in another round, and turn away eliminated members.
+func (s *LeagueService) SubmitPicks(leagueID, roundID, userID string, req SubmitLeaguePicksRequest) ([]models.LeaguePick, error) {
+	tx := s.db.Begin()
+
+	var league models.League
+	if err := tx.First(&league, "id = ?", leagueID).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("league not found")
+	}
+	var round models.LeagueRound
+	if err := tx.Preload("Pools").First(&round, "id = ? AND league_id = ?", roundID, leagueID).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("round not found")
+	}
+	inRound := make(map[string]bool, len(round.Pools))
+	for _, rp := range round.Pools {
+		inRound[rp.PoolID] = true
+	}
+
+	survivor := league.Mode == models.LeagueModeSurvivor
+	if survivor {
+		if len(req.Picks) != 1 {
+			tx.Rollback()
+			return nil, fmt.Errorf("survivor leagues take exactly one pick per round")
+		}
+		standings, err := s.computeStandings(tx, leagueID)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		for _, st := range standings {
+			if st.UserID == userID && st.Eliminated {
+				tx.Rollback()
+				return nil, fmt.Errorf("you were eliminated in round %d", st.EliminatedRound)
+			}
+		}
+
+		// A survivor pick for this round can be swapped only while the
+		// previously picked pool is still open
+		var existing []models.LeaguePick
+		tx.Where("round_id = ? AND user_id = ?", roundID, userID).Find(&existing)
+		for _, p := range existing {
+			var pool models.Pool
+			if err := tx.First(&pool, "id = ?", p.PoolID).Error; err == nil && !poolAcceptingPicks(&pool) {
+				tx.Rollback()
+				return nil, fmt.Errorf("your pick for this round is already locked in")
+			}
+		}
+		if err := tx.Where("round_id = ? AND user_id = ?", roundID, userID).Delete(&models.LeaguePick{}).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	picks := make([]models.LeaguePick, 0, len(req.Picks))
+	for _, in := range req.Picks {
+		if !inRound[in.PoolID] {
+			tx.Rollback()
+			return nil, fmt.Errorf("pool %s isn't part of this round", in.PoolID)
+		}
+		var pool models.Pool
+		if err := tx.First(&pool, "id = ?", in.PoolID).Error; err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("pool not found")
+		}
+		if !poolAcceptingPicks(&pool) {
+			tx.Rollback()
+			return nil, fmt.Errorf("pool %q is no longer accepting picks", pool.Title)
+		}
+		var option models.PoolOption
+		if err := tx.First(&option, "id = ? AND pool_id = ?", in.OptionID, in.PoolID).Error; err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("invalid option for pool %q", pool.Title)
+		}
+
+		if survivor {
+			var reused int64
+			tx.Model(&models.LeaguePick{}).
+				Joins("JOIN pool_options ON pool_options.id = league_picks.option_id").
+				Where("league_picks.league_id = ? AND league_picks.user_id = ? AND league_picks.round_id <> ? AND LOWER(pool_options.label) = LOWER(?)",
+					leagueID, userID, roundID, option.Label).
+				Count(&reused)
+			if reused > 0 {
+				tx.Rollback()
+				return nil, fmt.Errorf("you've already used %q in this league", option.Label)
+			}
+		} else {
+			if err := tx.Where("round_id = ? AND user_id = ? AND pool_id = ?", roundID, userID, in.PoolID).
+				Delete(&models.LeaguePick{}).Error; err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		}
+
+		pick := models.LeaguePick{
+			ID:       uuid.New().String(),
+			LeagueID: leagueID,
+			RoundID:  roundID,
+			UserID:   userID,
+			PoolID:   in.PoolID,
+			OptionID: in.OptionID,
+			Option:   option,
+		}
+		if err := tx.Create(&pick).Error; err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to save pick: %w", err)
+		}
+		picks = append(picks, pick)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return picks, nil
+}
+
+func poolAcceptingPicks(pool *models.Pool) bool {
+	if pool.Status != models.PoolStatusOpen {
+		return false
+	}
+	return pool.LockAt == nil || time.Now().Before(*pool.LockAt)
+}
+
+// GetUserPicks returns a member's picks across every round of a league.
+func (s *LeagueService) GetUserPicks(leagueID, userID string) ([]models.LeaguePick, error) {
+	var picks []models.LeaguePick
+	err := s.db.Where("league_id = ? AND user_id = ?", leagueID, userID).
+		Preload("Option").
+		Order("created_at ASC").
+		Find(&picks).Error
+	return picks, err
+}
+
+type LeagueStanding struct {
+	Rank            int    `json:"rank"`
+	UserID          string `json:"user_id"`
+	Name            string `json:"name"`
+	AvatarURL       string `json:"avatar_url"`
+	Correct         int    `json:"correct"`
+	Incorrect       int    `json:"incorrect"`
+	Pending         int    `json:"pending"`
+	Eliminated      bool   `json:"eliminated"`
+	EliminatedRound int    `json:"eliminated_round,omitempty"`
+}
+
+// GetStandings ranks every current member who has made a pick in the league.
+// Results come from how the round pools resolved, not from points balances.
+// Pick'em ranks by correct picks; survivor ranks survivors first, then by how
+// long eliminated members lasted.
+func (s *LeagueService) GetStandings(leagueID string) ([]LeagueStanding, error) {
+	return s.computeStandings(s.db, leagueID)
+}
+
+func (s *LeagueService) computeStandings(db *gorm.DB, leagueID string) ([]LeagueStanding, error) {
+	var league models.League
+	err := db.
+		Preload("Rounds", func(db *gorm.DB) *gorm.DB {
+			return db.Order("number ASC")
+		}).
+		Preload("Rounds.Pools.Pool").
+		First(&league, "id = ?", leagueID).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var poolIDs []string
+	poolStatus := make(map[string]models.PoolStatus)
+	for _, r := range league.Rounds {
+		for _, rp := range r.Pools {
+			poolIDs = append(poolIDs, rp.PoolID)
+			poolStatus[rp.PoolID] = rp.Pool.Status
+		}
+	}
+	winners := winningOptions(db, poolIDs)
+	var pushedIDs []string
+	if len(poolIDs) > 0 {
+		db.Model(&models.PoolOption{}).Where("pool_id IN ? AND push = ?", poolIDs, true).Pluck("id", &pushedIDs)
+	}
+	pushed := make(map[string]bool, len(pushedIDs))
+	for _, id := range pushedIDs {
+		pushed[id] = true
+	}
+
+	var picks []models.LeaguePick
+	if err := db.Where("league_id = ?", leagueID).Find(&picks).Error; err != nil {
+		return nil, err
+	}
+	byUserRound := make(map[string]map[string][]models.LeaguePick)
+	var userIDs []string
+	for _, p := range picks {
+		if byUserRound[p.UserID] == nil {
+			byUserRound[p.UserID] = make(map[string][]models.LeaguePick)
+			userIDs = append(userIDs, p.UserID)
+		}
+		byUserRound[p.UserID][p.RoundID] = append(byUserRound[p.UserID][p.RoundID], p)
+	}
+	if len(userIDs) == 0 {
+		return []LeagueStanding{}, nil
+	}
+
+	// Only rank people still in the group
+	var users []models.User
+	if err := db.Where("id IN ? AND id IN (?)", userIDs,
+		db.Model(&models.GroupMember{}).Select("user_id").Where("group_id = ?", league.GroupID)).
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	standings := make([]LeagueStanding, 0, len(users))
+	for _, u := range users {
+		st := LeagueStanding{UserID: u.ID, Name: u.Name, AvatarURL: u.AvatarURL}
+		for _, r := range league.Rounds {
+			roundPicks := byUserRound[u.ID][r.ID]
+			lost := false
+			for _, p := range roundPicks {
+				switch poolStatus[p.PoolID] {
+				case models.PoolStatusResolved:
+					if pushed[p.OptionID] {
+						// A pushed pick neither wins nor loses
+						continue
+					}
+					if winners[p.PoolID] == p.OptionID {
+						st.Correct++
+					} else {
+						st.Incorrect++
+						lost = true
+					}
+				case models.PoolStatusCancelled:
+					// A cancelled pool is a push
+				default:
+					st.Pending++
+				}
+			}
+
+			if league.Mode == models.LeagueModeSurvivor && (lost || (len(roundPicks) == 0 && roundDecided(r, poolStatus))) {
+				st.Eliminated = true
+				st.EliminatedRound = r.Number
+				break
+			}
+		}
+		standings = append(standings, st)
+	}
+
+	less, same := pickemOrder, pickemSame
+	if league.Mode == models.LeagueModeSurvivor {
+		less, same = survivorOrder, survivorSame
+	}
+	sort.SliceStable(standings, func(i, j int) bool {
+		if same(&standings[i], &standings[j]) {
+			return strings.ToLower(standings[i].Name) < strings.ToLower(standings[j].Name)
+		}
+		return less(&standings[i], &standings[j])
+	})
+	for i := range standings {
+		standings[i].Rank = i + 1
+		if i > 0 && same(&standings[i], &standings[i-1]) {
+			standings[i].Rank = standings[i-1].Rank
+		}
+	}
+
+	return standings, nil
+}
+
+// roundDecided reports whether every pool in a round has been resolved or
+// cancelled, after which a missing survivor pick counts as a loss.
+func roundDecided(round models.LeagueRound, poolStatus map[string]models.PoolStatus) bool {
+	if len(round.Pools) == 0 {
+		return false
+	}
+	for _, rp := range round.Pools {
+		if st := poolStatus[rp.PoolID]; st != models.PoolStatusResolved && st != models.PoolStatusCancelled {
+			return false
+		}
+	}
+	return true
+}
+
+func pickemOrder(a, b *LeagueStanding) bool {
+	if a.Correct != b.Correct {
+		return a.Correct > b.Correct
+	}
+	return a.Incorrect < b.Incorrect
+}
+
+func pickemSame(a, b *LeagueStanding) bool {
+	return a.Correct == b.Correct && a.Incorrect == b.Incorrect
+}
+
+func survivorOrder(a, b *LeagueStanding) bool {
+	if a.Eliminated != b.Eliminated {
+		return !a.Eliminated
+	}
+	if a.EliminatedRound != b.EliminatedRound {
+		return a.EliminatedRound > b.EliminatedRound
+	}
+	return a.Correct > b.Correct
+}
+
+func survivorSame(a, b *LeagueStanding) bool {
+	return a.Eliminated == b.Eliminated && a.EliminatedRound == b.EliminatedRound && a.Correct == b.Correct
+}
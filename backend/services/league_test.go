@@ -0,0 +1,159 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/codyseavey/bets/models"
+)
+
+func TestLeague_PickemStandings(t *testing.T) {
+	db, poolSvc, _, group, alice, bob := setupPoolTest(t)
+	svc := NewLeagueService(db)
+
+	league, err := svc.CreateLeague(group.ID, alice.ID, CreateLeagueRequest{Title: "NFL Week by Week", Mode: models.LeagueModePickem})
+	if err != nil {
+		t.Fatalf("CreateLeague failed: %v", err)
+	}
+
+	game1, _ := poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{Title: "Lions vs Bears", Options: []string{"Lions", "Bears"}})
+	game2, _ := poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{Title: "Jets vs Bills", Options: []string{"Jets", "Bills"}})
+	round, err := svc.AddRound(league.ID, AddLeagueRoundRequest{PoolIDs: []string{game1.ID, game2.ID}})
+	if err != nil {
+		t.Fatalf("AddRound failed: %v", err)
+	}
+	if round.Number != 1 || round.Title != "Round 1" {
+		t.Errorf("expected Round 1, got %d %q", round.Number, round.Title)
+	}
+	if _, err := svc.AddRound(league.ID, AddLeagueRoundRequest{PoolIDs: []string{game1.ID}}); err == nil {
+		t.Error("expected error reusing a pool in another round")
+	}
+
+	if _, err := svc.SubmitPicks(league.ID, round.ID, alice.ID, SubmitLeaguePicksRequest{Picks: []LeaguePickInput{
+		{PoolID: game1.ID, OptionID: game1.Options[0].ID},
+		{PoolID: game2.ID, OptionID: game2.Options[0].ID},
+	}}); err != nil {
+		t.Fatalf("alice SubmitPicks failed: %v", err)
+	}
+	// Changing a pick replaces it rather than adding a second one
+	if _, err := svc.SubmitPicks(league.ID, round.ID, alice.ID, SubmitLeaguePicksRequest{Picks: []LeaguePickInput{
+		{PoolID: game2.ID, OptionID: game2.Options[1].ID},
+	}}); err != nil {
+		t.Fatalf("alice repick failed: %v", err)
+	}
+	if _, err := svc.SubmitPicks(league.ID, round.ID, bob.ID, SubmitLeaguePicksRequest{Picks: []LeaguePickInput{
+		{PoolID: game1.ID, OptionID: game1.Options[1].ID},
+		{PoolID: game2.ID, OptionID: game2.Options[1].ID},
+	}}); err != nil {
+		t.Fatalf("bob SubmitPicks failed: %v", err)
+	}
+
+	poolSvc.ResolvePool(game1.ID, game1.Options[0].ID, alice.ID, true)
+
+	if _, err := svc.SubmitPicks(league.ID, round.ID, bob.ID, SubmitLeaguePicksRequest{Picks: []LeaguePickInput{
+		{PoolID: game1.ID, OptionID: game1.Options[0].ID},
+	}}); err == nil {
+		t.Error("expected error picking in a resolved pool")
+	}
+
+	poolSvc.ResolvePool(game2.ID, game2.Options[1].ID, alice.ID, true)
+
+	standings, err := svc.GetStandings(league.ID)
+	if err != nil {
+		t.Fatalf("GetStandings failed: %v", err)
+	}
+	if len(standings) != 2 {
+		t.Fatalf("expected 2 standings, got %d", len(standings))
+	}
+	if standings[0].UserID != alice.ID || standings[0].Correct != 2 || standings[0].Rank != 1 {
+		t.Errorf("expected Alice first with 2 correct, got %+v", standings[0])
+	}
+	if standings[1].Correct != 1 || standings[1].Incorrect != 1 || standings[1].Rank != 2 {
+		t.Errorf("expected Bob 1-1 in second, got %+v", standings[1])
+	}
+
+	// Standings come from resolutions, not balances: no points moved
+	var aliceMember models.GroupMember
+	db.Where("group_id = ? AND user_id = ?", group.ID, alice.ID).First(&aliceMember)
+	if aliceMember.PointsBalance != 1000 {
+		t.Errorf("expected picks not to touch balance, got %d", aliceMember.PointsBalance)
+	}
+}
+
+func TestLeague_SurvivorElimination(t *testing.T) {
+	db, poolSvc, _, group, alice, bob := setupPoolTest(t)
+	svc := NewLeagueService(db)
+
+	league, _ := svc.CreateLeague(group.ID, alice.ID, CreateLeagueRequest{Title: "Survivor", Mode: models.LeagueModeSurvivor})
+
+	w1a, _ := poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{Title: "W1 Lions vs Bears", Options: []string{"Lions", "Bears"}})
+	w1b, _ := poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{Title: "W1 Jets vs Bills", Options: []string{"Jets", "Bills"}})
+	round1, _ := svc.AddRound(league.ID, AddLeagueRoundRequest{Title: "Week 1", PoolIDs: []string{w1a.ID, w1b.ID}})
+
+	if _, err := svc.SubmitPicks(league.ID, round1.ID, alice.ID, SubmitLeaguePicksRequest{Picks: []LeaguePickInput{
+		{PoolID: w1a.ID, OptionID: w1a.Options[0].ID},
+		{PoolID: w1b.ID, OptionID: w1b.Options[0].ID},
+	}}); err == nil {
+		t.Error("expected error making two survivor picks in one round")
+	}
+
+	svc.SubmitPicks(league.ID, round1.ID, alice.ID, SubmitLeaguePicksRequest{Picks: []LeaguePickInput{{PoolID: w1a.ID, OptionID: w1a.Options[0].ID}}})
+	svc.SubmitPicks(league.ID, round1.ID, bob.ID, SubmitLeaguePicksRequest{Picks: []LeaguePickInput{{PoolID: w1b.ID, OptionID: w1b.Options[0].ID}}})
+
+	poolSvc.ResolvePool(w1a.ID, w1a.Options[0].ID, alice.ID, true) // Lions win
+	poolSvc.ResolvePool(w1b.ID, w1b.Options[1].ID, alice.ID, true) // Bills win, Bob is out
+
+	w2, _ := poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{Title: "W2 Lions vs Packers", Options: []string{"Lions", "Packers"}})
+	round2, _ := svc.AddRound(league.ID, AddLeagueRoundRequest{PoolIDs: []string{w2.ID}})
+
+	if _, err := svc.SubmitPicks(league.ID, round2.ID, alice.ID, SubmitLeaguePicksRequest{Picks: []LeaguePickInput{
+		{PoolID: w2.ID, OptionID: w2.Options[0].ID},
+	}}); err == nil {
+		t.Error("expected error reusing Lions")
+	}
+	if _, err := svc.SubmitPicks(league.ID, round2.ID, bob.ID, SubmitLeaguePicksRequest{Picks: []LeaguePickInput{
+		{PoolID: w2.ID, OptionID: w2.Options[1].ID},
+	}}); err == nil {
+		t.Error("expected error picking after elimination")
+	}
+	if _, err := svc.SubmitPicks(league.ID, round2.ID, alice.ID, SubmitLeaguePicksRequest{Picks: []LeaguePickInput{
+		{PoolID: w2.ID, OptionID: w2.Options[1].ID},
+	}}); err != nil {
+		t.Fatalf("alice round 2 pick failed: %v", err)
+	}
+
+	standings, _ := svc.GetStandings(league.ID)
+	if standings[0].UserID != alice.ID || standings[0].Eliminated || standings[0].Pending != 1 {
+		t.Errorf("expected Alice alive with a pending pick, got %+v", standings[0])
+	}
+	if !standings[1].Eliminated || standings[1].EliminatedRound != 1 {
+		t.Errorf("expected Bob eliminated in round 1, got %+v", standings[1])
+	}
+}
+
+func TestLeague_SurvivorMissedRoundEliminates(t *testing.T) {
+	db, poolSvc, _, group, alice, bob := setupPoolTest(t)
+	svc := NewLeagueService(db)
+
+	league, _ := svc.CreateLeague(group.ID, alice.ID, CreateLeagueRequest{Title: "Survivor", Mode: models.LeagueModeSurvivor})
+	w1, _ := poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{Title: "W1", Options: []string{"A", "B"}})
+	w2, _ := poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{Title: "W2", Options: []string{"C", "D"}})
+	round1, _ := svc.AddRound(league.ID, AddLeagueRoundRequest{PoolIDs: []string{w1.ID}})
+	round2, _ := svc.AddRound(league.ID, AddLeagueRoundRequest{PoolIDs: []string{w2.ID}})
+
+	svc.SubmitPicks(league.ID, round1.ID, alice.ID, SubmitLeaguePicksRequest{Picks: []LeaguePickInput{{PoolID: w1.ID, OptionID: w1.Options[0].ID}}})
+	svc.SubmitPicks(league.ID, round1.ID, bob.ID, SubmitLeaguePicksRequest{Picks: []LeaguePickInput{{PoolID: w1.ID, OptionID: w1.Options[0].ID}}})
+	svc.SubmitPicks(league.ID, round2.ID, alice.ID, SubmitLeaguePicksRequest{Picks: []LeaguePickInput{{PoolID: w2.ID, OptionID: w2.Options[0].ID}}})
+
+	poolSvc.ResolvePool(w1.ID, w1.Options[0].ID, alice.ID, true)
+	poolSvc.ResolvePool(w2.ID, w2.Options[0].ID, alice.ID, true)
+
+	standings, _ := svc.GetStandings(league.ID)
+	for _, st := range standings {
+		if st.UserID == bob.ID && (!st.Eliminated || st.EliminatedRound != 2) {
+			t.Errorf("expected Bob eliminated in round 2 for not picking, got %+v", st)
+		}
+		if st.UserID == alice.ID && (st.Eliminated || st.Correct != 2) {
+			t.Errorf("expected Alice alive with 2 correct, got %+v", st)
+		}
+	}
+}
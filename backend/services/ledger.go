@@ -0,0 +1,131 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/codyseavey/bets/models"
+)
+
+// Points are kept in a double-entry ledger (see models.LedgerEntry). Nothing
+// is created or destroyed: grants come out of the group treasury, which runs
+// negative by however many points have been issued; stakes move into an
+// escrow account for whatever holds them and payouts move back out.
+// GroupMember.PointsBalance is a cached copy of each member account, kept in
+// step inside the same transaction, and PointsLog remains the per-member
+// history shown to users.
+
+const (
+	TreasuryAccount = "treasury"
+	memberPrefix    = "member:"
+	escrowPrefix    = "escrow:"
+)
+
+func MemberAccount(userID string) string    { return memberPrefix + userID }
+func PoolEscrow(poolID string) string       { return escrowPrefix + "pool:" + poolID }
+func BracketEscrow(bracketID string) string { return escrowPrefix + "bracket:" + bracketID }
+func SquaresEscrow(gameID string) string    { return escrowPrefix + "squares:" + gameID }
+func SideBetEscrow(sideBetID string) string { return escrowPrefix + "side_bet:" + sideBetID }
+
+// transferTx moves points between two accounts as one balanced ledger
+// transaction and updates the cached balance of any member account involved.
+func transferTx(tx *gorm.DB, groupID, from, to string, amount int, logID string) error {
+	if amount == 0 {
+		return nil
+	}
+	if err := postEntriesTx(tx, groupID, from, to, amount, logID); err != nil {
+		return err
+	}
+	for account, delta := range map[string]int{from: -amount, to: amount} {
+		userID, ok := strings.CutPrefix(account, memberPrefix)
+		if !ok {
+			continue
+		}
+		if err := tx.Model(&models.GroupMember{}).
+			Where("group_id = ? AND user_id = ?", groupID, userID).
+			Update("points_balance", gorm.Expr("points_balance + ?", delta)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// postEntriesTx writes the two legs of a movement without touching cached
+// balances.
+func postEntriesTx(tx *gorm.DB, groupID, from, to string, amount int, logID string) error {
+	txnID := uuid.New().String()
+	entries := []models.LedgerEntry{
+		{ID: uuid.New().String(), GroupID: groupID, TxnID: txnID, Account: from, Amount: -amount, LogID: logID},
+		{ID: uuid.New().String(), GroupID: groupID, TxnID: txnID, Account: to, Amount: amount, LogID: logID},
+	}
+	return tx.Create(&entries).Error
+}
+
+func isMemberTx(tx *gorm.DB, groupID, userID string) bool {
+	var count int64
+	tx.Model(&models.GroupMember{}).Where("group_id = ? AND user_id = ?", groupID, userID).Count(&count)
+	return count > 0
+}
+
+// creditMember moves points from another account into a member's and records
+// the movement in PointsLog, inside an existing transaction. A negative amount
+// moves points the other way. If the user has since left the group, the
+// points go to the treasury instead.
+func creditMember(tx *gorm.DB, groupID, userID string, amount int, from string, logType models.PointsLogType, refID, note string) error {
+	to := MemberAccount(userID)
+	if !isMemberTx(tx, groupID, userID) {
+		to = TreasuryAccount
+		note += " (forfeited to the treasury, no longer a member)"
+	}
+
+	logEntry := &models.PointsLog{
+		ID:          uuid.New().String(),
+		GroupID:     groupID,
+		UserID:      userID,
+		Amount:      amount,
+		Type:        logType,
+		ReferenceID: refID,
+		Note:        note,
+	}
+	if err := tx.Create(logEntry).Error; err != nil {
+		return err
+	}
+
+	return transferTx(tx, groupID, from, to, amount, logEntry.ID)
+}
+
+// debitMember moves points from a member into another account, failing if
+// their balance can't cover it, and records the movement in PointsLog.
+func debitMember(tx *gorm.DB, groupID, userID string, amount int, to string, logType models.PointsLogType, refID, note string) error {
+	var member models.GroupMember
+	if err := tx.Where("group_id = ? AND user_id = ?", groupID, userID).First(&member).Error; err != nil {
+		return fmt.Errorf("not a member of this group")
+	}
+	if member.PointsBalance < amount {
+		return fmt.Errorf("insufficient points (have %d, need %d)", member.PointsBalance, amount)
+	}
+	return creditMember(tx, groupID, userID, -amount, to, logType, refID, note)
+}
+
+// payMemberTx moves points straight from one member to another, failing if
+// the sender can't cover it. The ledger gets a single member-to-member
+// movement; each side gets its own PointsLog entry so both histories show it.
+func payMemberTx(tx *gorm.DB, groupID, fromUserID, toUserID string, amount int, sentType, receivedType models.PointsLogType, refID, sentNote, receivedNote string) error {
+	if err := debitMember(tx, groupID, fromUserID, amount, MemberAccount(toUserID), sentType, refID, sentNote); err != nil {
+		return err
+	}
+	// The movement above already credited the recipient; this is just their
+	// side of the history
+	return tx.Create(&models.PointsLog{
+		ID:          uuid.New().String(),
+		GroupID:     groupID,
+		UserID:      toUserID,
+		Amount:      amount,
+		Type:        receivedType,
+		ReferenceID: refID,
+		Note:        receivedNote,
+	}).Error
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/codyseavey/bets/models"
+)
+
+func assertReconciled(t *testing.T, db *gorm.DB, groupID string) *ReconcileReport {
+	t.Helper()
+	report, err := Reconcile(db, groupID)
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if !report.OK() {
+		t.Fatalf("expected a clean ledger, got drift %+v, unbalanced %v", report.Drift, report.UnbalancedTxns)
+	}
+	if report.Treasury+report.Members+report.Escrow != 0 {
+		t.Fatalf("expected accounts to sum to zero, got treasury %d, members %d, escrow %d",
+			report.Treasury, report.Members, report.Escrow)
+	}
+	return report
+}
+
+func TestLedgerStaysBalanced(t *testing.T) {
+	db, poolSvc, groupSvc, group, alice, bob := setupPoolTest(t)
+	carol := createTestUser(t, db, "carol", "Carol")
+	groupSvc.JoinGroup(group.InviteCode, carol.ID)
+
+	report := assertReconciled(t, db, group.ID)
+	if report.Treasury != -3000 || report.Members != 3000 {
+		t.Errorf("expected 3000 issued from the treasury, got treasury %d, members %d", report.Treasury, report.Members)
+	}
+
+	pool, _ := poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{
+		Title:   "Coin toss",
+		Options: []string{"Heads", "Tails"},
+	})
+	poolSvc.PlaceBet(pool.ID, alice.ID, PlaceBetRequest{OptionID: pool.Options[0].ID, Points: 100})
+	poolSvc.PlaceBet(pool.ID, bob.ID, PlaceBetRequest{OptionID: pool.Options[1].ID, Points: 300})
+	poolSvc.SeedPool(group.ID, pool.ID, alice.ID, true, SeedPoolRequest{Amount: 50, FromTreasury: true})
+
+	report = assertReconciled(t, db, group.ID)
+	if report.Escrow != 450 {
+		t.Errorf("expected 450 in escrow, got %d", report.Escrow)
+	}
+
+	// Carol leaves with a bet still riding; her winnings go to the treasury
+	poolSvc.PlaceBet(pool.ID, carol.ID, PlaceBetRequest{OptionID: pool.Options[0].ID, Points: 100})
+	if err := groupSvc.KickMember(group.ID, carol.ID); err != nil {
+		t.Fatalf("KickMember failed: %v", err)
+	}
+	assertReconciled(t, db, group.ID)
+
+	if err := poolSvc.ResolvePool(pool.ID, pool.Options[0].ID, alice.ID, false); err != nil {
+		t.Fatalf("ResolvePool failed: %v", err)
+	}
+	report = assertReconciled(t, db, group.ID)
+	if report.Escrow != 0 {
+		t.Errorf("expected escrow emptied after resolution, got %d", report.Escrow)
+	}
+
+	if err := groupSvc.GrantPoints(group.ID, bob.ID, -200, "Penalty"); err != nil {
+		t.Fatalf("GrantPoints failed: %v", err)
+	}
+	assertReconciled(t, db, group.ID)
+}
+
+func TestReconcileReportsDrift(t *testing.T) {
+	db, poolSvc, _, group, alice, bob := setupPoolTest(t)
+
+	pool, _ := poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{
+		Title:   "Coin toss",
+		Options: []string{"Heads", "Tails"},
+	})
+	poolSvc.PlaceBet(pool.ID, bob.ID, PlaceBetRequest{OptionID: pool.Options[0].ID, Points: 100})
+
+	// Someone edits a balance by hand
+	db.Model(&models.GroupMember{}).Where("group_id = ? AND user_id = ?", group.ID, alice.ID).
+		Update("points_balance", 5000)
+	// and a bet row without touching the ledger
+	db.Model(&models.Bet{}).Where("pool_id = ?", pool.ID).Update("points_wagered", 150)
+
+	report, err := Reconcile(db, group.ID)
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if report.OK() {
+		t.Fatal("expected drift to be reported")
+	}
+	found := map[string]AccountDrift{}
+	for _, d := range report.Drift {
+		found[d.Account] = d
+	}
+	if d, ok := found[MemberAccount(alice.ID)]; !ok || d.Expected != 5000 || d.Ledger != 1000 {
+		t.Errorf("expected Alice's drift (cached 5000, ledger 1000), got %+v", d)
+	}
+	if d, ok := found[PoolEscrow(pool.ID)]; !ok || d.Expected != 150 || d.Ledger != 100 {
+		t.Errorf("expected pool escrow drift (expected 150, ledger 100), got %+v", d)
+	}
+	if _, ok := found[MemberAccount(bob.ID)]; ok {
+		t.Error("expected Bob's account to reconcile")
+	}
+}
+
+func TestBackfillLedgerOpensExistingGroups(t *testing.T) {
+	db, poolSvc, _, group, alice, bob := setupPoolTest(t)
+
+	pool, _ := poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{
+		Title:   "Coin toss",
+		Options: []string{"Heads", "Tails"},
+	})
+	poolSvc.PlaceBet(pool.ID, bob.ID, PlaceBetRequest{OptionID: pool.Options[0].ID, Points: 100})
+
+	// Pretend the group predates the ledger
+	db.Where("group_id = ?", group.ID).Delete(&models.LedgerEntry{})
+
+	if err := BackfillLedger(db); err != nil {
+		t.Fatalf("BackfillLedger failed: %v", err)
+	}
+	report := assertReconciled(t, db, group.ID)
+	if report.Treasury != -2000 {
+		t.Errorf("expected opening entries totalling 2000, got treasury %d", report.Treasury)
+	}
+
+	// Running it again doesn't double up
+	if err := BackfillLedger(db); err != nil {
+		t.Fatalf("second BackfillLedger failed: %v", err)
+	}
+	assertReconciled(t, db, group.ID)
+}
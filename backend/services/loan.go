@@ -0,0 +1,311 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/codyseavey/bets/models"
+)
+
+type LoanService struct {
+	db *gorm.DB
+}
+
+func NewLoanService(db *gorm.DB) *LoanService {
+	return &LoanService{db: db}
+}
+
+type OfferLoanRequest struct {
+	BorrowerID      string     `json:"borrower_id" binding:"required"`
+	Principal       int        `json:"principal" binding:"required,gt=0"`
+	InterestPercent int        `json:"interest_percent" binding:"gte=0,lte=100"`
+	DueAt           *time.Time `json:"due_at"`
+	Note            string     `json:"note"`
+}
+
+type RepayLoanRequest struct {
+	Amount int `json:"amount" binding:"required,gt=0"`
+}
+
+// OfferLoan proposes a loan on the lender's terms. Nothing moves until the
+// borrower accepts.
+func (s *LoanService) OfferLoan(groupID, lenderID string, req OfferLoanRequest) (*models.Loan, error) {
+	if req.BorrowerID == lenderID {
+		return nil, fmt.Errorf("you can't lend points to yourself")
+	}
+	if req.DueAt != nil && req.DueAt.Before(time.Now()) {
+		return nil, fmt.Errorf("due date must be in the future")
+	}
+
+	var lender models.GroupMember
+	if err := s.db.Where("group_id = ? AND user_id = ?", groupID, lenderID).First(&lender).Error; err != nil {
+		return nil, fmt.Errorf("not a member of this group")
+	}
+	if lender.PointsBalance < req.Principal {
+		return nil, fmt.Errorf("insufficient points (have %d, need %d)", lender.PointsBalance, req.Principal)
+	}
+	if !isMemberTx(s.db, groupID, req.BorrowerID) {
+		return nil, fmt.Errorf("borrower is not a member of this group")
+	}
+
+	loan := &models.Loan{
+		ID:              uuid.New().String(),
+		GroupID:         groupID,
+		LenderID:        lenderID,
+		BorrowerID:      req.BorrowerID,
+		Principal:       req.Principal,
+		InterestPercent: req.InterestPercent,
+		Owed:            req.Principal + req.Principal*req.InterestPercent/100,
+		DueAt:           req.DueAt,
+		Note:            req.Note,
+		Status:          models.LoanStatusOffered,
+	}
+	if err := s.db.Create(loan).Error; err != nil {
+		return nil, fmt.Errorf("failed to create loan: %w", err)
+	}
+
+	return s.GetLoan(loan.ID)
+}
+
+func (s *LoanService) GetLoan(loanID string) (*models.Loan, error) {
+	var loan models.Loan
+	err := s.db.
+		Preload("Lender").
+		Preload("Borrower").
+		First(&loan, "id = ?", loanID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &loan, nil
+}
+
+// GetUserLoans lists loans in a group that the user made or took out.
+func (s *LoanService) GetUserLoans(groupID, userID, status string) ([]models.Loan, error) {
+	query := s.db.
+		Where("group_id = ? AND (lender_id = ? OR borrower_id = ?)", groupID, userID, userID).
+		Preload("Lender").
+		Preload("Borrower").
+		Order("created_at DESC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var loans []models.Loan
+	if err := query.Find(&loans).Error; err != nil {
+		return nil, err
+	}
+	return loans, nil
+}
+
+// AcceptLoan pays the principal from the lender to the borrower.
+func (s *LoanService) AcceptLoan(loanID, userID string) (*models.Loan, error) {
+	tx := s.db.Begin()
+
+	loan, err := loanInStatusTx(tx, loanID, models.LoanStatusOffered)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if loan.BorrowerID != userID {
+		tx.Rollback()
+		return nil, fmt.Errorf("only the borrower can accept")
+	}
+
+	names := userNamesTx(tx, loan.LenderID, loan.BorrowerID)
+	if err := payMemberTx(tx, loan.GroupID, loan.LenderID, loan.BorrowerID, loan.Principal,
+		models.PointsLogLoanGiven, models.PointsLogLoanReceived, loan.ID,
+		fmt.Sprintf("Loan to %s, %d to be repaid", names[loan.BorrowerID], loan.Owed),
+		fmt.Sprintf("Loan from %s, %d to be repaid", names[loan.LenderID], loan.Owed)); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("lender can no longer cover the loan: %w", err)
+	}
+
+	now := time.Now()
+	if err := tx.Model(loan).Updates(map[string]interface{}{
+		"status":      models.LoanStatusActive,
+		"accepted_at": now,
+	}).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetLoan(loanID)
+}
+
+// DeclineLoan is the borrower turning an offer down.
+func (s *LoanService) DeclineLoan(loanID, userID string) (*models.Loan, error) {
+	return s.closeLoan(loanID, userID, models.LoanStatusOffered, models.LoanStatusDeclined)
+}
+
+// WithdrawLoan lets the lender take back an offer that hasn't been accepted.
+func (s *LoanService) WithdrawLoan(loanID, userID string) (*models.Loan, error) {
+	return s.closeLoan(loanID, userID, models.LoanStatusOffered, models.LoanStatusWithdrawn)
+}
+
+// ForgiveLoan lets the lender write off whatever is left on an active loan.
+func (s *LoanService) ForgiveLoan(loanID, userID string) (*models.Loan, error) {
+	return s.closeLoan(loanID, userID, models.LoanStatusActive, models.LoanStatusForgiven)
+}
+
+func (s *LoanService) closeLoan(loanID, userID string, from, to models.LoanStatus) (*models.Loan, error) {
+	tx := s.db.Begin()
+
+	loan, err := loanInStatusTx(tx, loanID, from)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	switch to {
+	case models.LoanStatusDeclined:
+		if loan.BorrowerID != userID {
+			tx.Rollback()
+			return nil, fmt.Errorf("only the borrower can decline")
+		}
+	case models.LoanStatusWithdrawn:
+		if loan.LenderID != userID {
+			tx.Rollback()
+			return nil, fmt.Errorf("only the lender can withdraw")
+		}
+	case models.LoanStatusForgiven:
+		if loan.LenderID != userID {
+			tx.Rollback()
+			return nil, fmt.Errorf("only the lender can forgive a loan")
+		}
+	}
+
+	now := time.Now()
+	if err := tx.Model(loan).Updates(map[string]interface{}{
+		"status":    to,
+		"closed_at": now,
+	}).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetLoan(loanID)
+}
+
+// RepayLoan is the borrower paying some or all of an active loan by hand.
+func (s *LoanService) RepayLoan(loanID, userID string, req RepayLoanRequest) (*models.Loan, error) {
+	tx := s.db.Begin()
+
+	loan, err := loanInStatusTx(tx, loanID, models.LoanStatusActive)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if loan.BorrowerID != userID {
+		tx.Rollback()
+		return nil, fmt.Errorf("only the borrower can repay")
+	}
+	if outstanding := loan.Owed - loan.Repaid; req.Amount > outstanding {
+		tx.Rollback()
+		return nil, fmt.Errorf("only %d left to repay", outstanding)
+	}
+	if !isMemberTx(tx, loan.GroupID, loan.LenderID) {
+		tx.Rollback()
+		return nil, fmt.Errorf("lender is no longer a member of this group")
+	}
+
+	if err := repayLoanTx(tx, loan, req.Amount, ""); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetLoan(loanID)
+}
+
+// collectLoansTx puts up to amount of a borrower's fresh winnings toward their
+// active loans, oldest first. Loans from lenders who have since left the
+// group are skipped.
+func collectLoansTx(tx *gorm.DB, groupID, borrowerID string, amount int, source string) error {
+	if amount <= 0 || !isMemberTx(tx, groupID, borrowerID) {
+		return nil
+	}
+
+	var loans []models.Loan
+	if err := tx.Where("group_id = ? AND borrower_id = ? AND status = ?", groupID, borrowerID, models.LoanStatusActive).
+		Order("accepted_at").Find(&loans).Error; err != nil {
+		return err
+	}
+	for i := range loans {
+		if amount == 0 {
+			break
+		}
+		loan := &loans[i]
+		if !isMemberTx(tx, groupID, loan.LenderID) {
+			continue
+		}
+		payment := min(amount, loan.Owed-loan.Repaid)
+		if err := repayLoanTx(tx, loan, payment, source); err != nil {
+			return err
+		}
+		amount -= payment
+	}
+	return nil
+}
+
+// repayLoanTx moves a repayment from the borrower to the lender and closes the
+// loan once it's paid off. source, if set, says where the repayment came from.
+func repayLoanTx(tx *gorm.DB, loan *models.Loan, amount int, source string) error {
+	names := userNamesTx(tx, loan.LenderID, loan.BorrowerID)
+	repaid := loan.Repaid + amount
+	progress := fmt.Sprintf("%d of %d", repaid, loan.Owed)
+	if source != "" {
+		progress += ", " + source
+	}
+	if err := payMemberTx(tx, loan.GroupID, loan.BorrowerID, loan.LenderID, amount,
+		models.PointsLogLoanRepayment, models.PointsLogLoanCollected, loan.ID,
+		fmt.Sprintf("Loan repayment to %s (%s)", names[loan.LenderID], progress),
+		fmt.Sprintf("Loan repayment from %s (%s)", names[loan.BorrowerID], progress)); err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{"repaid": repaid}
+	if repaid == loan.Owed {
+		updates["status"] = models.LoanStatusRepaid
+		updates["closed_at"] = time.Now()
+	}
+	if err := tx.Model(&models.Loan{}).Where("id = ?", loan.ID).Updates(updates).Error; err != nil {
+		return err
+	}
+	loan.Repaid = repaid
+	return nil
+}
+
+func loanInStatusTx(tx *gorm.DB, loanID string, status models.LoanStatus) (*models.Loan, error) {
+	var loan models.Loan
+	if err := tx.First(&loan, "id = ?", loanID).Error; err != nil {
+		return nil, fmt.Errorf("loan not found")
+	}
+	if loan.Status != status {
+		return nil, fmt.Errorf("loan is %s", loan.Status)
+	}
+	return &loan, nil
+}
+
+func userNamesTx(tx *gorm.DB, userIDs ...string) map[string]string {
+	var users []models.User
+	tx.Select("id", "name").Where("id IN ?", userIDs).Find(&users)
+	names := make(map[string]string, len(users))
+	for _, u := range users {
+		names[u.ID] = u.Name
+	}
+	return names
+}
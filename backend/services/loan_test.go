@@ -0,0 +1,231 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codyseavey/bets/models"
+)
+
+func TestLoanLifecycle(t *testing.T) {
+	db, poolSvc, _, group, alice, bob := setupPoolTest(t)
+	svc := NewLoanService(db)
+
+	if _, err := svc.OfferLoan(group.ID, alice.ID, OfferLoanRequest{BorrowerID: alice.ID, Principal: 100}); err == nil {
+		t.Error("expected error lending to yourself")
+	}
+	if _, err := svc.OfferLoan(group.ID, alice.ID, OfferLoanRequest{BorrowerID: bob.ID, Principal: 5000}); err == nil {
+		t.Error("expected error lending more than the balance")
+	}
+	past := time.Now().Add(-time.Hour)
+	if _, err := svc.OfferLoan(group.ID, alice.ID, OfferLoanRequest{BorrowerID: bob.ID, Principal: 100, DueAt: &past}); err == nil {
+		t.Error("expected error with a due date in the past")
+	}
+
+	due := time.Now().AddDate(0, 0, 14)
+	loan, err := svc.OfferLoan(group.ID, alice.ID, OfferLoanRequest{
+		BorrowerID: bob.ID, Principal: 200, InterestPercent: 10, DueAt: &due, Note: "till payday",
+	})
+	if err != nil {
+		t.Fatalf("OfferLoan failed: %v", err)
+	}
+	if loan.Owed != 220 || loan.Status != models.LoanStatusOffered {
+		t.Errorf("expected an offer owing 220, got %+v", loan)
+	}
+	if got := memberBalance(t, poolSvc, group.ID, alice.ID); got != 1000 {
+		t.Errorf("expected nothing moved before acceptance, got %d", got)
+	}
+
+	if _, err := svc.AcceptLoan(loan.ID, alice.ID); err == nil {
+		t.Error("expected error when the lender accepts")
+	}
+	loan, err = svc.AcceptLoan(loan.ID, bob.ID)
+	if err != nil {
+		t.Fatalf("AcceptLoan failed: %v", err)
+	}
+	if got := memberBalance(t, poolSvc, group.ID, bob.ID); got != 1200 {
+		t.Errorf("expected Bob at 1200, got %d", got)
+	}
+	if _, err := svc.WithdrawLoan(loan.ID, alice.ID); err == nil {
+		t.Error("expected error withdrawing an accepted loan")
+	}
+
+	if _, err := svc.RepayLoan(loan.ID, bob.ID, RepayLoanRequest{Amount: 500}); err == nil {
+		t.Error("expected error repaying more than is owed")
+	}
+	if _, err := svc.RepayLoan(loan.ID, alice.ID, RepayLoanRequest{Amount: 20}); err == nil {
+		t.Error("expected error when the lender repays")
+	}
+	loan, err = svc.RepayLoan(loan.ID, bob.ID, RepayLoanRequest{Amount: 20})
+	if err != nil {
+		t.Fatalf("RepayLoan failed: %v", err)
+	}
+	if loan.Repaid != 20 || loan.Status != models.LoanStatusActive {
+		t.Errorf("expected 20 repaid and still active, got %+v", loan)
+	}
+
+	// Bob wins 300 net on a pool; 200 of it clears the loan
+	pool, _ := poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{
+		Title:   "Coin toss",
+		Options: []string{"Heads", "Tails"},
+	})
+	poolSvc.PlaceBet(pool.ID, bob.ID, PlaceBetRequest{OptionID: pool.Options[0].ID, Points: 100})
+	poolSvc.PlaceBet(pool.ID, alice.ID, PlaceBetRequest{OptionID: pool.Options[1].ID, Points: 300})
+	if err := poolSvc.ResolvePool(pool.ID, pool.Options[0].ID, alice.ID, false); err != nil {
+		t.Fatalf("ResolvePool failed: %v", err)
+	}
+
+	loan, _ = svc.GetLoan(loan.ID)
+	if loan.Repaid != 220 || loan.Status != models.LoanStatusRepaid || loan.ClosedAt == nil {
+		t.Errorf("expected the loan repaid from winnings, got %+v", loan)
+	}
+	// Bob: 1000 + 200 loan - 20 - 100 stake + 400 payout - 200 collected
+	if got := memberBalance(t, poolSvc, group.ID, bob.ID); got != 1280 {
+		t.Errorf("expected Bob at 1280, got %d", got)
+	}
+	// Alice: 1000 - 200 + 20 - 300 stake + 200 collected
+	if got := memberBalance(t, poolSvc, group.ID, alice.ID); got != 720 {
+		t.Errorf("expected Alice at 720, got %d", got)
+	}
+
+	var collected models.PointsLog
+	db.Where("user_id = ? AND type = ?", alice.ID, models.PointsLogLoanCollected).Order("created_at DESC").First(&collected)
+	if collected.Note != `Loan repayment from Bob (220 of 220, from pool winnings)` {
+		t.Errorf("unexpected collection note %q", collected.Note)
+	}
+
+	assertReconciled(t, db, group.ID)
+	report, _ := AuditGroup(db, group.ID)
+	if !report.Clean() {
+		t.Errorf("expected a clean audit, got %+v %+v", report.Balances, report.Escrows)
+	}
+}
+
+func TestLoanCollectionSpansLoansOldestFirst(t *testing.T) {
+	db, poolSvc, groupSvc, group, alice, bob := setupPoolTest(t)
+	carol := createTestUser(t, db, "carol", "Carol")
+	groupSvc.JoinGroup(group.InviteCode, carol.ID)
+	svc := NewLoanService(db)
+
+	first, _ := svc.OfferLoan(group.ID, alice.ID, OfferLoanRequest{BorrowerID: bob.ID, Principal: 50})
+	svc.AcceptLoan(first.ID, bob.ID)
+	second, _ := svc.OfferLoan(group.ID, carol.ID, OfferLoanRequest{BorrowerID: bob.ID, Principal: 100})
+	svc.AcceptLoan(second.ID, bob.ID)
+
+	pool, _ := poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{
+		Title:   "Coin toss",
+		Options: []string{"Heads", "Tails"},
+	})
+	poolSvc.PlaceBet(pool.ID, bob.ID, PlaceBetRequest{OptionID: pool.Options[0].ID, Points: 100})
+	poolSvc.PlaceBet(pool.ID, alice.ID, PlaceBetRequest{OptionID: pool.Options[1].ID, Points: 80})
+	poolSvc.ResolvePool(pool.ID, pool.Options[0].ID, alice.ID, false)
+
+	first, _ = svc.GetLoan(first.ID)
+	second, _ = svc.GetLoan(second.ID)
+	if first.Status != models.LoanStatusRepaid {
+		t.Errorf("expected the older loan repaid first, got %+v", first)
+	}
+	if second.Repaid != 30 || second.Status != models.LoanStatusActive {
+		t.Errorf("expected the rest of the 80 won to go to the newer loan, got %+v", second)
+	}
+
+	// A lost bet collects nothing; forgiving closes out the rest
+	if _, err := svc.ForgiveLoan(second.ID, bob.ID); err == nil {
+		t.Error("expected error when the borrower forgives")
+	}
+	second, err := svc.ForgiveLoan(second.ID, carol.ID)
+	if err != nil || second.Status != models.LoanStatusForgiven {
+		t.Errorf("expected the loan forgiven, got %+v, %v", second, err)
+	}
+
+	assertReconciled(t, db, group.ID)
+}
+
+func TestLoanDeclineAndWithdraw(t *testing.T) {
+	db, poolSvc, _, group, alice, bob := setupPoolTest(t)
+	svc := NewLoanService(db)
+
+	loan, _ := svc.OfferLoan(group.ID, alice.ID, OfferLoanRequest{BorrowerID: bob.ID, Principal: 100})
+	if _, err := svc.DeclineLoan(loan.ID, alice.ID); err == nil {
+		t.Error("expected error when the lender declines")
+	}
+	declined, err := svc.DeclineLoan(loan.ID, bob.ID)
+	if err != nil || declined.Status != models.LoanStatusDeclined {
+		t.Errorf("expected declined, got %+v, %v", declined, err)
+	}
+	if _, err := svc.AcceptLoan(loan.ID, bob.ID); err == nil {
+		t.Error("expected error accepting a declined loan")
+	}
+
+	offer, _ := svc.OfferLoan(group.ID, alice.ID, OfferLoanRequest{BorrowerID: bob.ID, Principal: 100})
+	withdrawn, err := svc.WithdrawLoan(offer.ID, alice.ID)
+	if err != nil || withdrawn.Status != models.LoanStatusWithdrawn {
+		t.Errorf("expected withdrawn, got %+v, %v", withdrawn, err)
+	}
+
+	if got := memberBalance(t, poolSvc, group.ID, bob.ID); got != 1000 {
+		t.Errorf("expected Bob untouched, got %d", got)
+	}
+	loans, _ := svc.GetUserLoans(group.ID, bob.ID, "")
+	if len(loans) != 2 {
+		t.Errorf("expected 2 loans listed, got %d", len(loans))
+	}
+}
+
+func TestLoanCollectionDoesNotNameRestrictedPool(t *testing.T) {
+	db, poolSvc, groupSvc, group, alice, bob := setupPoolTest(t)
+	carol := createTestUser(t, db, "carol", "Carol")
+	groupSvc.JoinGroup(group.InviteCode, carol.ID)
+	svc := NewLoanService(db)
+
+	// Carol lends to Bob, then is kept out of a pool Bob wins
+	loan, _ := svc.OfferLoan(group.ID, carol.ID, OfferLoanRequest{BorrowerID: bob.ID, Principal: 50})
+	svc.AcceptLoan(loan.ID, bob.ID)
+	pool, err := poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{
+		Title:          "Carol's surprise party",
+		Options:        []string{"Yes", "No"},
+		ParticipantIDs: []string{bob.ID},
+	})
+	if err != nil {
+		t.Fatalf("CreatePool failed: %v", err)
+	}
+	poolSvc.PlaceBet(pool.ID, bob.ID, PlaceBetRequest{OptionID: pool.Options[0].ID, Points: 100})
+	poolSvc.PlaceBet(pool.ID, alice.ID, PlaceBetRequest{OptionID: pool.Options[1].ID, Points: 100})
+	poolSvc.ResolvePool(pool.ID, pool.Options[0].ID, alice.ID, false)
+
+	loan, _ = svc.GetLoan(loan.ID)
+	if loan.Status != models.LoanStatusRepaid {
+		t.Fatalf("expected the loan repaid from winnings, got %+v", loan)
+	}
+	var logs []models.PointsLog
+	db.Where("reference_id = ?", loan.ID).Find(&logs)
+	for _, l := range logs {
+		if strings.Contains(l.Note, pool.Title) {
+			t.Errorf("loan entry %q names a pool its lender can't see", l.Note)
+		}
+	}
+}
+
+func TestLoanOverdue(t *testing.T) {
+	db, _, _, group, alice, bob := setupPoolTest(t)
+	svc := NewLoanService(db)
+
+	due := time.Now().Add(time.Hour)
+	loan, _ := svc.OfferLoan(group.ID, alice.ID, OfferLoanRequest{BorrowerID: bob.ID, Principal: 100, DueAt: &due})
+	loan, _ = svc.AcceptLoan(loan.ID, bob.ID)
+	if loan.Overdue {
+		t.Error("expected a loan before its due date not to be overdue")
+	}
+
+	db.Model(&models.Loan{}).Where("id = ?", loan.ID).Update("due_at", time.Now().Add(-time.Hour))
+	loan, _ = svc.GetLoan(loan.ID)
+	if !loan.Overdue {
+		t.Error("expected an active loan past its due date to be overdue")
+	}
+
+	loan, _ = svc.RepayLoan(loan.ID, bob.ID, RepayLoanRequest{Amount: 100})
+	if loan.Status != models.LoanStatusRepaid || loan.Overdue {
+		t.Errorf("expected a repaid loan not to be overdue, got %+v", loan)
+	}
+}
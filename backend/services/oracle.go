@@ -0,0 +1,532 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/codyseavey/bets/models"
+)
+
+// Oracle is an external source of truth for a pool's outcome. Implementations
+// report the raw outcome (an option label or ID); OracleService takes care of
+// matching it to an option and resolving the pool.
+type Oracle interface {
+	// Outcome fetches the current outcome for a binding. decided is false
+	// while the source hasn't settled yet (e.g. the game is still in progress).
+	Outcome(ctx context.Context, binding *models.PoolOracle) (outcome string, decided bool, err error)
+}
+
+const OracleTypeHTTPJSON = "http_json"
+
+const (
+	defaultOraclePollInterval = 300
+	minOraclePollInterval     = 30
+	oracleTickInterval        = 30 * time.Second
+)
+
+// ErrOracleCheckFailed wraps errors from polling a feed, whose details are
+// only shown to admins.
+var ErrOracleCheckFailed = errors.New("oracle check failed")
+
+type OracleService struct {
+	db      *gorm.DB
+	pools   *PoolService
+	hub     *Hub
+	oracles map[string]Oracle
+	dialer  *oracleDialer
+}
+
+func NewOracleService(db *gorm.DB, pools *PoolService, hub *Hub) *OracleService {
+	s := &OracleService{
+		db:      db,
+		pools:   pools,
+		hub:     hub,
+		oracles: make(map[string]Oracle),
+		dialer:  &oracleDialer{allowed: make(map[string]bool)},
+	}
+	// No proxy, so every connection goes through the dialer's address check
+	s.Register(OracleTypeHTTPJSON, NewHTTPJSONOracle(&http.Client{
+		Timeout:   10 * time.Second,
+		Transport: &http.Transport{DialContext: s.dialer.DialContext},
+	}))
+	return s
+}
+
+// AllowHosts lets oracles reach hosts that would otherwise be refused for
+// resolving to a private, loopback or link-local address. It's meant for
+// tests, and must be called before the service starts polling.
+func (s *OracleService) AllowHosts(hosts ...string) {
+	for _, h := range hosts {
+		s.dialer.allowed[strings.ToLower(h)] = true
+	}
+}
+
+// oracleDialer keeps oracle feeds from being pointed at the server's own
+// network. Addresses are checked when a URL is bound and again on every
+// connection, so a DNS change after binding doesn't get around it.
+type oracleDialer struct {
+	net.Dialer
+	allowed map[string]bool
+}
+
+// publicAddrs resolves host and fails unless every address it resolves to is
+// publicly routable.
+func (d *oracleDialer) publicAddrs(ctx context.Context, host string) ([]net.IP, error) {
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, fmt.Errorf("can't resolve %s", host)
+		}
+		for _, a := range addrs {
+			ips = append(ips, a.IP)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("can't resolve %s", host)
+	}
+	if d.allowed[strings.ToLower(host)] {
+		return ips, nil
+	}
+	for _, ip := range ips {
+		if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+			ip.IsUnspecified() || ip.IsMulticast() {
+			return nil, fmt.Errorf("%s is not a public address", host)
+		}
+	}
+	return ips, nil
+}
+
+func (d *oracleDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := d.publicAddrs(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	// Dial the addresses that were checked rather than resolving again
+	for _, ip := range ips {
+		var conn net.Conn
+		if conn, err = d.Dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port)); err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+// Register adds (or replaces) the oracle implementation for a binding type.
+func (s *OracleService) Register(oracleType string, oracle Oracle) {
+	s.oracles[oracleType] = oracle
+}
+
+type BindOracleRequest struct {
+	Type                string `json:"type"`
+	URL                 string `json:"url" binding:"required"`
+	Selector            string `json:"selector" binding:"required"`
+	PollIntervalSeconds int    `json:"poll_interval_seconds"`
+}
+
+// groupPool loads a pool, making sure it belongs to the group the caller's
+// role was checked against.
+func (s *OracleService) groupPool(groupID, poolID string) (*models.Pool, error) {
+	var pool models.Pool
+	if err := s.db.First(&pool, "id = ? AND group_id = ?", poolID, groupID).Error; err != nil {
+		return nil, fmt.Errorf("pool not found")
+	}
+	return &pool, nil
+}
+
+// BindOracle attaches an oracle to a pool, replacing any existing binding.
+func (s *OracleService) BindOracle(groupID, poolID, userID string, isAdmin bool, req BindOracleRequest) (*models.PoolOracle, error) {
+	pool, err := s.groupPool(groupID, poolID)
+	if err != nil {
+		return nil, err
+	}
+	if pool.CreatedBy != userID && !isAdmin {
+		return nil, fmt.Errorf("only pool creator or group admin can configure an oracle")
+	}
+	if err := checkChallengeRefereeTx(s.db, pool, userID, isAdmin); err != nil {
+		return nil, err
+	}
+	if pool.Status != models.PoolStatusOpen && pool.Status != models.PoolStatusLocked {
+		return nil, fmt.Errorf("pool is already %s", pool.Status)
+	}
+
+	if req.Type == "" {
+		req.Type = OracleTypeHTTPJSON
+	}
+	if _, ok := s.oracles[req.Type]; !ok {
+		return nil, fmt.Errorf("unknown oracle type %q", req.Type)
+	}
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("url must be an absolute http(s) URL")
+	}
+	if _, err := s.dialer.publicAddrs(context.Background(), u.Hostname()); err != nil {
+		return nil, fmt.Errorf("url must point at a public host: %w", err)
+	}
+	if _, err := parseJSONPath(req.Selector); err != nil {
+		return nil, err
+	}
+	if req.PollIntervalSeconds == 0 {
+		req.PollIntervalSeconds = defaultOraclePollInterval
+	}
+	if req.PollIntervalSeconds < minOraclePollInterval {
+		return nil, fmt.Errorf("poll interval must be at least %d seconds", minOraclePollInterval)
+	}
+
+	binding := &models.PoolOracle{
+		ID:                  uuid.New().String(),
+		PoolID:              poolID,
+		Type:                req.Type,
+		URL:                 req.URL,
+		Selector:            req.Selector,
+		PollIntervalSeconds: req.PollIntervalSeconds,
+		Status:              models.OracleStatusActive,
+		CreatedBy:           userID,
+	}
+
+	tx := s.db.Begin()
+	if err := tx.Where("pool_id = ?", poolID).Delete(&models.PoolOracle{}).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Create(binding).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to bind oracle: %w", err)
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return binding, nil
+}
+
+func (s *OracleService) GetOracle(groupID, poolID string) (*models.PoolOracle, error) {
+	if _, err := s.groupPool(groupID, poolID); err != nil {
+		return nil, err
+	}
+	var binding models.PoolOracle
+	if err := s.db.First(&binding, "pool_id = ?", poolID).Error; err != nil {
+		return nil, err
+	}
+	return &binding, nil
+}
+
+func (s *OracleService) UnbindOracle(groupID, poolID, userID string, isAdmin bool) error {
+	pool, err := s.groupPool(groupID, poolID)
+	if err != nil {
+		return err
+	}
+	if pool.CreatedBy != userID && !isAdmin {
+		return fmt.Errorf("only pool creator or group admin can remove an oracle")
+	}
+	// Otherwise a challenger could pull the oracle a neutral admin bound
+	if err := checkChallengeRefereeTx(s.db, pool, userID, isAdmin); err != nil {
+		return err
+	}
+	return s.db.Where("pool_id = ?", poolID).Delete(&models.PoolOracle{}).Error
+}
+
+// CheckNow polls a pool's oracle immediately instead of waiting for the
+// scheduler.
+func (s *OracleService) CheckNow(ctx context.Context, groupID, poolID, userID string, isAdmin bool) (*models.PoolOracle, bool, error) {
+	pool, err := s.groupPool(groupID, poolID)
+	if err != nil {
+		return nil, false, err
+	}
+	if pool.CreatedBy != userID && !isAdmin {
+		return nil, false, fmt.Errorf("only pool creator or group admin can check an oracle")
+	}
+	binding, err := s.GetOracle(groupID, poolID)
+	if err != nil {
+		return nil, false, fmt.Errorf("pool has no oracle")
+	}
+	resolved, err := s.Check(ctx, binding)
+	if err != nil {
+		return binding, false, fmt.Errorf("%w: %v", ErrOracleCheckFailed, err)
+	}
+	return binding, resolved, nil
+}
+
+// Run polls due oracle bindings until ctx is cancelled.
+func (s *OracleService) Run(ctx context.Context) {
+	ticker := time.NewTicker(oracleTickInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.CheckDue(ctx)
+		}
+	}
+}
+
+// CheckDue checks every active binding whose poll interval has elapsed.
+func (s *OracleService) CheckDue(ctx context.Context) {
+	var bindings []models.PoolOracle
+	if err := s.db.Where("status = ?", models.OracleStatusActive).Find(&bindings).Error; err != nil {
+		log.Printf("Failed to load oracle bindings: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for i := range bindings {
+		b := &bindings[i]
+		if b.LastCheckedAt != nil && now.Sub(*b.LastCheckedAt) < time.Duration(b.PollIntervalSeconds)*time.Second {
+			continue
+		}
+		if _, err := s.Check(ctx, b); err != nil {
+			log.Printf("Oracle check for pool %s failed: %v", b.PoolID, err)
+		}
+	}
+}
+
+// Check queries a binding's oracle once and resolves the pool if the outcome
+// is decided. Returns whether the pool was resolved.
+func (s *OracleService) Check(ctx context.Context, binding *models.PoolOracle) (bool, error) {
+	oracle, ok := s.oracles[binding.Type]
+	if !ok {
+		return false, s.recordCheck(binding, "", fmt.Errorf("unknown oracle type %q", binding.Type))
+	}
+
+	var pool models.Pool
+	if err := s.db.Preload("Options").First(&pool, "id = ?", binding.PoolID).Error; err != nil {
+		return false, s.recordCheck(binding, "", fmt.Errorf("pool not found"))
+	}
+	if pool.Status != models.PoolStatusOpen && pool.Status != models.PoolStatusLocked {
+		// Resolved or cancelled by hand in the meantime; nothing left to do
+		return false, s.db.Model(binding).Update("status", models.OracleStatusResolved).Error
+	}
+
+	outcome, decided, err := oracle.Outcome(ctx, binding)
+	if err != nil {
+		return false, s.recordCheck(binding, "", err)
+	}
+	if !decided {
+		return false, s.recordCheck(binding, "", nil)
+	}
+
+	optionID := MatchOption(pool.Options, outcome)
+	if optionID == "" {
+		return false, s.recordCheck(binding, outcome, fmt.Errorf("outcome %q does not match any option", outcome))
+	}
+
+	if err := s.pools.ResolvePoolAs(pool.ID, optionID, nil, "oracle:"+binding.Type); err != nil {
+		return false, s.recordCheck(binding, outcome, err)
+	}
+	if err := s.recordCheck(binding, outcome, nil); err != nil {
+		return true, err
+	}
+	if err := s.db.Model(binding).Update("status", models.OracleStatusResolved).Error; err != nil {
+		return true, err
+	}
+
+	if s.hub != nil {
+		if resolved, err := s.pools.GetPool(pool.ID, ""); err == nil {
+			s.pools.BroadcastPoolEvent(s.hub, pool.ID, WSEvent{
+				Type:    "pool_resolved",
+				Payload: resolved,
+			})
+		}
+	}
+
+	return true, nil
+}
+
+// recordCheck stores the result of a poll and passes checkErr through so
+// callers can return it directly.
+func (s *OracleService) recordCheck(binding *models.PoolOracle, value string, checkErr error) error {
+	now := time.Now()
+	errMsg := ""
+	if checkErr != nil {
+		errMsg = checkErr.Error()
+	}
+	binding.LastCheckedAt = &now
+	binding.LastValue = value
+	binding.LastError = errMsg
+	if err := s.db.Model(binding).Updates(map[string]interface{}{
+		"last_checked_at": now,
+		"last_value":      value,
+		"last_error":      errMsg,
+	}).Error; err != nil {
+		return err
+	}
+	return checkErr
+}
+
+// MatchOption finds the option whose ID or label (case-insensitively) equals
+// the oracle's outcome.
+func MatchOption(options []models.PoolOption, outcome string) string {
+	outcome = strings.TrimSpace(outcome)
+	for _, o := range options {
+		if o.ID == outcome || strings.EqualFold(o.Label, outcome) {
+			return o.ID
+		}
+	}
+	return ""
+}
+
+// HTTPJSONOracle fetches a JSON document and picks the outcome out of it with
+// a JSONPath-style selector such as "$.games[0].winner". A missing or null
+// value means the outcome isn't decided yet.
+type HTTPJSONOracle struct {
+	client *http.Client
+}
+
+func NewHTTPJSONOracle(client *http.Client) *HTTPJSONOracle {
+	return &HTTPJSONOracle{client: client}
+}
+
+const maxOracleResponseSize = 1 << 20
+
+func (o *HTTPJSONOracle) Outcome(ctx context.Context, binding *models.PoolOracle) (string, bool, error) {
+	path, err := parseJSONPath(binding.Selector)
+	if err != nil {
+		return "", false, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, binding.URL, nil)
+	if err != nil {
+		return "", false, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return "", false, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", false, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var doc interface{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxOracleResponseSize)).Decode(&doc); err != nil {
+		return "", false, fmt.Errorf("invalid JSON response: %w", err)
+	}
+
+	value, found := path.lookup(doc)
+	if !found || value == nil {
+		return "", false, nil
+	}
+
+	switch v := value.(type) {
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return "", false, nil
+		}
+		return v, true, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true, nil
+	case bool:
+		return strconv.FormatBool(v), true, nil
+	default:
+		return "", false, fmt.Errorf("selector must point at a scalar value")
+	}
+}
+
+// jsonPath is a parsed selector: a sequence of object keys (string) and array
+// indexes (int).
+type jsonPath []interface{}
+
+// parseJSONPath supports the common subset of JSONPath used to point at a
+// single value: $.a.b, $.a[0].b, $['key with spaces'] and a.b without "$".
+func parseJSONPath(selector string) (jsonPath, error) {
+	s := strings.TrimSpace(selector)
+	if s == "" {
+		return nil, fmt.Errorf("selector is required")
+	}
+	s = strings.TrimPrefix(s, "$")
+
+	var path jsonPath
+	for len(s) > 0 {
+		switch s[0] {
+		case '.':
+			s = s[1:]
+		case '[':
+			end := strings.Index(s, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid selector %q: unclosed [", selector)
+			}
+			inner := strings.TrimSpace(s[1:end])
+			s = s[end+1:]
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				path = append(path, inner[1:len(inner)-1])
+				continue
+			}
+			idx, err := strconv.Atoi(inner)
+			if err != nil || idx < 0 {
+				return nil, fmt.Errorf("invalid selector %q: bad index %q", selector, inner)
+			}
+			path = append(path, idx)
+			continue
+		}
+
+		end := strings.IndexAny(s, ".[")
+		if end < 0 {
+			end = len(s)
+		}
+		if end == 0 {
+			continue
+		}
+		path = append(path, s[:end])
+		s = s[end:]
+	}
+
+	if len(path) == 0 {
+		return nil, fmt.Errorf("invalid selector %q: must select a value", selector)
+	}
+	return path, nil
+}
+
+func (p jsonPath) lookup(doc interface{}) (interface{}, bool) {
+	cur := doc
+	for _, seg := range p {
+		switch key := seg.(type) {
+		case string:
+			// Allow "a.0.b" as shorthand for "a[0].b"
+			if arr, ok := cur.([]interface{}); ok {
+				idx, err := strconv.Atoi(key)
+				if err != nil || idx < 0 || idx >= len(arr) {
+					return nil, false
+				}
+				cur = arr[idx]
+				continue
+			}
+			obj, ok := cur.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			if cur, ok = obj[key]; !ok {
+				return nil, false
+			}
+		case int:
+			arr, ok := cur.([]interface{})
+			if !ok || key >= len(arr) {
+				return nil, false
+			}
+			cur = arr[key]
+		}
+	}
+	return cur, true
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/codyseavey/bets/models"
+)
+
+// fakeFeed is a local stand-in for a sports/stock data feed whose body can be
+// swapped mid-test.
+type fakeFeed struct {
+	mu   sync.Mutex
+	body string
+}
+
+func (f *fakeFeed) set(body string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.body = body
+}
+
+func (f *fakeFeed) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(f.body))
+}
+
+func TestParseJSONPath(t *testing.T) {
+	doc := map[string]interface{}{
+		"games": []interface{}{
+			map[string]interface{}{"winner": "Lions", "final score": 24.0},
+		},
+	}
+
+	tests := []struct {
+		selector string
+		want     interface{}
+	}{
+		{"$.games[0].winner", "Lions"},
+		{"games.0.winner", "Lions"},
+		{"$.games[0]['final score']", 24.0},
+	}
+	for _, tt := range tests {
+		path, err := parseJSONPath(tt.selector)
+		if err != nil {
+			t.Fatalf("parseJSONPath(%q) failed: %v", tt.selector, err)
+		}
+		got, found := path.lookup(doc)
+		if !found || got != tt.want {
+			t.Errorf("lookup(%q) = %v, %v; want %v", tt.selector, got, found, tt.want)
+		}
+	}
+
+	if _, err := parseJSONPath("$.games[abc]"); err == nil {
+		t.Error("expected error for non-numeric index")
+	}
+}
+
+func TestOracle_ResolvesPoolFromFeed(t *testing.T) {
+	db, poolSvc, _, group, alice, bob := setupPoolTest(t)
+	oracleSvc := NewOracleService(db, poolSvc, nil)
+	oracleSvc.AllowHosts("127.0.0.1")
+
+	feed := &fakeFeed{body: `{"game": {"status": "in_progress", "winner": null}}`}
+	server := httptest.NewServer(feed)
+	defer server.Close()
+
+	pool, _ := poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{
+		Title:   "Lions vs Bears",
+		Options: []string{"Lions", "Bears"},
+	})
+	poolSvc.PlaceBet(pool.ID, alice.ID, PlaceBetRequest{OptionID: pool.Options[0].ID, Points: 100})
+	poolSvc.PlaceBet(pool.ID, bob.ID, PlaceBetRequest{OptionID: pool.Options[1].ID, Points: 100})
+
+	// Only the creator or an admin can bind
+	if _, err := oracleSvc.BindOracle(group.ID, pool.ID, bob.ID, false, BindOracleRequest{
+		URL: server.URL, Selector: "$.game.winner",
+	}); err == nil {
+		t.Error("expected error binding oracle as non-creator")
+	}
+
+	binding, err := oracleSvc.BindOracle(group.ID, pool.ID, alice.ID, false, BindOracleRequest{
+		URL: server.URL, Selector: "$.game.winner",
+	})
+	if err != nil {
+		t.Fatalf("BindOracle failed: %v", err)
+	}
+
+	// Winner is still null, so nothing happens
+	resolved, err := oracleSvc.Check(context.Background(), binding)
+	if err != nil || resolved {
+		t.Fatalf("expected undecided check, got resolved=%v err=%v", resolved, err)
+	}
+
+	feed.set(`{"game": {"status": "final", "winner": "lions"}}`)
+	resolved, err = oracleSvc.Check(context.Background(), binding)
+	if err != nil || !resolved {
+		t.Fatalf("expected pool to resolve, got resolved=%v err=%v", resolved, err)
+	}
+
+	var updated models.Pool
+	db.First(&updated, "id = ?", pool.ID)
+	if updated.Status != models.PoolStatusResolved {
+		t.Errorf("expected pool resolved, got %s", updated.Status)
+	}
+
+	var aliceMember models.GroupMember
+	db.Where("group_id = ? AND user_id = ?", group.ID, alice.ID).First(&aliceMember)
+	if aliceMember.PointsBalance != 1100 {
+		t.Errorf("expected Alice 1100 after winning, got %d", aliceMember.PointsBalance)
+	}
+
+	var resolution models.PointsLog
+	db.Where("group_id = ? AND type = ?", group.ID, "pool_resolved").First(&resolution)
+	if resolution.Actor != "oracle:http_json" {
+		t.Errorf("expected oracle actor on resolution log, got %q", resolution.Actor)
+	}
+
+	stored, _ := oracleSvc.GetOracle(group.ID, pool.ID)
+	if stored.Status != models.OracleStatusResolved {
+		t.Errorf("expected binding marked resolved, got %s", stored.Status)
+	}
+}
+
+func TestOracle_RejectsPoolFromAnotherGroup(t *testing.T) {
+	db, poolSvc, groupSvc, group, alice, bob := setupPoolTest(t)
+	oracleSvc := NewOracleService(db, poolSvc, nil)
+
+	pool, _ := poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{
+		Title:   "Lions vs Bears",
+		Options: []string{"Lions", "Bears"},
+	})
+	// Bob runs a group of his own, where he is admin
+	other, _ := groupSvc.CreateGroup("Bob's group", 1000, bob.ID)
+
+	req := BindOracleRequest{URL: "https://feeds.example.com/game.json", Selector: "winner"}
+	if _, err := oracleSvc.BindOracle(other.ID, pool.ID, bob.ID, true, req); err == nil {
+		t.Error("expected error binding an oracle to another group's pool")
+	}
+	if _, _, err := oracleSvc.CheckNow(context.Background(), other.ID, pool.ID, bob.ID, true); err == nil {
+		t.Error("expected error checking another group's pool")
+	}
+	if err := oracleSvc.UnbindOracle(other.ID, pool.ID, bob.ID, true); err == nil {
+		t.Error("expected error unbinding another group's pool")
+	}
+}
+
+func TestOracle_RefusesPrivateAddresses(t *testing.T) {
+	db, poolSvc, _, group, alice, _ := setupPoolTest(t)
+	oracleSvc := NewOracleService(db, poolSvc, nil)
+
+	server := httptest.NewServer(&fakeFeed{body: `{"winner": "A"}`})
+	defer server.Close()
+
+	pool, _ := poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{
+		Title:   "Internal",
+		Options: []string{"A", "B"},
+	})
+	for _, u := range []string{
+		server.URL,
+		"http://localhost:8080/admin",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://10.0.0.5/status.json",
+		"http://192.168.1.1/",
+		"http://[::1]/",
+		"http://0.0.0.0/",
+	} {
+		if _, err := oracleSvc.BindOracle(group.ID, pool.ID, alice.ID, false, BindOracleRequest{URL: u, Selector: "winner"}); err == nil {
+			t.Errorf("expected %s to be refused", u)
+		}
+	}
+
+	// A binding made while the host was allowed is still checked on connect
+	oracleSvc.AllowHosts("127.0.0.1")
+	binding, err := oracleSvc.BindOracle(group.ID, pool.ID, alice.ID, false, BindOracleRequest{URL: server.URL, Selector: "winner"})
+	if err != nil {
+		t.Fatalf("BindOracle failed: %v", err)
+	}
+	delete(oracleSvc.dialer.allowed, "127.0.0.1")
+	if resolved, err := oracleSvc.Check(context.Background(), binding); err == nil || resolved {
+		t.Errorf("expected the connection to be refused, got resolved=%v err=%v", resolved, err)
+	}
+}
+
+func TestOracle_UnknownOutcomeLeavesPoolOpen(t *testing.T) {
+	db, poolSvc, _, group, alice, _ := setupPoolTest(t)
+	oracleSvc := NewOracleService(db, poolSvc, nil)
+	oracleSvc.AllowHosts("127.0.0.1")
+
+	server := httptest.NewServer(&fakeFeed{body: `{"winner": "Tie"}`})
+	defer server.Close()
+
+	pool, _ := poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{
+		Title:   "No tie option",
+		Options: []string{"A", "B"},
+	})
+	binding, err := oracleSvc.BindOracle(group.ID, pool.ID, alice.ID, false, BindOracleRequest{
+		URL: server.URL, Selector: "winner",
+	})
+	if err != nil {
+		t.Fatalf("BindOracle failed: %v", err)
+	}
+
+	if _, err := oracleSvc.Check(context.Background(), binding); err == nil {
+		t.Error("expected error for outcome matching no option")
+	}
+
+	stored, _ := oracleSvc.GetOracle(group.ID, pool.ID)
+	if stored.LastError == "" || stored.LastValue != "Tie" {
+		t.Errorf("expected last error and value recorded, got %+v", stored)
+	}
+
+	var updated models.Pool
+	db.First(&updated, "id = ?", pool.ID)
+	if updated.Status != models.PoolStatusOpen {
+		t.Errorf("expected pool to stay open, got %s", updated.Status)
+	}
+}
@@ -1,7 +1,9 @@
 package services
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -19,27 +21,74 @@ func NewPoolService(db *gorm.DB) *PoolService {
 }
 
 type CreatePoolRequest struct {
-	Title       string   `json:"title" binding:"required"`
-	Description string   `json:"description"`
-	Options     []string `json:"options" binding:"required,min=2"`
+	Title       string     `json:"title" binding:"required"`
+	Description string     `json:"description"`
+	Options     []string   `json:"options" binding:"required,min=2"`
+	LockAt      *time.Time `json:"lock_at"`
+	// ParticipantIDs restricts the pool to these members (plus the creator).
+	// Leave empty for a pool the whole group can see.
+	ParticipantIDs []string `json:"participant_ids"`
 }
 
 func (s *PoolService) CreatePool(groupID, userID string, req CreatePoolRequest) (*models.Pool, error) {
+	tx := s.db.Begin()
+	pool, err := createPoolTx(tx, groupID, userID, models.PoolTypeStandard, req)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return pool, nil
+}
+
+// CreatePoll creates a zero-stake poll. It takes the same request as a pool;
+// members vote on the options instead of betting.
+func (s *PoolService) CreatePoll(groupID, userID string, req CreatePoolRequest) (*models.Pool, error) {
+	tx := s.db.Begin()
+	pool, err := createPoolTx(tx, groupID, userID, models.PoolTypePoll, req)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return pool, nil
+}
+
+// createPoolTx inserts a pool and its options inside an existing transaction.
+// The caller owns the transaction and is responsible for commit/rollback.
+func createPoolTx(tx *gorm.DB, groupID, userID string, poolType models.PoolType, req CreatePoolRequest) (*models.Pool, error) {
 	pool := &models.Pool{
 		ID:          uuid.New().String(),
 		GroupID:     groupID,
 		Title:       req.Title,
 		Description: req.Description,
+		Type:        poolType,
 		Status:      models.PoolStatusOpen,
 		CreatedBy:   userID,
+		Restricted:  len(req.ParticipantIDs) > 0,
+		LockAt:      req.LockAt,
 	}
 
-	tx := s.db.Begin()
 	if err := tx.Create(pool).Error; err != nil {
-		tx.Rollback()
 		return nil, fmt.Errorf("failed to create pool: %w", err)
 	}
 
+	if pool.Restricted {
+		participants, err := addParticipantsTx(tx, pool, req.ParticipantIDs)
+		if err != nil {
+			return nil, err
+		}
+		pool.Participants = participants
+	}
+
 	for _, label := range req.Options {
 		opt := &models.PoolOption{
 			ID:     uuid.New().String(),
@@ -47,21 +96,23 @@ func (s *PoolService) CreatePool(groupID, userID string, req CreatePoolRequest)
 			Label:  label,
 		}
 		if err := tx.Create(opt).Error; err != nil {
-			tx.Rollback()
 			return nil, fmt.Errorf("failed to create option: %w", err)
 		}
 		pool.Options = append(pool.Options, *opt)
 	}
 
-	if err := tx.Commit().Error; err != nil {
-		return nil, err
-	}
-
 	return pool, nil
 }
 
-func (s *PoolService) GetGroupPools(groupID string, status string) ([]models.Pool, error) {
-	query := s.db.Where("group_id = ?", groupID).Preload("Options").Preload("Creator").Order("created_at DESC")
+// GetGroupPools lists the pools in a group that userID is allowed to see.
+func (s *PoolService) GetGroupPools(groupID, userID, status string) ([]models.Pool, error) {
+	query := s.db.Where("group_id = ?", groupID).
+		Scopes(visibleTo(s.db, userID)).
+		Preload("Options").
+		Preload("Raffle").
+		Preload("Spread").
+		Preload("Creator").
+		Order("created_at DESC")
 	if status != "" {
 		query = query.Where("status = ?", status)
 	}
@@ -79,17 +130,27 @@ func (s *PoolService) GetGroupPools(groupID string, status string) ([]models.Poo
 	return pools, nil
 }
 
-func (s *PoolService) GetPool(poolID string) (*models.Pool, error) {
+// GetPool loads a pool with its options and bets. Restricted pools are
+// reported as not found unless userID is a participant; system callers
+// (oracles, webhooks) that act for the whole group pass an empty userID.
+func (s *PoolService) GetPool(poolID, userID string) (*models.Pool, error) {
 	var pool models.Pool
 	err := s.db.
 		Preload("Options").
 		Preload("Creator").
+		Preload("Participants.User").
+		Preload("Raffle").
+		Preload("Spread").
+		Preload("Seeds").
 		Preload("Bets.User").
 		Preload("Bets.Option").
 		First(&pool, "id = ?", poolID).Error
 	if err != nil {
 		return nil, err
 	}
+	if userID != "" && !canViewPool(s.db, &pool, userID) {
+		return nil, gorm.ErrRecordNotFound
+	}
 	s.populatePoolStats(&pool)
 	return &pool, nil
 }
@@ -112,66 +173,80 @@ func (s *PoolService) PlaceBet(poolID, userID string, req PlaceBetRequest) (*mod
 		tx.Rollback()
 		return nil, fmt.Errorf("pool is not open for bets")
 	}
-
-	// Verify option belongs to pool
-	var option models.PoolOption
-	if err := tx.First(&option, "id = ? AND pool_id = ?", req.OptionID, poolID).Error; err != nil {
+	if pool.LockAt != nil && !time.Now().Before(*pool.LockAt) {
 		tx.Rollback()
-		return nil, fmt.Errorf("invalid option for this pool")
+		return nil, fmt.Errorf("pool closed for bets at %s", pool.LockAt.Format(time.RFC3339))
 	}
 
-	// Check user hasn't already bet on this pool
-	var existingCount int64
-	tx.Model(&models.Bet{}).Where("pool_id = ? AND user_id = ?", poolID, userID).Count(&existingCount)
-	if existingCount > 0 {
+	if pool.Type == models.PoolTypeChallenge {
 		tx.Rollback()
-		return nil, fmt.Errorf("you already placed a bet on this pool")
+		return nil, fmt.Errorf("challenge stakes are placed by accepting the challenge")
 	}
-
-	// Deduct points from member
-	var member models.GroupMember
-	if err := tx.Where("group_id = ? AND user_id = ?", pool.GroupID, userID).First(&member).Error; err != nil {
+	if pool.Type == models.PoolTypeRaffle {
 		tx.Rollback()
-		return nil, fmt.Errorf("not a member of this group")
+		return nil, fmt.Errorf("enter a raffle by buying tickets")
+	}
+	if pool.Type == models.PoolTypePoll && req.Points != 0 {
+		tx.Rollback()
+		return nil, fmt.Errorf("polls don't take stakes, just vote")
 	}
-	if member.PointsBalance < req.Points {
+	if pool.Type != models.PoolTypePoll && req.Points <= 0 {
 		tx.Rollback()
-		return nil, fmt.Errorf("insufficient points (have %d, need %d)", member.PointsBalance, req.Points)
+		return nil, fmt.Errorf("points must be greater than zero")
 	}
 
-	member.PointsBalance -= req.Points
-	if err := tx.Save(&member).Error; err != nil {
+	bet, err := s.placeBetTx(tx, &pool, userID, req.OptionID, req.Points)
+	if err != nil {
 		tx.Rollback()
 		return nil, err
 	}
 
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return bet, nil
+}
+
+// placeBetTx escrows a member's wager on a pool option inside an existing
+// transaction. Pool status checks are the caller's job, since challenges and
+// regular bets have different rules about when a stake can be placed.
+func (s *PoolService) placeBetTx(tx *gorm.DB, pool *models.Pool, userID, optionID string, points int) (*models.Bet, error) {
+	// Non-participants get the same answer as for a pool that doesn't exist
+	if !canViewPool(tx, pool, userID) {
+		return nil, fmt.Errorf("pool not found")
+	}
+
+	// Verify option belongs to pool
+	var option models.PoolOption
+	if err := tx.First(&option, "id = ? AND pool_id = ?", optionID, pool.ID).Error; err != nil {
+		return nil, fmt.Errorf("invalid option for this pool")
+	}
+
+	if pool.Type == models.PoolTypePoll {
+		return castVoteTx(tx, pool, userID, optionID)
+	}
+
+	// Check user hasn't already bet on this pool
+	var existingCount int64
+	tx.Model(&models.Bet{}).Where("pool_id = ? AND user_id = ?", pool.ID, userID).Count(&existingCount)
+	if existingCount > 0 {
+		return nil, fmt.Errorf("you already placed a bet on this pool")
+	}
+
 	bet := &models.Bet{
 		ID:            uuid.New().String(),
-		PoolID:        poolID,
+		PoolID:        pool.ID,
 		UserID:        userID,
-		OptionID:      req.OptionID,
-		PointsWagered: req.Points,
+		OptionID:      optionID,
+		PointsWagered: points,
 	}
 	if err := tx.Create(bet).Error; err != nil {
-		tx.Rollback()
 		return nil, fmt.Errorf("failed to place bet: %w", err)
 	}
 
-	logEntry := &models.PointsLog{
-		ID:          uuid.New().String(),
-		GroupID:     pool.GroupID,
-		UserID:      userID,
-		Amount:      -req.Points,
-		Type:        models.PointsLogBetPlaced,
-		ReferenceID: bet.ID,
-		Note:        fmt.Sprintf("Bet on \"%s\" in pool \"%s\"", option.Label, pool.Title),
-	}
-	if err := tx.Create(logEntry).Error; err != nil {
-		tx.Rollback()
-		return nil, err
-	}
-
-	if err := tx.Commit().Error; err != nil {
+	if err := debitMember(tx, pool.GroupID, userID, points, PoolEscrow(pool.ID), models.PointsLogBetPlaced, bet.ID,
+		fmt.Sprintf("Bet on \"%s\" in pool \"%s\"", option.Label, pool.Title)); err != nil {
 		return nil, err
 	}
 
@@ -186,6 +261,9 @@ func (s *PoolService) LockPool(poolID, userID string, isAdmin bool) error {
 	if pool.Status != models.PoolStatusOpen {
 		return fmt.Errorf("pool is not open")
 	}
+	if pool.Type == models.PoolTypeChallenge {
+		return fmt.Errorf("challenge pools lock when the challenge is accepted")
+	}
 	if pool.CreatedBy != userID && !isAdmin {
 		return fmt.Errorf("only pool creator or group admin can lock")
 	}
@@ -194,6 +272,58 @@ func (s *PoolService) LockPool(poolID, userID string, isAdmin bool) error {
 }
 
 func (s *PoolService) ResolvePool(poolID, winningOptionID, userID string, isAdmin bool) error {
+	return s.resolvePool(poolID, winningOptionID, nil, userID, isAdmin, "")
+}
+
+// ResolvePoolWithPushes resolves a pool where some options are a push (e.g. a
+// spread landing exactly on the number): bets on those options are refunded
+// and the rest of the pool settles as usual.
+func (s *PoolService) ResolvePoolWithPushes(poolID, winningOptionID string, pushOptionIDs []string, userID string, isAdmin bool) error {
+	return s.resolvePool(poolID, winningOptionID, pushOptionIDs, userID, isAdmin, "")
+}
+
+// castVoteTx records or changes a member's vote in a poll. Votes are Bets
+// with no points, so there's no balance check and nothing goes in PointsLog.
+func castVoteTx(tx *gorm.DB, pool *models.Pool, userID, optionID string) (*models.Bet, error) {
+	var member int64
+	tx.Model(&models.GroupMember{}).Where("group_id = ? AND user_id = ?", pool.GroupID, userID).Count(&member)
+	if member == 0 {
+		return nil, fmt.Errorf("not a member of this group")
+	}
+
+	var bet models.Bet
+	err := tx.Where("pool_id = ? AND user_id = ?", pool.ID, userID).First(&bet).Error
+	if err == nil {
+		bet.OptionID = optionID
+		if err := tx.Model(&bet).Update("option_id", optionID).Error; err != nil {
+			return nil, err
+		}
+		return &bet, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	bet = models.Bet{
+		ID:       uuid.New().String(),
+		PoolID:   pool.ID,
+		UserID:   userID,
+		OptionID: optionID,
+	}
+	if err := tx.Create(&bet).Error; err != nil {
+		return nil, fmt.Errorf("failed to record vote: %w", err)
+	}
+	return &bet, nil
+}
+
+// ResolvePoolAs resolves a pool on behalf of an automated actor such as an
+// oracle or webhook. The ledger entry is attributed to the pool creator (every
+// PointsLog row needs a real user) with the actor recorded alongside it.
+func (s *PoolService) ResolvePoolAs(poolID, winningOptionID string, pushOptionIDs []string, actor string) error {
+	return s.resolvePool(poolID, winningOptionID, pushOptionIDs, "", true, actor)
+}
+
+func (s *PoolService) resolvePool(poolID, winningOptionID string, pushOptionIDs []string, userID string, isAdmin bool, actor string) error {
 	tx := s.db.Begin()
 
 	var pool models.Pool
@@ -205,25 +335,89 @@ func (s *PoolService) ResolvePool(poolID, winningOptionID, userID string, isAdmi
 		tx.Rollback()
 		return fmt.Errorf("pool cannot be resolved (status: %s)", pool.Status)
 	}
+	if pool.Type == models.PoolTypeChallenge && pool.Status == models.PoolStatusOpen {
+		tx.Rollback()
+		return fmt.Errorf("challenge hasn't been accepted yet")
+	}
+	if pool.Type == models.PoolTypeRaffle {
+		tx.Rollback()
+		return fmt.Errorf("raffles are settled by drawing a winner")
+	}
+	if pool.Type == models.PoolTypePoll {
+		tx.Rollback()
+		return fmt.Errorf("polls have no winner to resolve; lock the poll to close voting")
+	}
+	if actor != "" {
+		userID = pool.CreatedBy
+	}
 	if pool.CreatedBy != userID && !isAdmin {
 		tx.Rollback()
 		return fmt.Errorf("only pool creator or group admin can resolve")
 	}
+	if actor == "" {
+		if err := checkChallengeRefereeTx(tx, &pool, userID, isAdmin); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
 
-	// Verify winning option
-	var option models.PoolOption
-	if err := tx.First(&option, "id = ? AND pool_id = ?", winningOptionID, poolID).Error; err != nil {
+	if pool.Type == models.PoolTypeSpread {
 		tx.Rollback()
-		return fmt.Errorf("invalid winning option")
+		return fmt.Errorf("spread pools are resolved by entering the final score")
 	}
 
-	// Get all bets
-	var bets []models.Bet
-	if err := tx.Where("pool_id = ?", poolID).Find(&bets).Error; err != nil {
+	if err := settlePoolTx(tx, &pool, winningOptionID, pushOptionIDs, userID, actor); err != nil {
 		tx.Rollback()
 		return err
 	}
 
+	return tx.Commit().Error
+}
+
+// settlePoolTx pays out a pool inside an existing transaction: pushed options
+// are refunded, the winners split the remaining pot in proportion to their
+// wagers (or everyone is refunded if nobody picked the winner), seeds are
+// settled, and the resolution is recorded. Permission and status checks are
+// the caller's job.
+func settlePoolTx(tx *gorm.DB, pool *models.Pool, winningOptionID string, pushOptionIDs []string, userID, actor string) error {
+	// Verify winning option. An empty one means every option is a push.
+	var option models.PoolOption
+	if winningOptionID != "" {
+		if err := tx.First(&option, "id = ? AND pool_id = ?", winningOptionID, pool.ID).Error; err != nil {
+			return fmt.Errorf("invalid winning option")
+		}
+	}
+
+	pushed, pushLabels, err := markPushOptionsTx(tx, pool.ID, winningOptionID, pushOptionIDs)
+	if err != nil {
+		return err
+	}
+	if winningOptionID == "" {
+		var optionCount int64
+		tx.Model(&models.PoolOption{}).Where("pool_id = ?", pool.ID).Count(&optionCount)
+		if int(optionCount) != len(pushed) {
+			return fmt.Errorf("invalid winning option")
+		}
+	}
+
+	// Get all bets
+	var allBets []models.Bet
+	if err := tx.Where("pool_id = ?", pool.ID).Find(&allBets).Error; err != nil {
+		return err
+	}
+
+	// Pushed bets are refunded outright and take no part in the payout
+	bets := make([]models.Bet, 0, len(allBets))
+	for _, b := range allBets {
+		if !pushed[b.OptionID] {
+			bets = append(bets, b)
+			continue
+		}
+		if err := creditMember(tx, pool.GroupID, b.UserID, b.PointsWagered, PoolEscrow(pool.ID), models.PointsLogBetRefund, b.ID, "Push, bet refunded"); err != nil {
+			return err
+		}
+	}
+
 	totalPot := 0
 	totalWinningWagers := 0
 	for _, b := range bets {
@@ -236,8 +430,7 @@ func (s *PoolService) ResolvePool(poolID, winningOptionID, userID string, isAdmi
 	if totalWinningWagers == 0 {
 		// Nobody picked the winner, refund everyone
 		for _, b := range bets {
-			if err := s.creditMember(tx, pool.GroupID, b.UserID, b.PointsWagered, models.PointsLogBetRefund, b.ID, "No winners, bet refunded"); err != nil {
-				tx.Rollback()
+			if err := creditMember(tx, pool.GroupID, b.UserID, b.PointsWagered, PoolEscrow(pool.ID), models.PointsLogBetRefund, b.ID, "No winners, bet refunded"); err != nil {
 				return err
 			}
 		}
@@ -266,20 +459,73 @@ func (s *PoolService) ResolvePool(poolID, winningOptionID, userID string, isAdmi
 			}
 			distributed += winnings
 
-			if err := s.creditMember(tx, pool.GroupID, b.UserID, winnings, models.PointsLogBetWon, b.ID,
+			if err := creditMember(tx, pool.GroupID, b.UserID, winnings, PoolEscrow(pool.ID), models.PointsLogBetWon, b.ID,
 				fmt.Sprintf("Won %d points from pool \"%s\"", winnings, pool.Title)); err != nil {
-				tx.Rollback()
 				return err
 			}
+			// Whatever they won on top of their stake goes toward any loans first.
+			// The note leaves out the pool: the lender may not be allowed to see it.
+			if err := collectLoansTx(tx, pool.GroupID, b.UserID, winnings-b.PointsWagered, "from pool winnings"); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := settleSeedsTx(tx, pool, bets, winningOptionID); err != nil {
+		return err
+	}
+	if err := settleSideBetsTx(tx, pool, winningOptionID, pushed); err != nil {
+		return err
+	}
+
+	note := fmt.Sprintf("Resolved pool \"%s\" - winning option: \"%s\"", pool.Title, option.Label)
+	if winningOptionID == "" {
+		note = fmt.Sprintf("Resolved pool \"%s\" - push, all bets refunded", pool.Title)
+	} else if len(pushLabels) > 0 {
+		note += fmt.Sprintf(" (push: %s)", strings.Join(pushLabels, ", "))
+	}
+	if err := recordResolutionTx(tx, pool, userID, winningOptionID, note, actor); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// markPushOptionsTx validates the options being pushed and flags them so
+// clients can show which bets were refunded. It returns the pushed option IDs
+// and their quoted labels for the resolution note.
+func markPushOptionsTx(tx *gorm.DB, poolID, winningOptionID string, pushOptionIDs []string) (map[string]bool, []string, error) {
+	pushed := make(map[string]bool, len(pushOptionIDs))
+	var labels []string
+	for _, id := range pushOptionIDs {
+		if id == winningOptionID {
+			return nil, nil, fmt.Errorf("the winning option can't also be a push")
+		}
+		if pushed[id] {
+			continue
+		}
+		var opt models.PoolOption
+		if err := tx.First(&opt, "id = ? AND pool_id = ?", id, poolID).Error; err != nil {
+			return nil, nil, fmt.Errorf("invalid push option")
 		}
+		if err := tx.Model(&opt).Update("push", true).Error; err != nil {
+			return nil, nil, err
+		}
+		pushed[id] = true
+		labels = append(labels, fmt.Sprintf("%q", opt.Label))
 	}
+	return pushed, labels, nil
+}
 
+// recordResolutionTx marks a pool resolved and writes the zero-amount
+// pool_resolved ledger entry that records the winning option. The note must
+// start with `Resolved pool "<title>"`, which populatePoolStats looks up.
+func recordResolutionTx(tx *gorm.DB, pool *models.Pool, userID, winningOptionID, note, actor string) error {
 	now := time.Now()
-	if err := tx.Model(&pool).Updates(map[string]interface{}{
+	if err := tx.Model(pool).Updates(map[string]interface{}{
 		"status":      models.PoolStatusResolved,
 		"resolved_at": now,
 	}).Error; err != nil {
-		tx.Rollback()
 		return err
 	}
 
@@ -294,14 +540,10 @@ func (s *PoolService) ResolvePool(poolID, winningOptionID, userID string, isAdmi
 		Amount:      0,
 		Type:        "pool_resolved",
 		ReferenceID: winningOptionID,
-		Note:        fmt.Sprintf("Resolved pool \"%s\" - winning option: \"%s\"", pool.Title, option.Label),
-	}
-	if err := tx.Create(resolutionLog).Error; err != nil {
-		tx.Rollback()
-		return err
+		Note:        note,
+		Actor:       actor,
 	}
-
-	return tx.Commit().Error
+	return tx.Create(resolutionLog).Error
 }
 
 func (s *PoolService) CancelPool(poolID, userID string, isAdmin bool) error {
@@ -320,47 +562,61 @@ func (s *PoolService) CancelPool(poolID, userID string, isAdmin bool) error {
 		tx.Rollback()
 		return fmt.Errorf("only pool creator or group admin can cancel")
 	}
-
-	// Refund all bets
-	var bets []models.Bet
-	if err := tx.Where("pool_id = ?", poolID).Find(&bets).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
-
-	for _, b := range bets {
-		if err := s.creditMember(tx, pool.GroupID, b.UserID, b.PointsWagered, models.PointsLogBetRefund, b.ID, "Pool cancelled, bet refunded"); err != nil {
+	if pool.Status != models.PoolStatusOpen {
+		// An open challenge is still the challenger's to withdraw
+		if err := checkChallengeRefereeTx(tx, &pool, userID, isAdmin); err != nil {
 			tx.Rollback()
 			return err
 		}
 	}
 
-	if err := tx.Model(&pool).Update("status", models.PoolStatusCancelled).Error; err != nil {
+	if err := s.refundPoolTx(tx, &pool, "Pool cancelled, bet refunded"); err != nil {
 		tx.Rollback()
 		return err
 	}
+	if pool.Type == models.PoolTypeChallenge {
+		// Nobody can answer a challenge whose pool is gone
+		err := tx.Model(&models.Challenge{}).
+			Where("pool_id = ? AND status = ?", pool.ID, models.ChallengeStatusPending).
+			Updates(map[string]interface{}{
+				"status":       models.ChallengeStatusCancelled,
+				"responded_at": time.Now(),
+			}).Error
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
 
 	return tx.Commit().Error
 }
 
-func (s *PoolService) creditMember(tx *gorm.DB, groupID, userID string, amount int, logType models.PointsLogType, refID, note string) error {
-	result := tx.Model(&models.GroupMember{}).
-		Where("group_id = ? AND user_id = ?", groupID, userID).
-		Update("points_balance", gorm.Expr("points_balance + ?", amount))
-	if result.Error != nil {
-		return result.Error
+// refundPoolTx returns every wager on a pool to its bettor and marks the pool
+// cancelled, inside an existing transaction.
+func (s *PoolService) refundPoolTx(tx *gorm.DB, pool *models.Pool, note string) error {
+	if pool.Type == models.PoolTypePoll {
+		// Votes carry no points, so there's nothing to refund
+		return tx.Model(pool).Update("status", models.PoolStatusCancelled).Error
 	}
 
-	logEntry := &models.PointsLog{
-		ID:          uuid.New().String(),
-		GroupID:     groupID,
-		UserID:      userID,
-		Amount:      amount,
-		Type:        logType,
-		ReferenceID: refID,
-		Note:        note,
+	var bets []models.Bet
+	if err := tx.Where("pool_id = ?", pool.ID).Find(&bets).Error; err != nil {
+		return err
 	}
-	return tx.Create(logEntry).Error
+
+	for _, b := range bets {
+		if err := creditMember(tx, pool.GroupID, b.UserID, b.PointsWagered, PoolEscrow(pool.ID), models.PointsLogBetRefund, b.ID, note); err != nil {
+			return err
+		}
+	}
+	if err := refundSeedsTx(tx, pool, "Pool cancelled, seed refunded"); err != nil {
+		return err
+	}
+	if err := refundSideBetsTx(tx, pool, "Pool cancelled, side bet stake refunded"); err != nil {
+		return err
+	}
+
+	return tx.Model(pool).Update("status", models.PoolStatusCancelled).Error
 }
 
 func (s *PoolService) populatePoolStats(pool *models.Pool) {
@@ -371,6 +627,27 @@ func (s *PoolService) populatePoolStats(pool *models.Pool) {
 	pool.TotalPot = int(totalPot)
 	pool.BetCount = int(betCount)
 
+	var bounty int64
+	s.db.Model(&models.PoolSeed{}).Where("pool_id = ? AND status = ?", pool.ID, models.PoolSeedHeld).
+		Select("COALESCE(SUM(amount), 0)").Scan(&bounty)
+	pool.Bounty = int(bounty)
+
+	if pool.Type == models.PoolTypePoll {
+		var tallies []struct {
+			OptionID string
+			Votes    int
+		}
+		s.db.Model(&models.Bet{}).Select("option_id, COUNT(*) AS votes").
+			Where("pool_id = ?", pool.ID).Group("option_id").Scan(&tallies)
+		pool.VoteCounts = make(map[string]int, len(pool.Options))
+		for _, o := range pool.Options {
+			pool.VoteCounts[o.ID] = 0
+		}
+		for _, t := range tallies {
+			pool.VoteCounts[t.OptionID] = t.Votes
+		}
+	}
+
 	// If resolved, find winning option from the resolution log
 	if pool.Status == models.PoolStatusResolved {
 		var log models.PointsLog
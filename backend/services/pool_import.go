@@ -0,0 +1,397 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/codyseavey/bets/models"
+)
+
+// Supported fixture file formats for bulk pool import.
+const (
+	ImportFormatCSV = "csv"
+	ImportFormatICS = "ics"
+)
+
+// maxImportPools caps a single import so a malformed or hostile file can't
+// create thousands of pools in one transaction.
+const maxImportPools = 500
+
+type ImportPoolsRequest struct {
+	Format string
+	Data   []byte
+	// DefaultOptions are used for rows/events that don't specify their own
+	// options (e.g. an .ics event whose summary isn't "A vs B").
+	DefaultOptions []string
+	DryRun         bool
+}
+
+type ImportPoolsResult struct {
+	DryRun  bool          `json:"dry_run"`
+	Created int           `json:"created"`
+	Pools   []models.Pool `json:"pools"`
+}
+
+// DetectImportFormat guesses the fixture format from a filename, falling back
+// to sniffing the content for an iCalendar header.
+func DetectImportFormat(filename string, data []byte) string {
+	lower := strings.ToLower(filename)
+	switch {
+	case strings.HasSuffix(lower, ".ics"), strings.HasSuffix(lower, ".ical"):
+		return ImportFormatICS
+	case strings.HasSuffix(lower, ".csv"):
+		return ImportFormatCSV
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("BEGIN:VCALENDAR")) {
+		return ImportFormatICS
+	}
+	return ImportFormatCSV
+}
+
+// ImportPools parses a fixture file and creates one pool per row/event in a
+// single transaction. With DryRun set, the pools are built and validated but
+// nothing is written.
+func (s *PoolService) ImportPools(groupID, userID string, req ImportPoolsRequest) (*ImportPoolsResult, error) {
+	var (
+		reqs []CreatePoolRequest
+		err  error
+	)
+	switch req.Format {
+	case ImportFormatCSV:
+		reqs, err = ParseFixturesCSV(req.Data, req.DefaultOptions)
+	case ImportFormatICS:
+		reqs, err = ParseFixturesICS(req.Data, req.DefaultOptions)
+	default:
+		return nil, fmt.Errorf("unsupported import format %q", req.Format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(reqs) == 0 {
+		return nil, fmt.Errorf("no fixtures found in file")
+	}
+	if len(reqs) > maxImportPools {
+		return nil, fmt.Errorf("too many fixtures (%d, max %d)", len(reqs), maxImportPools)
+	}
+
+	result := &ImportPoolsResult{DryRun: req.DryRun}
+
+	if req.DryRun {
+		for _, r := range reqs {
+			pool := models.Pool{
+				GroupID:     groupID,
+				Title:       r.Title,
+				Description: r.Description,
+				Type:        models.PoolTypeStandard,
+				Status:      models.PoolStatusOpen,
+				CreatedBy:   userID,
+				LockAt:      r.LockAt,
+			}
+			for _, label := range r.Options {
+				pool.Options = append(pool.Options, models.PoolOption{Label: label})
+			}
+			result.Pools = append(result.Pools, pool)
+		}
+		return result, nil
+	}
+
+	tx := s.db.Begin()
+	for i, r := range reqs {
+		pool, err := createPoolTx(tx, groupID, userID, models.PoolTypeStandard, r)
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("fixture %d: %w", i+1, err)
+		}
+		result.Pools = append(result.Pools, *pool)
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	result.Created = len(result.Pools)
+	return result, nil
+}
+
+// ParseFixturesCSV reads a CSV with a header row. Recognized columns are
+// title (required), description, options and lock_at. Options are separated
+// by "|" and lock_at accepts RFC 3339 or "2006-01-02 15:04" (UTC).
+func ParseFixturesCSV(data []byte, defaultOptions []string) ([]CreatePoolRequest, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.TrimLeadingSpace = true
+	r.FieldsPerRecord = -1
+
+	header, err := r.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("invalid CSV header: %w", err)
+	}
+
+	cols := make(map[string]int, len(header))
+	for i, h := range header {
+		cols[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))] = i
+	}
+	if _, ok := cols["title"]; !ok {
+		return nil, fmt.Errorf("CSV is missing a \"title\" column")
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := cols[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var reqs []CreatePoolRequest
+	line := 1
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		title := field(record, "title")
+		if title == "" {
+			// Skip blank spacer rows, common in exported spreadsheets
+			if strings.TrimSpace(strings.Join(record, "")) == "" {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: title is required", line)
+		}
+
+		req := CreatePoolRequest{
+			Title:       title,
+			Description: field(record, "description"),
+			Options:     splitOptions(field(record, "options"), "|"),
+		}
+		if len(req.Options) == 0 {
+			req.Options = defaultOptions
+		}
+		if len(req.Options) < 2 {
+			return nil, fmt.Errorf("line %d: at least 2 options are required", line)
+		}
+
+		if raw := field(record, "lock_at"); raw != "" {
+			t, err := parseFixtureTime(raw)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid lock_at %q", line, raw)
+			}
+			req.LockAt = &t
+		}
+
+		reqs = append(reqs, req)
+	}
+
+	return reqs, nil
+}
+
+// ParseFixturesICS reads VEVENTs from an iCalendar file. SUMMARY becomes the
+// pool title, DESCRIPTION its description and DTSTART its lock_at. Options
+// come from a summary of the form "Home vs Away" (or "Away @ Home"), falling
+// back to defaultOptions.
+func ParseFixturesICS(data []byte, defaultOptions []string) ([]CreatePoolRequest, error) {
+	var (
+		reqs    []CreatePoolRequest
+		inEvent bool
+		current map[string]icsProperty
+		eventNo int
+	)
+
+	for _, line := range unfoldICSLines(data) {
+		switch {
+		case line == "BEGIN:VEVENT":
+			inEvent = true
+			eventNo++
+			current = make(map[string]icsProperty)
+			continue
+		case line == "END:VEVENT" && inEvent:
+			inEvent = false
+			req, err := icsEventToPool(current, defaultOptions)
+			if err != nil {
+				return nil, fmt.Errorf("event %d: %w", eventNo, err)
+			}
+			reqs = append(reqs, req)
+			continue
+		}
+		if !inEvent {
+			continue
+		}
+
+		prop, ok := parseICSProperty(line)
+		if !ok {
+			continue
+		}
+		// Keep the first occurrence; repeated properties aren't meaningful here
+		if _, exists := current[prop.name]; !exists {
+			current[prop.name] = prop
+		}
+	}
+
+	return reqs, nil
+}
+
+type icsProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+func icsEventToPool(props map[string]icsProperty, defaultOptions []string) (CreatePoolRequest, error) {
+	summary := unescapeICSText(props["SUMMARY"].value)
+	if summary == "" {
+		return CreatePoolRequest{}, fmt.Errorf("SUMMARY is required")
+	}
+
+	req := CreatePoolRequest{
+		Title:       summary,
+		Description: unescapeICSText(props["DESCRIPTION"].value),
+		Options:     optionsFromSummary(summary),
+	}
+	if len(req.Options) == 0 {
+		req.Options = defaultOptions
+	}
+	if len(req.Options) < 2 {
+		return CreatePoolRequest{}, fmt.Errorf("could not derive options from %q; provide default options", summary)
+	}
+
+	if start, ok := props["DTSTART"]; ok {
+		t, err := parseICSTime(start)
+		if err != nil {
+			return CreatePoolRequest{}, fmt.Errorf("invalid DTSTART %q: %w", start.value, err)
+		}
+		req.LockAt = &t
+	}
+
+	return req, nil
+}
+
+// unfoldICSLines splits iCalendar content into logical lines, joining
+// continuation lines (those starting with a space or tab) per RFC 5545.
+func unfoldICSLines(data []byte) []string {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func parseICSProperty(line string) (icsProperty, bool) {
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return icsProperty{}, false
+	}
+	head, value := line[:colon], line[colon+1:]
+
+	parts := strings.Split(head, ";")
+	prop := icsProperty{
+		name:   strings.ToUpper(parts[0]),
+		params: make(map[string]string),
+		value:  value,
+	}
+	for _, p := range parts[1:] {
+		if k, v, ok := strings.Cut(p, "="); ok {
+			prop.params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+	}
+	return prop, true
+}
+
+func parseICSTime(prop icsProperty) (time.Time, error) {
+	v := prop.value
+	if prop.params["VALUE"] == "DATE" || len(v) == 8 {
+		return time.Parse("20060102", v)
+	}
+	if strings.HasSuffix(v, "Z") {
+		return time.Parse("20060102T150405Z", v)
+	}
+	loc := time.UTC
+	if tzid := prop.params["TZID"]; tzid != "" {
+		l, err := time.LoadLocation(tzid)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unknown TZID %q", tzid)
+		}
+		loc = l
+	}
+	return time.ParseInLocation("20060102T150405", v, loc)
+}
+
+var icsTextUnescaper = strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+
+func unescapeICSText(s string) string {
+	return strings.TrimSpace(icsTextUnescaper.Replace(s))
+}
+
+// optionsFromSummary splits a matchup title like "Lions vs Bears" into its
+// two sides. Returns nil if the summary doesn't look like a matchup.
+func optionsFromSummary(summary string) []string {
+	for _, sep := range []string{" vs. ", " vs ", " v ", " @ "} {
+		start, end := indexFold(summary, sep)
+		if start < 0 {
+			continue
+		}
+		a := strings.TrimSpace(summary[:start])
+		b := strings.TrimSpace(summary[end:])
+		if a == "" || b == "" {
+			continue
+		}
+		return []string{a, b}
+	}
+	return nil
+}
+
+// indexFold is strings.Index ignoring case. It returns where the match starts
+// and ends in s, since a case-folded match needn't be the same number of bytes
+// as sep.
+func indexFold(s, sep string) (int, int) {
+	runes := utf8.RuneCountInString(sep)
+	for start := range s {
+		end := start
+		for i := 0; i < runes && end < len(s); i++ {
+			_, size := utf8.DecodeRuneInString(s[end:])
+			end += size
+		}
+		if strings.EqualFold(s[start:end], sep) {
+			return start, end
+		}
+	}
+	return -1, -1
+}
+
+func splitOptions(raw, sep string) []string {
+	var opts []string
+	for _, o := range strings.Split(raw, sep) {
+		if o = strings.TrimSpace(o); o != "" {
+			opts = append(opts, o)
+		}
+	}
+	return opts
+}
+
+func parseFixtureTime(raw string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time format")
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codyseavey/bets/models"
+)
+
+const testFixturesCSV = `title,description,options,lock_at
+Lions vs Bears,Week 1,Lions|Bears,2030-09-07T17:00:00Z
+
+Packers vs Vikings,,Packers|Vikings|Tie,2030-09-08 20:15
+`
+
+const testFixturesICS = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Arsenal vs Chelsea\r\n" +
+	"DESCRIPTION:Matchday 1\\, Emirates\r\n" +
+	"DTSTART:20300815T140000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Community Shield\r\n" +
+	"DTSTART;TZID=Europe/London:20300810T150000\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseFixturesCSV(t *testing.T) {
+	reqs, err := ParseFixturesCSV([]byte(testFixturesCSV), nil)
+	if err != nil {
+		t.Fatalf("ParseFixturesCSV failed: %v", err)
+	}
+	if len(reqs) != 2 {
+		t.Fatalf("expected 2 fixtures, got %d", len(reqs))
+	}
+
+	if reqs[0].Title != "Lions vs Bears" || reqs[0].Description != "Week 1" {
+		t.Errorf("unexpected first fixture: %+v", reqs[0])
+	}
+	if len(reqs[1].Options) != 3 || reqs[1].Options[2] != "Tie" {
+		t.Errorf("expected 3 options for second fixture, got %v", reqs[1].Options)
+	}
+	want := time.Date(2030, 9, 8, 20, 15, 0, 0, time.UTC)
+	if reqs[1].LockAt == nil || !reqs[1].LockAt.Equal(want) {
+		t.Errorf("expected lock_at %v, got %v", want, reqs[1].LockAt)
+	}
+}
+
+func TestParseFixturesCSV_MissingOptions(t *testing.T) {
+	data := []byte("title\nJust a title\n")
+
+	if _, err := ParseFixturesCSV(data, nil); err == nil {
+		t.Error("expected error when a row has no options")
+	}
+
+	reqs, err := ParseFixturesCSV(data, []string{"Yes", "No"})
+	if err != nil {
+		t.Fatalf("ParseFixturesCSV with defaults failed: %v", err)
+	}
+	if len(reqs[0].Options) != 2 {
+		t.Errorf("expected default options to be used, got %v", reqs[0].Options)
+	}
+}
+
+func TestParseFixturesICS(t *testing.T) {
+	reqs, err := ParseFixturesICS([]byte(testFixturesICS), []string{"Home", "Away"})
+	if err != nil {
+		t.Fatalf("ParseFixturesICS failed: %v", err)
+	}
+	if len(reqs) != 2 {
+		t.Fatalf("expected 2 fixtures, got %d", len(reqs))
+	}
+
+	if got := reqs[0].Options; len(got) != 2 || got[0] != "Arsenal" || got[1] != "Chelsea" {
+		t.Errorf("expected options from summary, got %v", got)
+	}
+	if reqs[0].Description != "Matchday 1, Emirates" {
+		t.Errorf("expected unescaped description, got %q", reqs[0].Description)
+	}
+	if want := time.Date(2030, 8, 15, 14, 0, 0, 0, time.UTC); !reqs[0].LockAt.Equal(want) {
+		t.Errorf("expected lock_at %v, got %v", want, reqs[0].LockAt)
+	}
+
+	// No "vs" in the summary, so the defaults apply
+	if got := reqs[1].Options; len(got) != 2 || got[0] != "Home" {
+		t.Errorf("expected default options, got %v", got)
+	}
+}
+
+func TestOptionsFromSummary(t *testing.T) {
+	cases := map[string][]string{
+		"Lions VS Bears":            {"Lions", "Bears"},
+		"İstanbul vs Ankara":        {"İstanbul", "Ankara"},
+		"ĞÜŞ Spor v Beşiktaş":       {"ĞÜŞ Spor", "Beşiktaş"},
+		"Community Shield":          nil,
+		"Lions vs. Bears @ Soldier": {"Lions", "Bears @ Soldier"},
+	}
+	for summary, want := range cases {
+		got := optionsFromSummary(summary)
+		if len(got) != len(want) || (want != nil && (got[0] != want[0] || got[1] != want[1])) {
+			t.Errorf("optionsFromSummary(%q) = %q, want %q", summary, got, want)
+		}
+	}
+}
+
+func TestParseFixturesICS_UnknownTZID(t *testing.T) {
+	ics := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"SUMMARY:Arsenal vs Chelsea\r\n" +
+		"DTSTART;TZID=Mars/Olympus_Mons:20300810T150000\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	if _, err := ParseFixturesICS([]byte(ics), nil); err == nil || !strings.Contains(err.Error(), "unknown TZID") {
+		t.Errorf("expected an unknown TZID error, got %v", err)
+	}
+}
+
+func TestImportPools_DryRunCreatesNothing(t *testing.T) {
+	db, poolSvc, _, group, alice, _ := setupPoolTest(t)
+
+	result, err := poolSvc.ImportPools(group.ID, alice.ID, ImportPoolsRequest{
+		Format: ImportFormatCSV,
+		Data:   []byte(testFixturesCSV),
+		DryRun: true,
+	})
+	if err != nil {
+		t.Fatalf("ImportPools dry run failed: %v", err)
+	}
+	if len(result.Pools) != 2 || result.Created != 0 {
+		t.Errorf("expected 2 previewed and 0 created, got %d/%d", len(result.Pools), result.Created)
+	}
+
+	var count int64
+	db.Model(&models.Pool{}).Where("group_id = ?", group.ID).Count(&count)
+	if count != 0 {
+		t.Errorf("expected no pools after dry run, got %d", count)
+	}
+}
+
+func TestImportPools_CreatesPoolsWithLockAt(t *testing.T) {
+	db, poolSvc, _, group, alice, bob := setupPoolTest(t)
+
+	result, err := poolSvc.ImportPools(group.ID, alice.ID, ImportPoolsRequest{
+		Format:         ImportFormatICS,
+		Data:           []byte(testFixturesICS),
+		DefaultOptions: []string{"Home", "Away"},
+	})
+	if err != nil {
+		t.Fatalf("ImportPools failed: %v", err)
+	}
+	if result.Created != 2 {
+		t.Fatalf("expected 2 pools created, got %d", result.Created)
+	}
+
+	var options int64
+	db.Model(&models.PoolOption{}).Where("pool_id = ?", result.Pools[0].ID).Count(&options)
+	if options != 2 {
+		t.Errorf("expected 2 options on imported pool, got %d", options)
+	}
+
+	// A pool whose lock_at has passed rejects bets even while still open
+	past := time.Now().Add(-time.Hour)
+	db.Model(&models.Pool{}).Where("id = ?", result.Pools[0].ID).Update("lock_at", past)
+	_, err = poolSvc.PlaceBet(result.Pools[0].ID, bob.ID, PlaceBetRequest{
+		OptionID: result.Pools[0].Options[0].ID,
+		Points:   100,
+	})
+	if err == nil {
+		t.Error("expected error betting after lock_at")
+	}
+}
+
+func TestImportPools_InvalidRowRollsBack(t *testing.T) {
+	db, poolSvc, _, group, alice, _ := setupPoolTest(t)
+
+	data := []byte("title,options,lock_at\nGood,A|B,\nBad,A|B,not-a-date\n")
+	if _, err := poolSvc.ImportPools(group.ID, alice.ID, ImportPoolsRequest{
+		Format: ImportFormatCSV,
+		Data:   data,
+	}); err == nil {
+		t.Fatal("expected error for invalid lock_at")
+	}
+
+	var count int64
+	db.Model(&models.Pool{}).Where("group_id = ?", group.ID).Count(&count)
+	if count != 0 {
+		t.Errorf("expected no pools after failed import, got %d", count)
+	}
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/codyseavey/bets/models"
+)
+
+// visibleTo is a query scope that limits a pools query to the pools userID
+// can see: every unrestricted pool plus restricted ones they're invited to.
+func visibleTo(db *gorm.DB, userID string) func(*gorm.DB) *gorm.DB {
+	return func(q *gorm.DB) *gorm.DB {
+		invited := db.Model(&models.PoolParticipant{}).Select("pool_id").Where("user_id = ?", userID)
+		return q.Where("pools.restricted = ? OR pools.id IN (?)", false, invited)
+	}
+}
+
+// HiddenPoolIDs returns a subquery of the restricted pools in a group that
+// userID isn't invited to, for filtering related rows (bets, ledger entries).
+func HiddenPoolIDs(db *gorm.DB, groupID, userID string) *gorm.DB {
+	invited := db.Model(&models.PoolParticipant{}).Select("pool_id").Where("user_id = ?", userID)
+	return db.Model(&models.Pool{}).Select("id").
+		Where("group_id = ? AND restricted = ? AND id NOT IN (?)", groupID, true, invited)
+}
+
+func canViewPool(db *gorm.DB, pool *models.Pool, userID string) bool {
+	if !pool.Restricted {
+		return true
+	}
+	var count int64
+	db.Model(&models.PoolParticipant{}).Where("pool_id = ? AND user_id = ?", pool.ID, userID).Count(&count)
+	return count > 0
+}
+
+// addParticipantsTx invites group members to a restricted pool. The creator
+// is always added so they can see the pool they made.
+func addParticipantsTx(tx *gorm.DB, pool *models.Pool, userIDs []string) ([]models.PoolParticipant, error) {
+	seen := map[string]bool{}
+	ids := make([]string, 0, len(userIDs)+1)
+	for _, id := range append([]string{pool.CreatedBy}, userIDs...) {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	var memberCount int64
+	tx.Model(&models.GroupMember{}).Where("group_id = ? AND user_id IN ?", pool.GroupID, ids).Count(&memberCount)
+	if int(memberCount) != len(ids) {
+		return nil, fmt.Errorf("all participants must be members of this group")
+	}
+
+	participants := make([]models.PoolParticipant, 0, len(ids))
+	for _, id := range ids {
+		p := models.PoolParticipant{PoolID: pool.ID, UserID: id}
+		if err := tx.Create(&p).Error; err != nil {
+			return nil, fmt.Errorf("failed to add participant: %w", err)
+		}
+		participants = append(participants, p)
+	}
+	return participants, nil
+}
+
+type UpdateParticipantsRequest struct {
+	// UserIDs replaces the participant list. An empty list opens the pool to
+	// the whole group.
+	UserIDs []string `json:"user_ids"`
+}
+
+// UpdateParticipants replaces a pool's participant list. Only the creator can
+// change it (an admin might be who the pool is being kept from), and members
+// who already bet can't be removed.
+func (s *PoolService) UpdateParticipants(poolID, userID string, req UpdateParticipantsRequest) (*models.Pool, error) {
+	tx := s.db.Begin()
+
+	var pool models.Pool
+	if err := tx.First(&pool, "id = ?", poolID).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("pool not found")
+	}
+	if pool.CreatedBy != userID {
+		tx.Rollback()
+		return nil, fmt.Errorf("only the pool creator can change participants")
+	}
+	if pool.Status != models.PoolStatusOpen && pool.Status != models.PoolStatusLocked {
+		tx.Rollback()
+		return nil, fmt.Errorf("pool is already %s", pool.Status)
+	}
+	if pool.Type != models.PoolTypeStandard {
+		tx.Rollback()
+		return nil, fmt.Errorf("participants can only be changed on standard pools")
+	}
+
+	restricted := len(req.UserIDs) > 0
+	if restricted {
+		keep := map[string]bool{pool.CreatedBy: true}
+		for _, id := range req.UserIDs {
+			keep[id] = true
+		}
+		var bettors []string
+		tx.Model(&models.Bet{}).Where("pool_id = ?", poolID).Pluck("user_id", &bettors)
+		for _, id := range bettors {
+			if !keep[id] {
+				tx.Rollback()
+				return nil, fmt.Errorf("members who already bet can't be removed")
+			}
+		}
+	}
+
+	if err := tx.Where("pool_id = ?", poolID).Delete(&models.PoolParticipant{}).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Model(&pool).Update("restricted", restricted).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if restricted {
+		if _, err := addParticipantsTx(tx, &pool, req.UserIDs); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetPool(poolID, userID)
+}
+
+// BroadcastPoolEvent sends a pool event to everyone who can see the pool:
+// the whole group for unrestricted pools, only participants otherwise.
+func (s *PoolService) BroadcastPoolEvent(hub *Hub, poolID string, event WSEvent) {
+	var pool models.Pool
+	if err := s.db.Select("id", "group_id", "restricted").First(&pool, "id = ?", poolID).Error; err != nil {
+		return
+	}
+
+	if !pool.Restricted {
+		hub.BroadcastToGroup(pool.GroupID, event)
+		return
+	}
+
+	var userIDs []string
+	s.db.Model(&models.PoolParticipant{}).Where("pool_id = ?", poolID).Pluck("user_id", &userIDs)
+	hub.SendToUsers(pool.GroupID, userIDs, event)
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/codyseavey/bets/models"
+)
+
+func TestRestrictedPool_Visibility(t *testing.T) {
+	db, poolSvc, groupSvc, group, alice, bob := setupPoolTest(t)
+	charlie := createTestUser(t, db, "charlie", "Charlie")
+	groupSvc.JoinGroup(group.InviteCode, charlie.ID)
+
+	// Alice and Bob plan a surprise party for Charlie
+	secret, err := poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{
+		Title:          "Will Charlie cry at the surprise party?",
+		Options:        []string{"Yes", "No"},
+		ParticipantIDs: []string{bob.ID},
+	})
+	if err != nil {
+		t.Fatalf("CreatePool failed: %v", err)
+	}
+	if !secret.Restricted || len(secret.Participants) != 2 {
+		t.Fatalf("expected restricted pool with creator + 1 participant, got %v/%d", secret.Restricted, len(secret.Participants))
+	}
+	poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{
+		Title:   "Open pool",
+		Options: []string{"A", "B"},
+	})
+
+	bobPools, _ := poolSvc.GetGroupPools(group.ID, bob.ID, "")
+	if len(bobPools) != 2 {
+		t.Errorf("expected Bob to see 2 pools, got %d", len(bobPools))
+	}
+	charliePools, _ := poolSvc.GetGroupPools(group.ID, charlie.ID, "")
+	if len(charliePools) != 1 || charliePools[0].Restricted {
+		t.Errorf("expected Charlie to see only the open pool, got %d", len(charliePools))
+	}
+
+	if _, err := poolSvc.GetPool(secret.ID, charlie.ID); err == nil {
+		t.Error("expected Charlie not to be able to load the restricted pool")
+	}
+	if _, err := poolSvc.GetPool(secret.ID, bob.ID); err != nil {
+		t.Errorf("expected Bob to load the restricted pool, got %v", err)
+	}
+
+	if _, err := poolSvc.PlaceBet(secret.ID, charlie.ID, PlaceBetRequest{OptionID: secret.Options[0].ID, Points: 50}); err == nil {
+		t.Error("expected Charlie's bet on the restricted pool to fail")
+	}
+	if _, err := poolSvc.PlaceBet(secret.ID, bob.ID, PlaceBetRequest{OptionID: secret.Options[0].ID, Points: 50}); err != nil {
+		t.Errorf("expected Bob's bet to succeed, got %v", err)
+	}
+}
+
+func TestRestrictedPool_NonMemberParticipant(t *testing.T) {
+	db, poolSvc, _, group, alice, _ := setupPoolTest(t)
+	outsider := createTestUser(t, db, "outsider", "Outsider")
+
+	_, err := poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{
+		Title:          "Members only",
+		Options:        []string{"A", "B"},
+		ParticipantIDs: []string{outsider.ID},
+	})
+	if err == nil {
+		t.Error("expected error inviting a non-member")
+	}
+
+	var count int64
+	db.Model(&models.Pool{}).Where("group_id = ?", group.ID).Count(&count)
+	if count != 0 {
+		t.Errorf("expected pool creation rolled back, got %d pools", count)
+	}
+}
+
+func TestUpdateParticipants(t *testing.T) {
+	_, poolSvc, _, group, alice, bob := setupPoolTest(t)
+
+	pool, _ := poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{
+		Title:   "Starts open",
+		Options: []string{"A", "B"},
+	})
+	poolSvc.PlaceBet(pool.ID, bob.ID, PlaceBetRequest{OptionID: pool.Options[0].ID, Points: 10})
+
+	// Bob already bet, so he can't be left out
+	if _, err := poolSvc.UpdateParticipants(pool.ID, alice.ID, UpdateParticipantsRequest{UserIDs: []string{alice.ID}}); err == nil {
+		t.Error("expected error removing a member who already bet")
+	}
+
+	// Only the creator can change the list
+	if _, err := poolSvc.UpdateParticipants(pool.ID, bob.ID, UpdateParticipantsRequest{UserIDs: []string{bob.ID}}); err == nil {
+		t.Error("expected error when non-creator updates participants")
+	}
+
+	updated, err := poolSvc.UpdateParticipants(pool.ID, alice.ID, UpdateParticipantsRequest{UserIDs: []string{bob.ID}})
+	if err != nil {
+		t.Fatalf("UpdateParticipants failed: %v", err)
+	}
+	if !updated.Restricted || len(updated.Participants) != 2 {
+		t.Errorf("expected restricted pool with 2 participants, got %v/%d", updated.Restricted, len(updated.Participants))
+	}
+
+	reopened, err := poolSvc.UpdateParticipants(pool.ID, alice.ID, UpdateParticipantsRequest{})
+	if err != nil {
+		t.Fatalf("UpdateParticipants (reopen) failed: %v", err)
+	}
+	if reopened.Restricted || len(reopened.Participants) != 0 {
+		t.Errorf("expected pool reopened to the group, got %v/%d", reopened.Restricted, len(reopened.Participants))
+	}
+}
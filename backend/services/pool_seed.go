@@ -0,0 +1,209 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/codyseavey/bets/models"
+)
+
+// TreasuryActor marks ledger entries for points minted by or lapsing back to
+// the group treasury rather than moving through a member's balance.
+const TreasuryActor = "treasury"
+
+type SeedPoolRequest struct {
+	Amount       int  `json:"amount" binding:"required,gt=0"`
+	FromTreasury bool `json:"from_treasury"`
+}
+
+// SeedPool adds bonus points to an open pool. The creator seeds from their own
+// balance; admins can seed from the group treasury instead. Treasury seeds
+// above the group's ApproveAbove threshold wait for a second admin, and come
+// back as a pending approval.
+func (s *PoolService) SeedPool(groupID, poolID, userID string, isAdmin bool, req SeedPoolRequest) (*models.PoolSeed, *models.GrantApproval, error) {
+	if req.FromTreasury && isAdmin {
+		var group models.Group
+		if err := s.db.First(&group, "id = ?", groupID).Error; err != nil {
+			return nil, nil, fmt.Errorf("group not found")
+		}
+		if needsApproval(&group, req.Amount) {
+			if _, err := seedablePoolTx(s.db, groupID, poolID, userID, isAdmin, req); err != nil {
+				return nil, nil, err
+			}
+			approval, err := createApproval(s.db, &models.GrantApproval{
+				GroupID:     groupID,
+				Kind:        models.GrantApprovalSeed,
+				RequestedBy: userID,
+				PoolID:      poolID,
+				Amount:      req.Amount,
+				Total:       req.Amount,
+			})
+			return nil, approval, err
+		}
+	}
+
+	tx := s.db.Begin()
+
+	seed, err := seedPoolTx(tx, groupID, poolID, userID, isAdmin, req)
+	if err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, nil, err
+	}
+
+	return seed, nil, nil
+}
+
+// seedablePoolTx loads a pool and checks the user may seed it as requested.
+func seedablePoolTx(tx *gorm.DB, groupID, poolID, userID string, isAdmin bool, req SeedPoolRequest) (*models.Pool, error) {
+	var pool models.Pool
+	if err := tx.First(&pool, "id = ? AND group_id = ?", poolID, groupID).Error; err != nil || !canViewPool(tx, &pool, userID) {
+		return nil, fmt.Errorf("pool not found")
+	}
+	if pool.Status != models.PoolStatusOpen {
+		return nil, fmt.Errorf("only open pools can be seeded")
+	}
+	if pool.Type != models.PoolTypeStandard && pool.Type != models.PoolTypeSpread {
+		return nil, fmt.Errorf("only standard and spread pools can be seeded")
+	}
+	if req.FromTreasury && !isAdmin {
+		return nil, fmt.Errorf("only group admins can seed from the treasury")
+	}
+	if !req.FromTreasury && pool.CreatedBy != userID {
+		return nil, fmt.Errorf("only the pool creator can seed from their own points")
+	}
+	return &pool, nil
+}
+
+func seedPoolTx(tx *gorm.DB, groupID, poolID, userID string, isAdmin bool, req SeedPoolRequest) (*models.PoolSeed, error) {
+	pool, err := seedablePoolTx(tx, groupID, poolID, userID, isAdmin, req)
+	if err != nil {
+		return nil, err
+	}
+
+	seed := &models.PoolSeed{
+		ID:           uuid.New().String(),
+		PoolID:       poolID,
+		SeededBy:     userID,
+		FromTreasury: req.FromTreasury,
+		Amount:       req.Amount,
+		Status:       models.PoolSeedHeld,
+	}
+	if err := tx.Create(seed).Error; err != nil {
+		return nil, fmt.Errorf("failed to seed pool: %w", err)
+	}
+
+	if req.FromTreasury {
+		err = treasuryLogTx(tx, pool.GroupID, userID, req.Amount, PoolEscrow(pool.ID), models.PointsLogPoolSeeded, seed.ID,
+			fmt.Sprintf("Seeded pool \"%s\" with %d points from the group treasury", pool.Title, req.Amount))
+	} else {
+		err = debitMember(tx, pool.GroupID, userID, req.Amount, PoolEscrow(pool.ID), models.PointsLogPoolSeeded, seed.ID,
+			fmt.Sprintf("Seeded pool \"%s\" with %d points", pool.Title, req.Amount))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return seed, nil
+}
+
+// treasuryLogTx moves points between the treasury and another account
+// (negative amounts flow back into the treasury). No member balance changes,
+// so the PointsLog entry is zero-amount, attributed to the member who
+// triggered it.
+func treasuryLogTx(tx *gorm.DB, groupID, userID string, amount int, to string, logType models.PointsLogType, refID, note string) error {
+	logEntry := &models.PointsLog{
+		ID:          uuid.New().String(),
+		GroupID:     groupID,
+		UserID:      userID,
+		Amount:      0,
+		Type:        logType,
+		ReferenceID: refID,
+		Note:        note,
+		Actor:       TreasuryActor,
+	}
+	if err := tx.Create(logEntry).Error; err != nil {
+		return err
+	}
+	return transferTx(tx, groupID, TreasuryAccount, to, amount, logEntry.ID)
+}
+
+// settleSeedsTx pays a resolved pool's seeds out to the winning bets in
+// proportion to their wagers, with the remainder going to the last winner as
+// with the main pot. With no winning bets the seeds are refunded.
+func settleSeedsTx(tx *gorm.DB, pool *models.Pool, bets []models.Bet, winningOptionID string) error {
+	var seeds []models.PoolSeed
+	if err := tx.Where("pool_id = ? AND status = ?", pool.ID, models.PoolSeedHeld).Find(&seeds).Error; err != nil {
+		return err
+	}
+	if len(seeds) == 0 {
+		return nil
+	}
+
+	var winners []models.Bet
+	totalWinningWagers := 0
+	for _, b := range bets {
+		if b.OptionID == winningOptionID {
+			winners = append(winners, b)
+			totalWinningWagers += b.PointsWagered
+		}
+	}
+	if len(winners) == 0 {
+		return refundSeedsTx(tx, pool, "Nobody won, seed refunded")
+	}
+
+	bounty := 0
+	for _, sd := range seeds {
+		bounty += sd.Amount
+	}
+
+	distributed := 0
+	for i, b := range winners {
+		share := (b.PointsWagered * bounty) / totalWinningWagers
+		if i == len(winners)-1 {
+			share = bounty - distributed
+		}
+		distributed += share
+		if share == 0 {
+			continue
+		}
+		if err := creditMember(tx, pool.GroupID, b.UserID, share, PoolEscrow(pool.ID), models.PointsLogBountyWon, b.ID,
+			fmt.Sprintf("Won %d bonus points from pool \"%s\"", share, pool.Title)); err != nil {
+			return err
+		}
+	}
+
+	return tx.Model(&models.PoolSeed{}).
+		Where("pool_id = ? AND status = ?", pool.ID, models.PoolSeedHeld).
+		Update("status", models.PoolSeedPaid).Error
+}
+
+// refundSeedsTx returns held seeds to whoever put them up. Treasury seeds just
+// go back to the treasury account.
+func refundSeedsTx(tx *gorm.DB, pool *models.Pool, note string) error {
+	var seeds []models.PoolSeed
+	if err := tx.Where("pool_id = ? AND status = ?", pool.ID, models.PoolSeedHeld).Find(&seeds).Error; err != nil {
+		return err
+	}
+
+	for _, sd := range seeds {
+		var err error
+		if sd.FromTreasury {
+			err = treasuryLogTx(tx, pool.GroupID, sd.SeededBy, -sd.Amount, PoolEscrow(pool.ID), models.PointsLogSeedRefund, sd.ID,
+				fmt.Sprintf("%s; %d points returned to the group treasury", note, sd.Amount))
+		} else {
+			err = creditMember(tx, pool.GroupID, sd.SeededBy, sd.Amount, PoolEscrow(pool.ID), models.PointsLogSeedRefund, sd.ID, note)
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&sd).Update("status", models.PoolSeedRefunded).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/codyseavey/bets/models"
+)
+
+func memberBalance(t *testing.T, svc *PoolService, groupID, userID string) int {
+	t.Helper()
+	var m models.GroupMember
+	if err := svc.db.Where("group_id = ? AND user_id = ?", groupID, userID).First(&m).Error; err != nil {
+		t.Fatalf("member not found: %v", err)
+	}
+	return m.PointsBalance
+}
+
+func TestSeededPoolPaysWinners(t *testing.T) {
+	db, poolSvc, groupSvc, group, alice, bob := setupPoolTest(t)
+	carol := createTestUser(t, db, "carol", "Carol")
+	groupSvc.JoinGroup(group.InviteCode, carol.ID)
+
+	pool, _ := poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{
+		Title:   "Long shot",
+		Options: []string{"Upset", "Favorite"},
+	})
+
+	if _, _, err := poolSvc.SeedPool(group.ID, pool.ID, bob.ID, false, SeedPoolRequest{Amount: 50}); err == nil {
+		t.Error("expected error seeding someone else's pool from your own points")
+	}
+	if _, _, err := poolSvc.SeedPool(group.ID, pool.ID, bob.ID, false, SeedPoolRequest{Amount: 50, FromTreasury: true}); err == nil {
+		t.Error("expected error seeding from the treasury as a non-admin")
+	}
+	// Bob is admin of his own group, which gives him no say over this pool
+	other, _ := groupSvc.CreateGroup("Bob's group", 1000, bob.ID)
+	if _, _, err := poolSvc.SeedPool(other.ID, pool.ID, bob.ID, true, SeedPoolRequest{Amount: 50, FromTreasury: true}); err == nil {
+		t.Error("expected error seeding another group's pool from the treasury")
+	}
+	if _, _, err := poolSvc.SeedPool(group.ID, pool.ID, alice.ID, false, SeedPoolRequest{Amount: 100}); err != nil {
+		t.Fatalf("creator SeedPool failed: %v", err)
+	}
+	if _, _, err := poolSvc.SeedPool(group.ID, pool.ID, alice.ID, true, SeedPoolRequest{Amount: 50, FromTreasury: true}); err != nil {
+		t.Fatalf("treasury SeedPool failed: %v", err)
+	}
+
+	seeded, _ := poolSvc.GetPool(pool.ID, alice.ID)
+	if seeded.Bounty != 150 {
+		t.Errorf("expected bounty 150, got %d", seeded.Bounty)
+	}
+	if got := memberBalance(t, poolSvc, group.ID, alice.ID); got != 900 {
+		t.Errorf("expected creator's seed debited (900), got %d", got)
+	}
+
+	poolSvc.PlaceBet(pool.ID, bob.ID, PlaceBetRequest{OptionID: pool.Options[0].ID, Points: 100})
+	poolSvc.PlaceBet(pool.ID, carol.ID, PlaceBetRequest{OptionID: pool.Options[0].ID, Points: 200})
+	poolSvc.PlaceBet(pool.ID, alice.ID, PlaceBetRequest{OptionID: pool.Options[1].ID, Points: 300})
+
+	if err := poolSvc.ResolvePool(pool.ID, pool.Options[0].ID, alice.ID, true); err != nil {
+		t.Fatalf("ResolvePool failed: %v", err)
+	}
+
+	// Pot of 600 and bounty of 150 split 1:2 between Bob and Carol
+	if got := memberBalance(t, poolSvc, group.ID, bob.ID); got != 900+200+50 {
+		t.Errorf("expected Bob at 1150, got %d", got)
+	}
+	if got := memberBalance(t, poolSvc, group.ID, carol.ID); got != 800+400+100 {
+		t.Errorf("expected Carol at 1300, got %d", got)
+	}
+
+	var bountyLogs int64
+	db.Model(&models.PointsLog{}).Where("group_id = ? AND type = ?", group.ID, models.PointsLogBountyWon).Count(&bountyLogs)
+	if bountyLogs != 2 {
+		t.Errorf("expected 2 bounty_won entries, got %d", bountyLogs)
+	}
+}
+
+func TestSeedRefundedWhenNobodyWins(t *testing.T) {
+	db, poolSvc, _, group, alice, bob := setupPoolTest(t)
+
+	pool, _ := poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{
+		Title:   "Nobody picks C",
+		Options: []string{"A", "B", "C"},
+	})
+	poolSvc.SeedPool(group.ID, pool.ID, alice.ID, false, SeedPoolRequest{Amount: 100})
+	poolSvc.SeedPool(group.ID, pool.ID, alice.ID, true, SeedPoolRequest{Amount: 40, FromTreasury: true})
+	poolSvc.PlaceBet(pool.ID, bob.ID, PlaceBetRequest{OptionID: pool.Options[0].ID, Points: 100})
+
+	poolSvc.ResolvePool(pool.ID, pool.Options[2].ID, alice.ID, true)
+
+	if got := memberBalance(t, poolSvc, group.ID, alice.ID); got != 1000 {
+		t.Errorf("expected creator's seed refunded to 1000, got %d", got)
+	}
+	if got := memberBalance(t, poolSvc, group.ID, bob.ID); got != 1000 {
+		t.Errorf("expected Bob's bet refunded, got %d", got)
+	}
+
+	var refunds []models.PointsLog
+	db.Where("group_id = ? AND type = ?", group.ID, models.PointsLogSeedRefund).Find(&refunds)
+	if len(refunds) != 2 {
+		t.Fatalf("expected 2 seed_refund entries, got %d", len(refunds))
+	}
+	for _, r := range refunds {
+		if r.Actor == TreasuryActor && r.Amount != 0 {
+			t.Errorf("expected treasury refund to lapse with no balance change, got %d", r.Amount)
+		}
+	}
+
+	var held int64
+	db.Model(&models.PoolSeed{}).Where("pool_id = ? AND status = ?", pool.ID, models.PoolSeedHeld).Count(&held)
+	if held != 0 {
+		t.Errorf("expected no held seeds left, got %d", held)
+	}
+}
+
+func TestCancelRefundsSeeds(t *testing.T) {
+	_, poolSvc, _, group, alice, _ := setupPoolTest(t)
+
+	pool, _ := poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{Title: "Called off", Options: []string{"A", "B"}})
+	poolSvc.SeedPool(group.ID, pool.ID, alice.ID, false, SeedPoolRequest{Amount: 75})
+
+	if err := poolSvc.CancelPool(pool.ID, alice.ID, false); err != nil {
+		t.Fatalf("CancelPool failed: %v", err)
+	}
+	if got := memberBalance(t, poolSvc, group.ID, alice.ID); got != 1000 {
+		t.Errorf("expected seed refunded on cancel, got %d", got)
+	}
+}
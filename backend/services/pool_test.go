@@ -331,3 +331,99 @@ func TestBetOnClosedPool(t *testing.T) {
 		t.Error("expected error betting on resolved pool")
 	}
 }
+
+func TestPollVotesSkipLedger(t *testing.T) {
+	db, poolSvc, _, group, alice, bob := setupPoolTest(t)
+
+	poll, err := poolSvc.CreatePoll(group.ID, alice.ID, CreatePoolRequest{
+		Title:   "Which game next?",
+		Options: []string{"Chess", "Poker"},
+	})
+	if err != nil {
+		t.Fatalf("CreatePoll failed: %v", err)
+	}
+	if poll.Type != models.PoolTypePoll {
+		t.Errorf("expected poll type, got %s", poll.Type)
+	}
+
+	if _, err := poolSvc.PlaceBet(poll.ID, alice.ID, PlaceBetRequest{OptionID: poll.Options[0].ID, Points: 50}); err == nil {
+		t.Error("expected error staking points on a poll")
+	}
+
+	var logsBefore int64
+	db.Model(&models.PointsLog{}).Where("group_id = ?", group.ID).Count(&logsBefore)
+
+	poolSvc.PlaceBet(poll.ID, alice.ID, PlaceBetRequest{OptionID: poll.Options[0].ID})
+	poolSvc.PlaceBet(poll.ID, bob.ID, PlaceBetRequest{OptionID: poll.Options[0].ID})
+	// Changing your mind moves the vote rather than failing
+	if _, err := poolSvc.PlaceBet(poll.ID, bob.ID, PlaceBetRequest{OptionID: poll.Options[1].ID}); err != nil {
+		t.Fatalf("changing vote failed: %v", err)
+	}
+
+	var logsAfter int64
+	db.Model(&models.PointsLog{}).Where("group_id = ?", group.ID).Count(&logsAfter)
+	if logsAfter != logsBefore {
+		t.Errorf("expected no ledger entries for votes, got %d new", logsAfter-logsBefore)
+	}
+	var bobMember models.GroupMember
+	db.Where("group_id = ? AND user_id = ?", group.ID, bob.ID).First(&bobMember)
+	if bobMember.PointsBalance != 1000 {
+		t.Errorf("expected balance untouched, got %d", bobMember.PointsBalance)
+	}
+
+	result, _ := poolSvc.GetPool(poll.ID, alice.ID)
+	if result.VoteCounts[poll.Options[0].ID] != 1 || result.VoteCounts[poll.Options[1].ID] != 1 {
+		t.Errorf("expected 1-1 tally, got %v", result.VoteCounts)
+	}
+
+	if err := poolSvc.ResolvePool(poll.ID, poll.Options[0].ID, alice.ID, true); err == nil {
+		t.Error("expected error resolving a poll")
+	}
+	if err := poolSvc.LockPool(poll.ID, alice.ID, false); err != nil {
+		t.Fatalf("LockPool failed: %v", err)
+	}
+	if _, err := poolSvc.PlaceBet(poll.ID, alice.ID, PlaceBetRequest{OptionID: poll.Options[1].ID}); err == nil {
+		t.Error("expected error voting in a closed poll")
+	}
+}
+
+func TestResolveWithPushRefundsPushedOptions(t *testing.T) {
+	db, poolSvc, groupSvc, group, alice, bob := setupPoolTest(t)
+	carol := createTestUser(t, db, "carol", "Carol")
+	groupSvc.JoinGroup(group.InviteCode, carol.ID)
+
+	pool, _ := poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{
+		Title:   "Margin of victory",
+		Options: []string{"Win by 4+", "Win by exactly 3", "Lose"},
+	})
+	poolSvc.PlaceBet(pool.ID, alice.ID, PlaceBetRequest{OptionID: pool.Options[0].ID, Points: 100})
+	poolSvc.PlaceBet(pool.ID, bob.ID, PlaceBetRequest{OptionID: pool.Options[1].ID, Points: 100})
+	poolSvc.PlaceBet(pool.ID, carol.ID, PlaceBetRequest{OptionID: pool.Options[2].ID, Points: 100})
+
+	if err := poolSvc.ResolvePoolWithPushes(pool.ID, pool.Options[0].ID, []string{pool.Options[0].ID}, alice.ID, true); err == nil {
+		t.Error("expected error pushing the winning option")
+	}
+
+	if err := poolSvc.ResolvePoolWithPushes(pool.ID, pool.Options[0].ID, []string{pool.Options[1].ID}, alice.ID, true); err != nil {
+		t.Fatalf("ResolvePoolWithPushes failed: %v", err)
+	}
+
+	balances := map[string]int{alice.ID: 1100, bob.ID: 1000, carol.ID: 900}
+	for userID, want := range balances {
+		var m models.GroupMember
+		db.Where("group_id = ? AND user_id = ?", group.ID, userID).First(&m)
+		if m.PointsBalance != want {
+			t.Errorf("expected %s at %d, got %d", userID, want, m.PointsBalance)
+		}
+	}
+
+	resolved, _ := poolSvc.GetPool(pool.ID, alice.ID)
+	if resolved.WinningOptionID != pool.Options[0].ID {
+		t.Errorf("expected winning option still found, got %q", resolved.WinningOptionID)
+	}
+	for _, o := range resolved.Options {
+		if o.Push != (o.ID == pool.Options[1].ID) {
+			t.Errorf("option %q push = %v", o.Label, o.Push)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/codyseavey/bets/models"
+)
+
+// raffleOptionLabel is the single option every raffle pool has; each
+// member's tickets are held as one bet on it.
+const raffleOptionLabel = "Tickets"
+
+const maxTicketsPerPurchase = 1000
+
+type RaffleService struct {
+	db    *gorm.DB
+	pools *PoolService
+}
+
+func NewRaffleService(db *gorm.DB, pools *PoolService) *RaffleService {
+	return &RaffleService{db: db, pools: pools}
+}
+
+// RaffleSeedHash is the commitment published when a raffle is created: the
+// hex SHA-256 of the hex-encoded seed.
+func RaffleSeedHash(seed string) string {
+	sum := sha256.Sum256([]byte(seed))
+	return hex.EncodeToString(sum[:])
+}
+
+// RaffleWinningTicket derives the winning ticket (1-based) from the revealed
+// seed: SHA-256 of "<seed>:<pool id>" read as a big-endian integer, modulo the
+// number of tickets, plus one. Tickets are numbered by member ID order, so
+// the result can be reproduced from the pool's public bets.
+func RaffleWinningTicket(seed, poolID string, totalTickets int) int {
+	sum := sha256.Sum256([]byte(seed + ":" + poolID))
+	n := new(big.Int).SetBytes(sum[:])
+	n.Mod(n, big.NewInt(int64(totalTickets)))
+	return int(n.Int64()) + 1
+}
+
+type CreateRaffleRequest struct {
+	Title          string     `json:"title" binding:"required"`
+	Description    string     `json:"description"`
+	TicketPrice    int        `json:"ticket_price" binding:"required,gt=0"`
+	LockAt         *time.Time `json:"lock_at"`
+	ParticipantIDs []string   `json:"participant_ids"`
+}
+
+// CreateRaffle opens a raffle pool and commits to a freshly generated seed.
+func (s *RaffleService) CreateRaffle(groupID, userID string, req CreateRaffleRequest) (*models.Pool, error) {
+	seedBytes := make([]byte, 32)
+	if _, err := rand.Read(seedBytes); err != nil {
+		return nil, fmt.Errorf("failed to generate raffle seed: %w", err)
+	}
+	seed := hex.EncodeToString(seedBytes)
+
+	tx := s.db.Begin()
+
+	pool, err := createPoolTx(tx, groupID, userID, models.PoolTypeRaffle, CreatePoolRequest{
+		Title:          req.Title,
+		Description:    req.Description,
+		Options:        []string{raffleOptionLabel},
+		LockAt:         req.LockAt,
+		ParticipantIDs: req.ParticipantIDs,
+	})
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	raffle := &models.Raffle{
+		PoolID:      pool.ID,
+		TicketPrice: req.TicketPrice,
+		SeedHash:    RaffleSeedHash(seed),
+		Seed:        seed,
+	}
+	if err := tx.Create(raffle).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to create raffle: %w", err)
+	}
+	pool.Raffle = raffle
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return pool, nil
+}
+
+type BuyTicketsRequest struct {
+	Count int `json:"count" binding:"required,gt=0"`
+}
+
+// BuyTickets adds tickets to a member's stake in a raffle. The first purchase
+// places their bet; later ones top it up.
+func (s *RaffleService) BuyTickets(poolID, userID string, req BuyTicketsRequest) (*models.Bet, error) {
+	if req.Count > maxTicketsPerPurchase {
+		return nil, fmt.Errorf("can't buy more than %d tickets at once", maxTicketsPerPurchase)
+	}
+
+	tx := s.db.Begin()
+
+	pool, raffle, err := s.raffleTx(tx, poolID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if pool.Status != models.PoolStatusOpen {
+		tx.Rollback()
+		return nil, fmt.Errorf("raffle is not selling tickets")
+	}
+	if pool.LockAt != nil && !time.Now().Before(*pool.LockAt) {
+		tx.Rollback()
+		return nil, fmt.Errorf("raffle closed at %s", pool.LockAt.Format(time.RFC3339))
+	}
+
+	cost := req.Count * raffle.TicketPrice
+
+	var bet models.Bet
+	err = tx.Where("pool_id = ? AND user_id = ?", poolID, userID).First(&bet).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		var option models.PoolOption
+		if err := tx.First(&option, "pool_id = ?", poolID).Error; err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("raffle has no ticket option")
+		}
+		placed, err := s.pools.placeBetTx(tx, pool, userID, option.ID, cost)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		bet = *placed
+	case err == nil:
+		if err := debitMember(tx, pool.GroupID, userID, cost, PoolEscrow(pool.ID), models.PointsLogRaffleTopUp, bet.ID,
+			fmt.Sprintf("Bought %d more ticket(s) in raffle \"%s\"", req.Count, pool.Title)); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		bet.PointsWagered += cost
+		if err := tx.Model(&bet).Update("points_wagered", bet.PointsWagered).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	default:
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return &bet, nil
+}
+
+type RaffleEntry struct {
+	UserID      string `json:"user_id"`
+	Name        string `json:"name"`
+	Tickets     int    `json:"tickets"`
+	FirstTicket int    `json:"first_ticket"`
+	LastTicket  int    `json:"last_ticket"`
+}
+
+type RaffleView struct {
+	*models.Raffle
+	Entries []RaffleEntry `json:"entries"`
+}
+
+// GetRaffle returns the raffle with each member's ticket range, which is all
+// anyone needs (with the revealed seed) to recompute the draw.
+func (s *RaffleService) GetRaffle(poolID string) (*RaffleView, error) {
+	var raffle models.Raffle
+	if err := s.db.First(&raffle, "pool_id = ?", poolID).Error; err != nil {
+		return nil, err
+	}
+	entries, _, err := raffleEntries(s.db, poolID, raffle.TicketPrice)
+	if err != nil {
+		return nil, err
+	}
+	return &RaffleView{Raffle: &raffle, Entries: entries}, nil
+}
+
+// raffleEntries numbers tickets consecutively from 1, walking members in
+// user ID order.
+func raffleEntries(db *gorm.DB, poolID string, ticketPrice int) ([]RaffleEntry, []models.Bet, error) {
+	var bets []models.Bet
+	if err := db.Preload("User").Where("pool_id = ?", poolID).Order("user_id ASC").Find(&bets).Error; err != nil {
+		return nil, nil, err
+	}
+
+	entries := make([]RaffleEntry, 0, len(bets))
+	next := 1
+	for _, b := range bets {
+		tickets := b.PointsWagered / ticketPrice
+		entries = append(entries, RaffleEntry{
+			UserID:      b.UserID,
+			Name:        b.User.Name,
+			Tickets:     tickets,
+			FirstTicket: next,
+			LastTicket:  next + tickets - 1,
+		})
+		next += tickets
+	}
+	return entries, bets, nil
+}
+
+// DrawWinner reveals the seed, picks the winning ticket and pays the whole pot
+// to its holder, recording the resolution the same way ResolvePool does.
+func (s *RaffleService) DrawWinner(poolID, userID string, isAdmin bool) (*RaffleView, error) {
+	tx := s.db.Begin()
+
+	pool, raffle, err := s.raffleTx(tx, poolID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if pool.Status != models.PoolStatusOpen && pool.Status != models.PoolStatusLocked {
+		tx.Rollback()
+		return nil, fmt.Errorf("raffle cannot be drawn (status: %s)", pool.Status)
+	}
+	if pool.CreatedBy != userID && !isAdmin {
+		tx.Rollback()
+		return nil, fmt.Errorf("only the raffle creator or group admin can draw")
+	}
+
+	entries, bets, err := raffleEntries(tx, poolID, raffle.TicketPrice)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	total := 0
+	pot := 0
+	for i, e := range entries {
+		total += e.Tickets
+		pot += bets[i].PointsWagered
+	}
+	if total == 0 {
+		tx.Rollback()
+		return nil, fmt.Errorf("no tickets have been sold")
+	}
+
+	ticket := RaffleWinningTicket(raffle.Seed, poolID, total)
+	var winner RaffleEntry
+	var winningBet models.Bet
+	for i, e := range entries {
+		if ticket >= e.FirstTicket && ticket <= e.LastTicket {
+			winner, winningBet = e, bets[i]
+			break
+		}
+	}
+
+	if err := creditMember(tx, pool.GroupID, winningBet.UserID, pot, PoolEscrow(pool.ID), models.PointsLogBetWon, winningBet.ID,
+		fmt.Sprintf("Won %d points from pool \"%s\"", pot, pool.Title)); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	note := fmt.Sprintf("Resolved pool \"%s\" - raffle ticket #%d of %d won by %s", pool.Title, ticket, total, winner.Name)
+	if err := recordResolutionTx(tx, pool, userID, winningBet.OptionID, note, ""); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := tx.Model(raffle).Updates(map[string]interface{}{
+		"revealed_seed":  raffle.Seed,
+		"total_tickets":  total,
+		"winning_ticket": ticket,
+		"winner_id":      winningBet.UserID,
+		"drawn_at":       now,
+	}).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetRaffle(poolID)
+}
+
+func (s *RaffleService) raffleTx(tx *gorm.DB, poolID string) (*models.Pool, *models.Raffle, error) {
+	var pool models.Pool
+	if err := tx.First(&pool, "id = ?", poolID).Error; err != nil {
+		return nil, nil, fmt.Errorf("pool not found")
+	}
+	if pool.Type != models.PoolTypeRaffle {
+		return nil, nil, fmt.Errorf("pool is not a raffle")
+	}
+	var raffle models.Raffle
+	if err := tx.First(&raffle, "pool_id = ?", poolID).Error; err != nil {
+		return nil, nil, fmt.Errorf("raffle not found")
+	}
+	return &pool, &raffle, nil
+}
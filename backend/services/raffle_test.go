@@ -0,0 +1,104 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/codyseavey/bets/models"
+)
+
+func TestRaffle_CommitRevealDraw(t *testing.T) {
+	db, poolSvc, _, group, alice, bob := setupPoolTest(t)
+	svc := NewRaffleService(db, poolSvc)
+
+	pool, err := svc.CreateRaffle(group.ID, alice.ID, CreateRaffleRequest{Title: "Office raffle", TicketPrice: 10})
+	if err != nil {
+		t.Fatalf("CreateRaffle failed: %v", err)
+	}
+	if pool.Type != models.PoolTypeRaffle || len(pool.Raffle.SeedHash) != 64 {
+		t.Fatalf("expected raffle pool with a published seed hash, got %+v", pool.Raffle)
+	}
+	commitment := pool.Raffle.SeedHash
+
+	// The seed itself must not leak before the draw
+	body, _ := json.Marshal(pool)
+	if strings.Contains(string(body), pool.Raffle.Seed) {
+		t.Error("raffle seed exposed in pool JSON before draw")
+	}
+
+	if _, err := poolSvc.PlaceBet(pool.ID, bob.ID, PlaceBetRequest{OptionID: pool.Options[0].ID, Points: 10}); err == nil {
+		t.Error("expected error placing a regular bet on a raffle")
+	}
+	if _, err := svc.DrawWinner(pool.ID, alice.ID, false); err == nil {
+		t.Error("expected error drawing with no tickets sold")
+	}
+
+	if _, err := svc.BuyTickets(pool.ID, alice.ID, BuyTicketsRequest{Count: 3}); err != nil {
+		t.Fatalf("alice BuyTickets failed: %v", err)
+	}
+	svc.BuyTickets(pool.ID, bob.ID, BuyTicketsRequest{Count: 1})
+	bet, err := svc.BuyTickets(pool.ID, bob.ID, BuyTicketsRequest{Count: 2})
+	if err != nil {
+		t.Fatalf("bob top-up failed: %v", err)
+	}
+	if bet.PointsWagered != 30 {
+		t.Errorf("expected bob's stake topped up to 30, got %d", bet.PointsWagered)
+	}
+
+	if _, err := svc.DrawWinner(pool.ID, bob.ID, false); err == nil {
+		t.Error("expected error drawing as non-creator")
+	}
+	if err := poolSvc.ResolvePool(pool.ID, pool.Options[0].ID, alice.ID, true); err == nil {
+		t.Error("expected error resolving a raffle directly")
+	}
+
+	result, err := svc.DrawWinner(pool.ID, alice.ID, false)
+	if err != nil {
+		t.Fatalf("DrawWinner failed: %v", err)
+	}
+
+	// Anyone can verify: the revealed seed matches the commitment and
+	// reproduces the winning ticket
+	if RaffleSeedHash(result.RevealedSeed) != commitment {
+		t.Error("revealed seed doesn't match the published hash")
+	}
+	if RaffleWinningTicket(result.RevealedSeed, pool.ID, 6) != result.WinningTicket || result.TotalTickets != 6 {
+		t.Errorf("winning ticket %d of %d doesn't reproduce", result.WinningTicket, result.TotalTickets)
+	}
+	var winnerID string
+	for _, e := range result.Entries {
+		if result.WinningTicket >= e.FirstTicket && result.WinningTicket <= e.LastTicket {
+			winnerID = e.UserID
+		}
+	}
+	if result.WinnerID == nil || *result.WinnerID != winnerID {
+		t.Fatalf("winner doesn't hold the winning ticket")
+	}
+
+	var winner models.GroupMember
+	db.Where("group_id = ? AND user_id = ?", group.ID, winnerID).First(&winner)
+	if winner.PointsBalance != 1000-30+60 {
+		t.Errorf("expected winner to collect the 60 point pot, got balance %d", winner.PointsBalance)
+	}
+
+	resolved, _ := poolSvc.GetPool(pool.ID, "")
+	if resolved.Status != models.PoolStatusResolved || resolved.WinningOptionID != pool.Options[0].ID {
+		t.Errorf("expected pool resolved through the ledger, got %s / %q", resolved.Status, resolved.WinningOptionID)
+	}
+	if _, err := svc.DrawWinner(pool.ID, alice.ID, false); err == nil {
+		t.Error("expected error drawing twice")
+	}
+
+	// Bob's top-up is still one bet on his record
+	rec := MemberRecordSince(db, group.ID, bob.ID, group.CreatedAt)
+	want := MemberRecord{Bets: 1, Losses: 1}
+	if winnerID == bob.ID {
+		want = MemberRecord{Bets: 1, Wins: 1}
+	}
+	if rec != want {
+		t.Errorf("expected Bob's record %+v, got %+v", want, rec)
+	}
+
+	assertReconciled(t, db, group.ID)
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"log"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/codyseavey/bets/models"
+)
+
+// AccountDrift is a ledger account whose balance disagrees with what the rest
+// of the database says it should hold.
+type AccountDrift struct {
+	Account  string `json:"account"`
+	Expected int    `json:"expected"` // cached balance for members, outstanding stakes for escrows
+	Ledger   int    `json:"ledger"`   // balance recomputed from ledger entries
+	Reason   string `json:"reason"`
+}
+
+type ReconcileReport struct {
+	GroupID        string         `json:"group_id"`
+	Treasury       int            `json:"treasury"` // negative of the points issued to members and escrow
+	Members        int            `json:"members"`  // sum of member accounts
+	Escrow         int            `json:"escrow"`   // sum of escrow accounts
+	Drift          []AccountDrift `json:"drift"`
+	UnbalancedTxns []string       `json:"unbalanced_txns"`
+}
+
+func (r *ReconcileReport) OK() bool {
+	return len(r.Drift) == 0 && len(r.UnbalancedTxns) == 0
+}
+
+// LedgerBalances recomputes every account in a group from its ledger entries.
+func LedgerBalances(db *gorm.DB, groupID string) (map[string]int, error) {
+	var rows []struct {
+		Account string
+		Balance int
+	}
+	if err := db.Model(&models.LedgerEntry{}).
+		Select("account, COALESCE(SUM(amount), 0) AS balance").
+		Where("group_id = ?", groupID).
+		Group("account").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	balances := make(map[string]int, len(rows))
+	for _, r := range rows {
+		balances[r.Account] = r.Balance
+	}
+	return balances, nil
+}
+
+// expectedEscrows works out what each escrow account should hold from the
+// stakes still outstanding on open pools, brackets, squares games and side
+// bets. Anything settled should hold nothing.
+func expectedEscrows(db *gorm.DB, groupID string) (map[string]int, error) {
+	expected := map[string]int{}
+	var rows []struct {
+		ID     string
+		Amount int
+	}
+	add := func(account func(string) string) {
+		for _, r := range rows {
+			if r.Amount != 0 {
+				expected[account(r.ID)] += r.Amount
+			}
+		}
+		rows = nil
+	}
+
+	openPools := db.Model(&models.Pool{}).Select("id").
+		Where("group_id = ? AND type <> ? AND status IN ?", groupID, models.PoolTypePoll,
+			[]models.PoolStatus{models.PoolStatusOpen, models.PoolStatusLocked})
+	if err := db.Model(&models.Bet{}).Select("pool_id AS id, SUM(points_wagered) AS amount").
+		Where("pool_id IN (?)", openPools).Group("pool_id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	add(PoolEscrow)
+	if err := db.Model(&models.PoolSeed{}).Select("pool_id AS id, SUM(amount) AS amount").
+		Where("pool_id IN (?) AND status = ?", openPools, models.PoolSeedHeld).Group("pool_id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	add(PoolEscrow)
+
+	if err := db.Model(&models.BracketEntry{}).
+		Select("brackets.id AS id, COUNT(*) * brackets.entry_fee AS amount").
+		Joins("JOIN brackets ON brackets.id = bracket_entries.bracket_id").
+		Where("brackets.group_id = ? AND brackets.status IN ?", groupID,
+			[]models.BracketStatus{models.BracketStatusOpen, models.BracketStatusLocked}).
+		Group("brackets.id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	add(BracketEscrow)
+
+	openSquares := []models.SquaresStatus{models.SquaresStatusOpen, models.SquaresStatusDrawn}
+	if err := db.Model(&models.SquaresClaim{}).
+		Select("squares_games.id AS id, COUNT(*) * squares_games.price_per_square AS amount").
+		Joins("JOIN squares_games ON squares_games.id = squares_claims.game_id").
+		Where("squares_games.group_id = ? AND squares_games.status IN ?", groupID, openSquares).
+		Group("squares_games.id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	add(SquaresEscrow)
+	if err := db.Model(&models.SquaresQuarter{}).
+		Select("squares_games.id AS id, -SUM(squares_quarters.amount) AS amount").
+		Joins("JOIN squares_games ON squares_games.id = squares_quarters.game_id").
+		Where("squares_games.group_id = ? AND squares_games.status IN ?", groupID, openSquares).
+		Group("squares_games.id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	add(SquaresEscrow)
+
+	if err := db.Model(&models.SideBetMember{}).
+		Select("side_bets.id AS id, SUM(side_bet_members.stake) AS amount").
+		Joins("JOIN side_bets ON side_bets.id = side_bet_members.side_bet_id").
+		Where("side_bets.group_id = ? AND side_bets.status = ? AND side_bet_members.joined_at IS NOT NULL",
+			groupID, models.SideBetStatusOpen).
+		Group("side_bets.id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	add(SideBetEscrow)
+
+	return expected, nil
+}
+
+// Reconcile recomputes every account in a group from the ledger and reports
+// where it disagrees with the cached member balances or with the stakes that
+// should be sitting in escrow, plus any ledger transaction that doesn't
+// balance.
+func Reconcile(db *gorm.DB, groupID string) (*ReconcileReport, error) {
+	report := &ReconcileReport{GroupID: groupID, Drift: []AccountDrift{}, UnbalancedTxns: []string{}}
+
+	if err := db.Model(&models.LedgerEntry{}).
+		Where("group_id = ?", groupID).
+		Group("txn_id").
+		Having("SUM(amount) <> 0").
+		Pluck("txn_id", &report.UnbalancedTxns).Error; err != nil {
+		return nil, err
+	}
+
+	balances, err := LedgerBalances(db, groupID)
+	if err != nil {
+		return nil, err
+	}
+	expected, err := expectedEscrows(db, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	var members []models.GroupMember
+	if err := db.Where("group_id = ?", groupID).Find(&members).Error; err != nil {
+		return nil, err
+	}
+	reasons := map[string]string{}
+	for _, m := range members {
+		account := MemberAccount(m.UserID)
+		expected[account] = m.PointsBalance
+		reasons[account] = "cached balance differs from the ledger"
+	}
+
+	accounts := make(map[string]bool, len(balances)+len(expected))
+	for a := range balances {
+		accounts[a] = true
+	}
+	for a := range expected {
+		accounts[a] = true
+	}
+	for account := range accounts {
+		bal := balances[account]
+		switch {
+		case account == TreasuryAccount:
+			report.Treasury = bal
+			continue
+		case strings.HasPrefix(account, memberPrefix):
+			report.Members += bal
+		default:
+			report.Escrow += bal
+		}
+
+		if bal == expected[account] {
+			continue
+		}
+		reason := reasons[account]
+		switch {
+		case reason != "":
+		case strings.HasPrefix(account, memberPrefix):
+			reason = "account belongs to someone no longer in the group"
+		case expected[account] == 0:
+			reason = "escrow holds points for something already settled"
+		default:
+			reason = "escrow doesn't match the outstanding stakes"
+		}
+		report.Drift = append(report.Drift, AccountDrift{
+			Account:  account,
+			Expected: expected[account],
+			Ledger:   bal,
+			Reason:   reason,
+		})
+	}
+	sort.Slice(report.Drift, func(i, j int) bool { return report.Drift[i].Account < report.Drift[j].Account })
+
+	return report, nil
+}
+
+// BackfillLedger opens the ledger for groups created before it existed: each
+// member's current balance and every outstanding stake is issued from the
+// treasury as an opening entry, so reconciliation starts out clean. Groups
+// that already have ledger entries are left alone.
+func BackfillLedger(db *gorm.DB) error {
+	var groupIDs []string
+	if err := db.Model(&models.Group{}).
+		Where("id NOT IN (?)", db.Model(&models.LedgerEntry{}).Select("group_id")).
+		Pluck("id", &groupIDs).Error; err != nil {
+		return err
+	}
+
+	for _, groupID := range groupIDs {
+		opening, err := expectedEscrows(db, groupID)
+		if err != nil {
+			return err
+		}
+		var members []models.GroupMember
+		if err := db.Where("group_id = ?", groupID).Find(&members).Error; err != nil {
+			return err
+		}
+		for _, m := range members {
+			opening[MemberAccount(m.UserID)] = m.PointsBalance
+		}
+		if len(opening) == 0 {
+			continue
+		}
+
+		tx := db.Begin()
+		for account, amount := range opening {
+			if amount == 0 {
+				continue
+			}
+			if err := postEntriesTx(tx, groupID, TreasuryAccount, account, amount, ""); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+		if err := tx.Commit().Error; err != nil {
+			return err
+		}
+		log.Printf("Opened points ledger for group %s (%d accounts)", groupID, len(opening))
+	}
+	return nil
+}
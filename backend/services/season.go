@@ -0,0 +1,320 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/codyseavey/bets/models"
+)
+
+type SeasonService struct {
+	db *gorm.DB
+}
+
+func NewSeasonService(db *gorm.DB) *SeasonService {
+	return &SeasonService{db: db}
+}
+
+// CurrentSeason returns the number of the season in progress and when it
+// started.
+func CurrentSeason(db *gorm.DB, groupID string) (int, time.Time, error) {
+	var last models.Season
+	err := db.Where("group_id = ?", groupID).Order("number DESC").Limit(1).Find(&last).Error
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	if last.ID != "" {
+		return last.Number + 1, last.EndedAt, nil
+	}
+
+	var group models.Group
+	if err := db.Select("created_at").First(&group, "id = ?", groupID).Error; err != nil {
+		return 0, time.Time{}, err
+	}
+	return 1, group.CreatedAt, nil
+}
+
+// MemberRecord is a member's betting record over some stretch of time.
+type MemberRecord struct {
+	Wins     int64
+	Losses   int64
+	Bets     int64
+	Bailouts int64
+}
+
+// MemberRecordSince counts a member's bets, wins and losses in a group from
+// since onward, so leaderboards can be scoped to the current season.
+func MemberRecordSince(db *gorm.DB, groupID, userID string, since time.Time) MemberRecord {
+	var rec MemberRecord
+	db.Model(&models.Bet{}).
+		Joins("JOIN pools ON pools.id = bets.pool_id").
+		Where("bets.user_id = ? AND pools.group_id = ? AND pools.type <> ? AND bets.created_at >= ?", userID, groupID, models.PoolTypePoll, since).
+		Count(&rec.Bets)
+
+	ledger := func(logType models.PointsLogType) int64 {
+		var n int64
+		db.Model(&models.PointsLog{}).
+			Where("user_id = ? AND group_id = ? AND type = ? AND created_at >= ?", userID, groupID, logType, since).
+			Count(&n)
+		return n
+	}
+	rec.Wins = ledger(models.PointsLogBetWon)
+	rec.Bailouts = ledger(models.PointsLogBailout)
+
+	// Losses = bets placed that neither won nor were refunded
+	rec.Losses = ledger(models.PointsLogBetPlaced) - rec.Wins - ledger(models.PointsLogBetRefund)
+	if rec.Losses < 0 {
+		rec.Losses = 0
+	}
+	return rec
+}
+
+type CloseSeasonRequest struct {
+	// Name labels the archived season; defaults to "Season N"
+	Name string `json:"name"`
+}
+
+// CloseSeason archives the current season's standings and group stats, then
+// resets every member's balance to the group's default points with a
+// season_reset ledger entry for each. It refuses while points are still
+// escrowed in unsettled pools, brackets or squares, or owed on active loans or
+// waiting on pending approvals, since those would pay out into the new season.
+func (s *SeasonService) CloseSeason(groupID, userID string, req CloseSeasonRequest) (*models.Season, error) {
+	tx := s.db.Begin()
+
+	var group models.Group
+	if err := tx.First(&group, "id = ?", groupID).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("group not found")
+	}
+
+	if err := checkNothingEscrowed(tx, groupID); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	number, startedAt, err := CurrentSeason(tx, groupID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	now := time.Now()
+
+	season := &models.Season{
+		ID:        uuid.New().String(),
+		GroupID:   groupID,
+		Number:    number,
+		Name:      req.Name,
+		StartedAt: startedAt,
+		EndedAt:   now,
+		ClosedBy:  userID,
+	}
+	if season.Name == "" {
+		season.Name = fmt.Sprintf("Season %d", number)
+	}
+
+	tx.Model(&models.Pool{}).Where("group_id = ? AND created_at >= ?", groupID, startedAt).Count(&season.TotalPools)
+	tx.Model(&models.Pool{}).Where("group_id = ? AND status = ? AND resolved_at >= ?", groupID, models.PoolStatusResolved, startedAt).Count(&season.ResolvedPools)
+	tx.Model(&models.Bet{}).Joins("JOIN pools ON pools.id = bets.pool_id").
+		Where("pools.group_id = ? AND pools.type <> ? AND bets.created_at >= ?", groupID, models.PoolTypePoll, startedAt).Count(&season.TotalBets)
+	tx.Model(&models.GroupMember{}).Where("group_id = ?", groupID).Count(&season.TotalMembers)
+	tx.Model(&models.GroupMember{}).Where("group_id = ?", groupID).Select("COALESCE(SUM(points_balance), 0)").Scan(&season.TotalPointsInCirculation)
+
+	if err := tx.Create(season).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to archive season: %w", err)
+	}
+
+	var members []models.GroupMember
+	if err := tx.Where("group_id = ?", groupID).Order("points_balance DESC").Find(&members).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// Members from before starts were recorded get the default points as
+	// their start, so later changes to it don't move this season's results
+	for _, m := range members {
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.SeasonStart{
+			GroupID: groupID, Season: number, UserID: m.UserID, Points: group.DefaultPoints,
+		}).Error
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	for i, m := range members {
+		rank := i + 1
+		if i > 0 && m.PointsBalance == members[i-1].PointsBalance {
+			rank = season.Standings[i-1].Rank
+		}
+		rec := MemberRecordSince(tx, groupID, m.UserID, startedAt)
+		standing := models.SeasonStanding{
+			SeasonID:      season.ID,
+			UserID:        m.UserID,
+			Rank:          rank,
+			PointsBalance: m.PointsBalance,
+			TotalWins:     rec.Wins,
+			TotalLosses:   rec.Losses,
+			TotalBets:     rec.Bets,
+			Bailouts:      rec.Bailouts,
+		}
+		if err := tx.Create(&standing).Error; err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to archive standing: %w", err)
+		}
+		season.Standings = append(season.Standings, standing)
+
+		if err := creditMember(tx, groupID, m.UserID, group.DefaultPoints-m.PointsBalance, TreasuryAccount, models.PointsLogSeasonReset, season.ID,
+			fmt.Sprintf("%s closed: balance reset from %d to %d", season.Name, m.PointsBalance, group.DefaultPoints)); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if err := tx.Create(&models.SeasonStart{GroupID: groupID, Season: number + 1, UserID: m.UserID, Points: group.DefaultPoints}).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetSeason(season.ID)
+}
+
+// addSeasonStartTx records points a member brought into the season in
+// progress. Someone who rejoins after being removed adds to their row.
+func addSeasonStartTx(tx *gorm.DB, groupID, userID string, points int) error {
+	number, _, err := CurrentSeason(tx, groupID)
+	if err != nil {
+		return err
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "group_id"}, {Name: "season"}, {Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"points": gorm.Expr("season_starts.points + ?", points)}),
+	}).Create(&models.SeasonStart{GroupID: groupID, Season: number, UserID: userID, Points: points}).Error
+}
+
+// addSeasonForfeitTx records the balance a member took out of the season in
+// progress when they were removed.
+func addSeasonForfeitTx(tx *gorm.DB, groupID, userID string, forfeited int) error {
+	number, _, err := CurrentSeason(tx, groupID)
+	if err != nil {
+		return err
+	}
+	var group models.Group
+	if err := tx.Select("default_points").First(&group, "id = ?", groupID).Error; err != nil {
+		return fmt.Errorf("group not found")
+	}
+	// Members from before starts were recorded are assumed to have started
+	// on the default points
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "group_id"}, {Name: "season"}, {Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"forfeited": gorm.Expr("season_starts.forfeited + ?", forfeited)}),
+	}).Create(&models.SeasonStart{GroupID: groupID, Season: number, UserID: userID, Points: group.DefaultPoints, Forfeited: forfeited}).Error
+}
+
+// seasonNets works out each member's result for a season: what they ended on
+// (zero for anyone who's gone), plus anything forfeited when they left, minus
+// what they started with. balances holds the final balances of the members
+// still around; defaultPoints stands in for members with no recorded start.
+func seasonNets(db *gorm.DB, groupID string, season int, balances map[string]int, defaultPoints int) (map[string]int, error) {
+	var starts []models.SeasonStart
+	if err := db.Where("group_id = ? AND season = ?", groupID, season).Find(&starts).Error; err != nil {
+		return nil, err
+	}
+
+	nets := make(map[string]int, len(balances))
+	for userID, balance := range balances {
+		nets[userID] = balance - defaultPoints
+	}
+	for _, st := range starts {
+		nets[st.UserID] = balances[st.UserID] + st.Forfeited - st.Points
+	}
+	return nets, nil
+}
+
+func checkNothingEscrowed(tx *gorm.DB, groupID string) error {
+	var pools int64
+	tx.Model(&models.Pool{}).
+		Where("group_id = ? AND type <> ? AND status IN ?", groupID, models.PoolTypePoll,
+			[]models.PoolStatus{models.PoolStatusOpen, models.PoolStatusLocked}).
+		Where("id IN (?)", tx.Model(&models.Bet{}).Select("pool_id")).
+		Count(&pools)
+	if pools > 0 {
+		return fmt.Errorf("resolve or cancel the %d pool(s) with outstanding bets before closing the season", pools)
+	}
+
+	var seeded int64
+	tx.Model(&models.PoolSeed{}).
+		Joins("JOIN pools ON pools.id = pool_seeds.pool_id").
+		Where("pools.group_id = ? AND pool_seeds.status = ?", groupID, models.PoolSeedHeld).
+		Count(&seeded)
+	if seeded > 0 {
+		return fmt.Errorf("resolve or cancel the seeded pool(s) before closing the season")
+	}
+
+	var brackets int64
+	tx.Model(&models.Bracket{}).
+		Where("group_id = ? AND entry_fee > 0 AND status IN ?", groupID, []models.BracketStatus{models.BracketStatusOpen, models.BracketStatusLocked}).
+		Where("id IN (?)", tx.Model(&models.BracketEntry{}).Select("bracket_id")).
+		Count(&brackets)
+	if brackets > 0 {
+		return fmt.Errorf("finish or cancel the %d bracket(s) holding entry fees before closing the season", brackets)
+	}
+
+	var squares int64
+	tx.Model(&models.SquaresGame{}).
+		Where("group_id = ? AND status IN ?", groupID, []models.SquaresStatus{models.SquaresStatusOpen, models.SquaresStatusDrawn}).
+		Where("id IN (?)", tx.Model(&models.SquaresClaim{}).Select("game_id")).
+		Count(&squares)
+	if squares > 0 {
+		return fmt.Errorf("finish or cancel the %d squares game(s) before closing the season", squares)
+	}
+
+	var sideBets int64
+	tx.Model(&models.SideBet{}).Where("group_id = ? AND status = ?", groupID, models.SideBetStatusOpen).Count(&sideBets)
+	if sideBets > 0 {
+		return fmt.Errorf("settle or cancel the %d side bet(s) before closing the season", sideBets)
+	}
+
+	// A loan's debt or a held grant would otherwise carry into the new
+	// season on top of the reset balances
+	var loans int64
+	tx.Model(&models.Loan{}).Where("group_id = ? AND status = ?", groupID, models.LoanStatusActive).Count(&loans)
+	if loans > 0 {
+		return fmt.Errorf("repay or forgive the %d active loan(s) before closing the season", loans)
+	}
+
+	var approvals int64
+	tx.Model(&models.GrantApproval{}).Where("group_id = ? AND status = ?", groupID, models.GrantApprovalPending).Count(&approvals)
+	if approvals > 0 {
+		return fmt.Errorf("approve, reject or cancel the %d pending approval(s) before closing the season", approvals)
+	}
+	return nil
+}
+
+func (s *SeasonService) GetSeasons(groupID string) ([]models.Season, error) {
+	var seasons []models.Season
+	err := s.db.Where("group_id = ?", groupID).Order("number DESC").Find(&seasons).Error
+	return seasons, err
+}
+
+func (s *SeasonService) GetSeason(seasonID string) (*models.Season, error) {
+	var season models.Season
+	err := s.db.
+		Preload("Standings", func(db *gorm.DB) *gorm.DB {
+			return db.Order("rank ASC")
+		}).
+		Preload("Standings.User").
+		First(&season, "id = ?", seasonID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &season, nil
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/codyseavey/bets/models"
+)
+
+func TestSeason_CloseArchivesAndResets(t *testing.T) {
+	db, poolSvc, _, group, alice, bob := setupPoolTest(t)
+	svc := NewSeasonService(db)
+
+	pool, _ := poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{Title: "Q1", Options: []string{"Yes", "No"}})
+	poolSvc.PlaceBet(pool.ID, alice.ID, PlaceBetRequest{OptionID: pool.Options[0].ID, Points: 200})
+	poolSvc.PlaceBet(pool.ID, bob.ID, PlaceBetRequest{OptionID: pool.Options[1].ID, Points: 100})
+
+	if _, err := svc.CloseSeason(group.ID, alice.ID, CloseSeasonRequest{}); err == nil {
+		t.Error("expected error closing a season with bets still escrowed")
+	}
+
+	poolSvc.ResolvePool(pool.ID, pool.Options[0].ID, alice.ID, true)
+
+	season, err := svc.CloseSeason(group.ID, alice.ID, CloseSeasonRequest{})
+	if err != nil {
+		t.Fatalf("CloseSeason failed: %v", err)
+	}
+	if season.Number != 1 || season.Name != "Season 1" {
+		t.Errorf("expected Season 1, got %d %q", season.Number, season.Name)
+	}
+	if season.TotalPools != 1 || season.ResolvedPools != 1 || season.TotalBets != 2 || season.TotalPointsInCirculation != 2000 {
+		t.Errorf("unexpected archived stats: %+v", season)
+	}
+	if len(season.Standings) != 2 {
+		t.Fatalf("expected 2 standings, got %d", len(season.Standings))
+	}
+	top := season.Standings[0]
+	if top.UserID != alice.ID || top.PointsBalance != 1100 || top.TotalWins != 1 || top.Rank != 1 {
+		t.Errorf("expected Alice archived first with 1100 and a win, got %+v", top)
+	}
+	if season.Standings[1].TotalLosses != 1 {
+		t.Errorf("expected Bob archived with a loss, got %+v", season.Standings[1])
+	}
+
+	// Balances reset to the group default, each with an explaining ledger entry
+	for userID, delta := range map[string]int{alice.ID: -100, bob.ID: 100} {
+		var m models.GroupMember
+		db.Where("group_id = ? AND user_id = ?", group.ID, userID).First(&m)
+		if m.PointsBalance != group.DefaultPoints {
+			t.Errorf("expected %s reset to %d, got %d", userID, group.DefaultPoints, m.PointsBalance)
+		}
+		var reset models.PointsLog
+		if err := db.Where("user_id = ? AND type = ?", userID, models.PointsLogSeasonReset).First(&reset).Error; err != nil {
+			t.Fatalf("expected season_reset log for %s: %v", userID, err)
+		}
+		if reset.Amount != delta || reset.ReferenceID != season.ID {
+			t.Errorf("expected reset of %d referencing the season, got %+v", delta, reset)
+		}
+	}
+
+	number, startedAt, _ := CurrentSeason(db, group.ID)
+	if number != 2 || !startedAt.Equal(season.EndedAt) {
+		t.Errorf("expected season 2 starting at %v, got %d at %v", season.EndedAt, number, startedAt)
+	}
+	if rec := MemberRecordSince(db, group.ID, alice.ID, startedAt); rec.Wins != 0 || rec.Bets != 0 {
+		t.Errorf("expected a clean record for the new season, got %+v", rec)
+	}
+
+	next, err := svc.CloseSeason(group.ID, bob.ID, CloseSeasonRequest{Name: "Winter"})
+	if err != nil {
+		t.Fatalf("second CloseSeason failed: %v", err)
+	}
+	if next.Number != 2 || next.Name != "Winter" || next.Standings[0].Rank != next.Standings[1].Rank {
+		t.Errorf("expected tied Winter season 2, got %+v", next)
+	}
+
+	assertReconciled(t, db, group.ID)
+}
+
+func TestSeason_CloseRefusesLoansAndPendingApprovals(t *testing.T) {
+	db, _, groupSvc, group, alice, bob := setupPoolTest(t)
+	svc := NewSeasonService(db)
+	loanSvc := NewLoanService(db)
+
+	loan, _ := loanSvc.OfferLoan(group.ID, alice.ID, OfferLoanRequest{BorrowerID: bob.ID, Principal: 100})
+	loanSvc.AcceptLoan(loan.ID, bob.ID)
+	if _, err := svc.CloseSeason(group.ID, alice.ID, CloseSeasonRequest{}); err == nil {
+		t.Error("expected error closing with an active loan")
+	}
+	if _, err := loanSvc.ForgiveLoan(loan.ID, alice.ID); err != nil {
+		t.Fatalf("ForgiveLoan failed: %v", err)
+	}
+
+	groupSvc.SetMemberRole(group.ID, alice.ID, bob.ID, "admin")
+	groupSvc.SetApproveAbove(group.ID, alice.ID, 100)
+	approval, err := groupSvc.RequestGrant(group.ID, alice.ID, bob.ID, 500, "big")
+	if err != nil || approval == nil {
+		t.Fatalf("expected a pending approval, got %+v, %v", approval, err)
+	}
+	if _, err := svc.CloseSeason(group.ID, alice.ID, CloseSeasonRequest{}); err == nil {
+		t.Error("expected error closing with a pending approval")
+	}
+	if _, err := groupSvc.RejectGrant(group.ID, approval.ID, bob.ID); err != nil {
+		t.Fatalf("RejectGrant failed: %v", err)
+	}
+
+	if _, err := svc.CloseSeason(group.ID, alice.ID, CloseSeasonRequest{}); err != nil {
+		t.Errorf("expected the season to close once nothing is outstanding: %v", err)
+	}
+}
@@ -0,0 +1,345 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/codyseavey/bets/models"
+)
+
+type SettlementService struct {
+	db *gorm.DB
+}
+
+func NewSettlementService(db *gorm.DB) *SettlementService {
+	return &SettlementService{db: db}
+}
+
+// PreviewSettlement shows what settling the season in progress would look
+// like right now. Nothing is saved.
+func (s *SettlementService) PreviewSettlement(groupID string) (*models.Settlement, error) {
+	var group models.Group
+	if err := s.db.First(&group, "id = ?", groupID).Error; err != nil {
+		return nil, fmt.Errorf("group not found")
+	}
+	if group.CashRate <= 0 {
+		return nil, fmt.Errorf("set the group's cash rate before settling up")
+	}
+
+	var members []models.GroupMember
+	if err := s.db.Where("group_id = ?", groupID).Find(&members).Error; err != nil {
+		return nil, err
+	}
+	balances := make(map[string]int, len(members))
+	for _, m := range members {
+		balances[m.UserID] = m.PointsBalance
+	}
+	number, _, err := CurrentSeason(s.db, groupID)
+	if err != nil {
+		return nil, err
+	}
+	points, err := seasonNets(s.db, groupID, number, balances, group.DefaultPoints)
+	if err != nil {
+		return nil, err
+	}
+
+	settlement := buildSettlement(groupID, group.CashRate, points)
+	if err := fillSettlementUsers(s.db, settlement); err != nil {
+		return nil, err
+	}
+	return settlement, nil
+}
+
+// CreateSettlement settles a closed season from its archived standings. A
+// season can only be settled once.
+func (s *SettlementService) CreateSettlement(groupID, seasonID, adminID string) (*models.Settlement, error) {
+	tx := s.db.Begin()
+
+	var group models.Group
+	if err := tx.First(&group, "id = ?", groupID).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("group not found")
+	}
+	if group.CashRate <= 0 {
+		tx.Rollback()
+		return nil, fmt.Errorf("set the group's cash rate before settling up")
+	}
+
+	var season models.Season
+	if err := tx.Preload("Standings").First(&season, "id = ? AND group_id = ?", seasonID, groupID).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("season not found")
+	}
+	var existing int64
+	tx.Model(&models.Settlement{}).Where("season_id = ?", seasonID).Count(&existing)
+	if existing > 0 {
+		tx.Rollback()
+		return nil, fmt.Errorf("%s has already been settled", season.Name)
+	}
+
+	balances := make(map[string]int, len(season.Standings))
+	for _, st := range season.Standings {
+		balances[st.UserID] = st.PointsBalance
+	}
+	points, err := seasonNets(tx, groupID, season.Number, balances, group.DefaultPoints)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	settlement := buildSettlement(groupID, group.CashRate, points)
+	settlement.ID = uuid.New().String()
+	settlement.SeasonID = seasonID
+	settlement.CreatedBy = adminID
+	for i := range settlement.Nets {
+		settlement.Nets[i].SettlementID = settlement.ID
+	}
+	for i := range settlement.Payments {
+		settlement.Payments[i].ID = uuid.New().String()
+		settlement.Payments[i].SettlementID = settlement.ID
+	}
+	if err := tx.Create(settlement).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to save settlement: %w", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetSettlement(groupID, seasonID)
+}
+
+func (s *SettlementService) GetSettlement(groupID, seasonID string) (*models.Settlement, error) {
+	var settlement models.Settlement
+	err := s.db.
+		Preload("Nets", func(db *gorm.DB) *gorm.DB {
+			return db.Order("points DESC")
+		}).
+		Preload("Nets.User").
+		Preload("Payments", func(db *gorm.DB) *gorm.DB {
+			return db.Order("cents DESC")
+		}).
+		Preload("Payments.FromUser").
+		Preload("Payments.ToUser").
+		First(&settlement, "group_id = ? AND season_id = ?", groupID, seasonID).Error
+	if err != nil {
+		return nil, fmt.Errorf("season hasn't been settled")
+	}
+	return &settlement, nil
+}
+
+// MarkPaymentPaid records that a payment was (or, with paid false, wasn't
+// after all) made. The member being paid or a group admin can mark it.
+func (s *SettlementService) MarkPaymentPaid(groupID, seasonID, paymentID, userID string, paid bool) (*models.SettlementPayment, error) {
+	var payment models.SettlementPayment
+	err := s.db.
+		Joins("JOIN settlements ON settlements.id = settlement_payments.settlement_id").
+		Where("settlement_payments.id = ? AND settlements.group_id = ? AND settlements.season_id = ?", paymentID, groupID, seasonID).
+		First(&payment).Error
+	if err != nil {
+		return nil, fmt.Errorf("payment not found")
+	}
+
+	if payment.ToUserID != userID {
+		var member models.GroupMember
+		if err := s.db.Where("group_id = ? AND user_id = ?", groupID, userID).First(&member).Error; err != nil || member.Role != "admin" {
+			return nil, fmt.Errorf("only the member being paid or an admin can mark this")
+		}
+	}
+	if paid == (payment.PaidAt != nil) {
+		if paid {
+			return nil, fmt.Errorf("payment is already marked paid")
+		}
+		return nil, fmt.Errorf("payment isn't marked paid")
+	}
+
+	updates := map[string]interface{}{"paid_at": nil, "marked_by": ""}
+	if paid {
+		updates["paid_at"] = time.Now()
+		updates["marked_by"] = userID
+	}
+	if err := s.db.Model(&payment).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+
+	err = s.db.Preload("FromUser").Preload("ToUser").First(&payment, "id = ?", paymentID).Error
+	return &payment, err
+}
+
+// buildSettlement converts members' point nets to cents at the group's cash
+// rate and works out the payments. The result has no IDs.
+//
+// A member's net for a season is their final balance, plus anything forfeited
+// if they were removed, minus what they started the season with (see
+// models.SeasonStart). Members removed partway through are settled too.
+// Grants, allowances and bailouts put points into play that nobody lost, so
+// what winners are owed rarely equals what losers owe; the larger side is
+// scaled down to match the smaller, and the difference is reported as
+// Unmatched.
+func buildSettlement(groupID string, cashRate int, points map[string]int) *models.Settlement {
+	userIDs := make([]string, 0, len(points))
+	for userID := range points {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Slice(userIDs, func(i, j int) bool {
+		if points[userIDs[i]] != points[userIDs[j]] {
+			return points[userIDs[i]] > points[userIDs[j]]
+		}
+		return userIDs[i] < userIDs[j]
+	})
+
+	// Winners and losers, each in cents as a positive amount
+	var winners, losers []int
+	var winnerIDs, loserIDs []string
+	for _, userID := range userIDs {
+		cents := toCents(points[userID], cashRate)
+		switch {
+		case cents > 0:
+			winners = append(winners, cents)
+			winnerIDs = append(winnerIDs, userID)
+		case cents < 0:
+			losers = append(losers, -cents)
+			loserIDs = append(loserIDs, userID)
+		}
+	}
+
+	won, lost := sumInts(winners), sumInts(losers)
+	settlement := &models.Settlement{
+		GroupID:   groupID,
+		CashRate:  cashRate,
+		Unmatched: won - lost,
+		Nets:      []models.SettlementNet{},
+		Payments:  []models.SettlementPayment{},
+	}
+	if settlement.Unmatched < 0 {
+		settlement.Unmatched = -settlement.Unmatched
+	}
+	if won > lost {
+		winners = scaleTo(winners, lost)
+	} else {
+		losers = scaleTo(losers, won)
+	}
+
+	cents := make(map[string]int, len(userIDs))
+	for i, userID := range winnerIDs {
+		cents[userID] = winners[i]
+	}
+	for i, userID := range loserIDs {
+		cents[userID] = -losers[i]
+	}
+	for _, userID := range userIDs {
+		settlement.Nets = append(settlement.Nets, models.SettlementNet{
+			UserID: userID,
+			Points: points[userID],
+			Cents:  cents[userID],
+		})
+	}
+
+	settlement.Payments = matchDebts(winnerIDs, winners, loserIDs, losers)
+	return settlement
+}
+
+// matchDebts pairs the biggest remaining debt with the biggest remaining
+// credit until everything is paid. Each payment clears at least one side, so
+// there are always fewer payments than people involved.
+func matchDebts(winnerIDs []string, winners []int, loserIDs []string, losers []int) []models.SettlementPayment {
+	owed := append([]int(nil), winners...)
+	owes := append([]int(nil), losers...)
+	payments := []models.SettlementPayment{}
+	for {
+		w, l := largest(owed), largest(owes)
+		if w < 0 || l < 0 {
+			return payments
+		}
+		amount := min(owed[w], owes[l])
+		payments = append(payments, models.SettlementPayment{
+			FromUserID: loserIDs[l],
+			ToUserID:   winnerIDs[w],
+			Cents:      amount,
+		})
+		owed[w] -= amount
+		owes[l] -= amount
+	}
+}
+
+// largest returns the index of the biggest positive amount, or -1.
+func largest(amounts []int) int {
+	best := -1
+	for i, a := range amounts {
+		if a > 0 && (best < 0 || a > amounts[best]) {
+			best = i
+		}
+	}
+	return best
+}
+
+// scaleTo shrinks amounts proportionally so they add up to total, handing out
+// the rounding leftovers to the largest remainders.
+func scaleTo(amounts []int, total int) []int {
+	sum := sumInts(amounts)
+	if sum == total {
+		return amounts
+	}
+	scaled := make([]int, len(amounts))
+	remainders := make([]int, len(amounts))
+	given := 0
+	for i, a := range amounts {
+		scaled[i] = a * total / sum
+		remainders[i] = a * total % sum
+		given += scaled[i]
+	}
+	order := make([]int, len(amounts))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return remainders[order[i]] > remainders[order[j]] })
+	for _, i := range order[:total-given] {
+		scaled[i]++
+	}
+	return scaled
+}
+
+// toCents converts points to cents at cashRate points per dollar, rounding
+// half away from zero.
+func toCents(points, cashRate int) int {
+	if points < 0 {
+		return -toCents(-points, cashRate)
+	}
+	return (points*100*2 + cashRate) / (cashRate * 2)
+}
+
+func sumInts(amounts []int) int {
+	total := 0
+	for _, a := range amounts {
+		total += a
+	}
+	return total
+}
+
+func fillSettlementUsers(db *gorm.DB, settlement *models.Settlement) error {
+	userIDs := make([]string, 0, len(settlement.Nets))
+	for _, n := range settlement.Nets {
+		userIDs = append(userIDs, n.UserID)
+	}
+	var users []models.User
+	if err := db.Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+		return err
+	}
+	byID := make(map[string]models.User, len(users))
+	for _, u := range users {
+		byID[u.ID] = u
+	}
+	for i := range settlement.Nets {
+		settlement.Nets[i].User = byID[settlement.Nets[i].UserID]
+	}
+	for i := range settlement.Payments {
+		settlement.Payments[i].FromUser = byID[settlement.Payments[i].FromUserID]
+		settlement.Payments[i].ToUser = byID[settlement.Payments[i].ToUserID]
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/codyseavey/bets/models"
+)
+
+func TestBuildSettlementMinimisesPayments(t *testing.T) {
+	// 100 points to the dollar: Alice +$5, Bob +$1, Carol -$4, Dave -$2
+	settlement := buildSettlement("g", 100, map[string]int{
+		"alice": 500, "bob": 100, "carol": -400, "dave": -200,
+	})
+
+	if settlement.Unmatched != 0 {
+		t.Errorf("expected nothing unmatched, got %d", settlement.Unmatched)
+	}
+	if len(settlement.Payments) != 3 {
+		t.Fatalf("expected 3 payments, got %+v", settlement.Payments)
+	}
+	balance := map[string]int{}
+	for _, p := range settlement.Payments {
+		balance[p.FromUserID] -= p.Cents
+		balance[p.ToUserID] += p.Cents
+	}
+	for _, n := range settlement.Nets {
+		if balance[n.UserID] != n.Cents {
+			t.Errorf("expected %s to end up at %d cents, payments give %d", n.UserID, n.Cents, balance[n.UserID])
+		}
+	}
+	if p := settlement.Payments[0]; p.FromUserID != "carol" || p.ToUserID != "alice" || p.Cents != 400 {
+		t.Errorf("expected Carol to pay Alice $4 first, got %+v", p)
+	}
+}
+
+func TestBuildSettlementScalesMintedPoints(t *testing.T) {
+	// Winners are up 300 points but only 150 were lost; the rest was granted
+	settlement := buildSettlement("g", 10, map[string]int{
+		"alice": 200, "bob": 100, "carol": -150,
+	})
+
+	if settlement.Unmatched != 1500 {
+		t.Errorf("expected $15 unmatched, got %d cents", settlement.Unmatched)
+	}
+	paid := 0
+	for _, p := range settlement.Payments {
+		if p.FromUserID != "carol" {
+			t.Errorf("expected only Carol to pay, got %+v", p)
+		}
+		paid += p.Cents
+	}
+	if paid != 1500 {
+		t.Errorf("expected Carol to pay exactly her $15, got %d cents", paid)
+	}
+	for _, n := range settlement.Nets {
+		if n.UserID == "alice" && n.Cents != 1000 {
+			t.Errorf("expected Alice's winnings scaled to $10, got %d cents", n.Cents)
+		}
+	}
+}
+
+func TestSettleSeasonAndMarkPaid(t *testing.T) {
+	db, poolSvc, groupSvc, group, alice, bob := setupPoolTest(t)
+	seasonSvc := NewSeasonService(db)
+	svc := NewSettlementService(db)
+
+	pool, _ := poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{Title: "Q1", Options: []string{"Yes", "No"}})
+	poolSvc.PlaceBet(pool.ID, alice.ID, PlaceBetRequest{OptionID: pool.Options[0].ID, Points: 250})
+	poolSvc.PlaceBet(pool.ID, bob.ID, PlaceBetRequest{OptionID: pool.Options[1].ID, Points: 250})
+	poolSvc.ResolvePool(pool.ID, pool.Options[0].ID, alice.ID, false)
+
+	season, err := seasonSvc.CloseSeason(group.ID, alice.ID, CloseSeasonRequest{})
+	if err != nil {
+		t.Fatalf("CloseSeason failed: %v", err)
+	}
+	if _, err := svc.CreateSettlement(group.ID, season.ID, alice.ID); err == nil {
+		t.Error("expected an error settling without a cash rate")
+	}
+
+	rate := 50
+	groupSvc.UpdateGroup(group.ID, group.Name, group.DefaultPoints, nil, &rate)
+	settlement, err := svc.CreateSettlement(group.ID, season.ID, alice.ID)
+	if err != nil {
+		t.Fatalf("CreateSettlement failed: %v", err)
+	}
+	if len(settlement.Payments) != 1 {
+		t.Fatalf("expected one payment, got %+v", settlement.Payments)
+	}
+	payment := settlement.Payments[0]
+	if payment.FromUserID != bob.ID || payment.ToUserID != alice.ID || payment.Cents != 500 || payment.FromUser.Name != "Bob" {
+		t.Errorf("expected Bob to pay Alice $5, got %+v", payment)
+	}
+	if _, err := svc.CreateSettlement(group.ID, season.ID, alice.ID); err == nil {
+		t.Error("expected an error settling the same season twice")
+	}
+
+	// Changing the rate afterwards doesn't rewrite the settlement
+	rate = 1
+	groupSvc.UpdateGroup(group.ID, group.Name, group.DefaultPoints, nil, &rate)
+	stored, _ := svc.GetSettlement(group.ID, season.ID)
+	if stored.CashRate != 50 || stored.Payments[0].Cents != 500 {
+		t.Errorf("expected the settlement to keep its rate, got %+v", stored)
+	}
+
+	if _, err := svc.MarkPaymentPaid(group.ID, season.ID, payment.ID, bob.ID, true); err == nil {
+		t.Error("expected the payer to be unable to mark their own payment paid")
+	}
+	paid, err := svc.MarkPaymentPaid(group.ID, season.ID, payment.ID, alice.ID, true)
+	if err != nil {
+		t.Fatalf("MarkPaymentPaid failed: %v", err)
+	}
+	if paid.PaidAt == nil || paid.MarkedBy != alice.ID {
+		t.Errorf("expected the payment marked paid by Alice, got %+v", paid)
+	}
+	unpaid, err := svc.MarkPaymentPaid(group.ID, season.ID, payment.ID, alice.ID, false)
+	if err != nil || unpaid.PaidAt != nil {
+		t.Errorf("expected the payment unmarked, got %+v, %v", unpaid, err)
+	}
+
+	// Settling is bookkeeping only
+	if got := memberBalance(t, poolSvc, group.ID, bob.ID); got != group.DefaultPoints {
+		t.Errorf("expected Bob's points untouched at %d, got %d", group.DefaultPoints, got)
+	}
+	assertReconciled(t, db, group.ID)
+
+	if err := groupSvc.DeleteGroup(group.ID); err != nil {
+		t.Fatalf("DeleteGroup failed: %v", err)
+	}
+	var left int64
+	db.Model(&models.SettlementPayment{}).Count(&left)
+	if left != 0 {
+		t.Errorf("expected settlement payments deleted with the group, %d left", left)
+	}
+}
+
+func TestPreviewSettlement(t *testing.T) {
+	db, _, groupSvc, group, alice, bob := setupPoolTest(t)
+	svc := NewSettlementService(db)
+
+	rate := 100
+	groupSvc.UpdateGroup(group.ID, group.Name, group.DefaultPoints, nil, &rate)
+	groupSvc.GrantPoints(group.ID, alice.ID, 300, "")
+	groupSvc.GrantPoints(group.ID, bob.ID, -300, "")
+
+	preview, err := svc.PreviewSettlement(group.ID)
+	if err != nil {
+		t.Fatalf("PreviewSettlement failed: %v", err)
+	}
+	if len(preview.Payments) != 1 || preview.Payments[0].Cents != 300 || preview.Payments[0].ToUser.Name != "Alice" {
+		t.Errorf("expected Bob to owe Alice $3, got %+v", preview.Payments)
+	}
+	var saved int64
+	db.Model(&models.Settlement{}).Count(&saved)
+	if saved != 0 {
+		t.Errorf("expected the preview not to be saved, found %d", saved)
+	}
+}
+
+func TestSettlementUsesSeasonStarts(t *testing.T) {
+	db, poolSvc, groupSvc, group, alice, bob := setupPoolTest(t)
+	seasonSvc := NewSeasonService(db)
+	svc := NewSettlementService(db)
+	carol := createTestUser(t, db, "carol", "Carol")
+	groupSvc.JoinGroup(group.InviteCode, carol.ID)
+
+	pool, _ := poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{Title: "Q1", Options: []string{"Yes", "No"}})
+	poolSvc.PlaceBet(pool.ID, alice.ID, PlaceBetRequest{OptionID: pool.Options[0].ID, Points: 200})
+	poolSvc.PlaceBet(pool.ID, carol.ID, PlaceBetRequest{OptionID: pool.Options[1].ID, Points: 300})
+	poolSvc.ResolvePool(pool.ID, pool.Options[0].ID, alice.ID, false)
+
+	// Carol leaves 300 down; the default points change before the season ends
+	if err := groupSvc.KickMember(group.ID, carol.ID); err != nil {
+		t.Fatalf("KickMember failed: %v", err)
+	}
+	rate := 100
+	groupSvc.UpdateGroup(group.ID, group.Name, 5000, nil, &rate)
+
+	preview, err := svc.PreviewSettlement(group.ID)
+	if err != nil {
+		t.Fatalf("PreviewSettlement failed: %v", err)
+	}
+	if len(preview.Payments) != 1 || preview.Payments[0].FromUserID != carol.ID || preview.Payments[0].Cents != 300 {
+		t.Errorf("expected Carol to owe Alice $3 in the preview, got %+v", preview.Payments)
+	}
+
+	season, err := seasonSvc.CloseSeason(group.ID, alice.ID, CloseSeasonRequest{})
+	if err != nil {
+		t.Fatalf("CloseSeason failed: %v", err)
+	}
+	settlement, err := svc.CreateSettlement(group.ID, season.ID, alice.ID)
+	if err != nil {
+		t.Fatalf("CreateSettlement failed: %v", err)
+	}
+	if len(settlement.Payments) != 1 {
+		t.Fatalf("expected one payment, got %+v", settlement.Payments)
+	}
+	payment := settlement.Payments[0]
+	if payment.FromUserID != carol.ID || payment.ToUserID != alice.ID || payment.Cents != 300 {
+		t.Errorf("expected Carol to pay Alice $3, got %+v", payment)
+	}
+	for _, n := range settlement.Nets {
+		if n.UserID == bob.ID && n.Points != 0 {
+			t.Errorf("expected Bob to break even, got %d", n.Points)
+		}
+	}
+
+	// The next season starts from the new default points
+	next, _ := svc.PreviewSettlement(group.ID)
+	if len(next.Payments) != 0 {
+		t.Errorf("expected nobody to owe anything at the start of a season, got %+v", next.Payments)
+	}
+}
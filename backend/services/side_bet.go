@@ -0,0 +1,386 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/codyseavey/bets/models"
+)
+
+type SideBetService struct {
+	db *gorm.DB
+}
+
+func NewSideBetService(db *gorm.DB) *SideBetService {
+	return &SideBetService{db: db}
+}
+
+type CreateSideBetRequest struct {
+	Terms     string   `json:"terms" binding:"required"`
+	OptionID  string   `json:"option_id" binding:"required"` // the creator's side
+	Stake     int      `json:"stake" binding:"required,gt=0"`
+	MemberIDs []string `json:"member_ids" binding:"required,min=1"` // members invited to take the other side
+}
+
+type JoinSideBetRequest struct {
+	OptionID string `json:"option_id" binding:"required"`
+	Stake    int    `json:"stake" binding:"required,gt=0"`
+}
+
+// sideBetPoolTx loads the parent pool for a side bet action and checks that
+// it's still taking side bets.
+func sideBetPoolTx(tx *gorm.DB, poolID, userID string) (*models.Pool, error) {
+	var pool models.Pool
+	if err := tx.First(&pool, "id = ?", poolID).Error; err != nil || !canViewPool(tx, &pool, userID) {
+		return nil, fmt.Errorf("pool not found")
+	}
+	if pool.Type != models.PoolTypeStandard && pool.Type != models.PoolTypeSpread {
+		return nil, fmt.Errorf("side bets can only be attached to standard and spread pools")
+	}
+	if pool.Status != models.PoolStatusOpen {
+		return nil, fmt.Errorf("pool is %s", pool.Status)
+	}
+	return &pool, nil
+}
+
+func validOptionTx(tx *gorm.DB, poolID, optionID string) error {
+	var count int64
+	tx.Model(&models.PoolOption{}).Where("id = ? AND pool_id = ?", optionID, poolID).Count(&count)
+	if count == 0 {
+		return fmt.Errorf("invalid option")
+	}
+	return nil
+}
+
+// CreateSideBet attaches a private side bet to a pool, escrows the creator's
+// stake, and invites the other members.
+func (s *SideBetService) CreateSideBet(groupID, poolID, userID string, req CreateSideBetRequest) (*models.SideBet, error) {
+	tx := s.db.Begin()
+
+	pool, err := sideBetPoolTx(tx, poolID, userID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if pool.GroupID != groupID {
+		tx.Rollback()
+		return nil, fmt.Errorf("pool not found")
+	}
+	if err := validOptionTx(tx, pool.ID, req.OptionID); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	seen := map[string]bool{userID: true}
+	var invitees []string
+	for _, id := range req.MemberIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		var count int64
+		tx.Model(&models.GroupMember{}).Where("group_id = ? AND user_id = ?", pool.GroupID, id).Count(&count)
+		if count == 0 || !canViewPool(tx, pool, id) {
+			tx.Rollback()
+			return nil, fmt.Errorf("user %s can't see this pool", id)
+		}
+		invitees = append(invitees, id)
+	}
+	if len(invitees) == 0 {
+		tx.Rollback()
+		return nil, fmt.Errorf("invite at least one other member")
+	}
+
+	now := time.Now()
+	sideBet := &models.SideBet{
+		ID:        uuid.New().String(),
+		GroupID:   pool.GroupID,
+		PoolID:    pool.ID,
+		CreatedBy: userID,
+		Terms:     req.Terms,
+		Status:    models.SideBetStatusOpen,
+	}
+	if err := tx.Create(sideBet).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to create side bet: %w", err)
+	}
+
+	members := []models.SideBetMember{{
+		SideBetID: sideBet.ID,
+		UserID:    userID,
+		OptionID:  req.OptionID,
+		Stake:     req.Stake,
+		JoinedAt:  &now,
+	}}
+	for _, id := range invitees {
+		members = append(members, models.SideBetMember{SideBetID: sideBet.ID, UserID: id})
+	}
+	if err := tx.Create(&members).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to invite members: %w", err)
+	}
+
+	if err := debitMember(tx, pool.GroupID, userID, req.Stake, SideBetEscrow(sideBet.ID), models.PointsLogSideBetStake, sideBet.ID,
+		fmt.Sprintf("Side bet on pool \"%s\"", pool.Title)); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetSideBet(sideBet.ID, userID)
+}
+
+// GetSideBet returns a side bet if userID was invited to it.
+func (s *SideBetService) GetSideBet(sideBetID, userID string) (*models.SideBet, error) {
+	var sideBet models.SideBet
+	err := s.db.
+		Where("id IN (?)", s.db.Model(&models.SideBetMember{}).Select("side_bet_id").Where("user_id = ?", userID)).
+		Preload("Creator").
+		Preload("Pool.Options").
+		Preload("Members.User").
+		First(&sideBet, "id = ?", sideBetID).Error
+	if err != nil {
+		return nil, fmt.Errorf("side bet not found")
+	}
+	return &sideBet, nil
+}
+
+// GetUserSideBets lists the side bets in a group that userID was invited to,
+// optionally limited to one pool.
+func (s *SideBetService) GetUserSideBets(groupID, userID, poolID string) ([]models.SideBet, error) {
+	query := s.db.
+		Where("group_id = ?", groupID).
+		Where("id IN (?)", s.db.Model(&models.SideBetMember{}).Select("side_bet_id").Where("user_id = ?", userID)).
+		Preload("Creator").
+		Preload("Members.User").
+		Order("created_at DESC")
+	if poolID != "" {
+		query = query.Where("pool_id = ?", poolID)
+	}
+
+	var sideBets []models.SideBet
+	if err := query.Find(&sideBets).Error; err != nil {
+		return nil, err
+	}
+	return sideBets, nil
+}
+
+// JoinSideBet lets an invited member pick a side and escrow their stake. The
+// parent pool has to still be open.
+func (s *SideBetService) JoinSideBet(sideBetID, userID string, req JoinSideBetRequest) (*models.SideBet, error) {
+	tx := s.db.Begin()
+
+	var sideBet models.SideBet
+	if err := tx.First(&sideBet, "id = ?", sideBetID).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("side bet not found")
+	}
+	var member models.SideBetMember
+	if err := tx.First(&member, "side_bet_id = ? AND user_id = ?", sideBetID, userID).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("side bet not found")
+	}
+	if sideBet.Status != models.SideBetStatusOpen {
+		tx.Rollback()
+		return nil, fmt.Errorf("side bet is already %s", sideBet.Status)
+	}
+	if member.JoinedAt != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("you've already joined this side bet")
+	}
+
+	pool, err := sideBetPoolTx(tx, sideBet.PoolID, userID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := validOptionTx(tx, pool.ID, req.OptionID); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Model(&member).Updates(map[string]interface{}{
+		"option_id": req.OptionID,
+		"stake":     req.Stake,
+		"joined_at": time.Now(),
+	}).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := debitMember(tx, pool.GroupID, userID, req.Stake, SideBetEscrow(sideBet.ID), models.PointsLogSideBetStake, sideBet.ID,
+		fmt.Sprintf("Side bet on pool \"%s\"", pool.Title)); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetSideBet(sideBetID, userID)
+}
+
+// CancelSideBet lets the creator call off a side bet before the pool locks.
+// Every stake is refunded.
+func (s *SideBetService) CancelSideBet(sideBetID, userID string) (*models.SideBet, error) {
+	tx := s.db.Begin()
+
+	var sideBet models.SideBet
+	if err := tx.First(&sideBet, "id = ?", sideBetID).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("side bet not found")
+	}
+	if sideBet.CreatedBy != userID {
+		tx.Rollback()
+		return nil, fmt.Errorf("only the side bet's creator can cancel it")
+	}
+	if sideBet.Status != models.SideBetStatusOpen {
+		tx.Rollback()
+		return nil, fmt.Errorf("side bet is already %s", sideBet.Status)
+	}
+	if _, err := sideBetPoolTx(tx, sideBet.PoolID, userID); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := refundSideBetTx(tx, &sideBet, "Side bet cancelled, stake refunded"); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetSideBet(sideBetID, userID)
+}
+
+func openSideBetsTx(tx *gorm.DB, poolID string) ([]models.SideBet, error) {
+	var sideBets []models.SideBet
+	err := tx.Where("pool_id = ? AND status = ?", poolID, models.SideBetStatusOpen).
+		Preload("Members", "joined_at IS NOT NULL").
+		Find(&sideBets).Error
+	return sideBets, err
+}
+
+// settleSideBetsTx pays out the open side bets on a pool that's just been
+// resolved. Within each side bet, stakes on pushed options are refunded and
+// the rest are split among the members who backed the winner in proportion
+// to their stakes. If nobody backed the winner, or nobody backed anything
+// else, everyone gets their stake back.
+func settleSideBetsTx(tx *gorm.DB, pool *models.Pool, winningOptionID string, pushed map[string]bool) error {
+	sideBets, err := openSideBetsTx(tx, pool.ID)
+	if err != nil {
+		return err
+	}
+
+	for i := range sideBets {
+		sb := &sideBets[i]
+		payouts := make(map[string]int, len(sb.Members))
+		var live []models.SideBetMember
+		pot, winningStakes := 0, 0
+		for _, m := range sb.Members {
+			if pushed[m.OptionID] {
+				payouts[m.UserID] = m.Stake
+				if err := creditMember(tx, pool.GroupID, m.UserID, m.Stake, SideBetEscrow(sb.ID), models.PointsLogSideBetRefund, sb.ID, "Push, side bet stake refunded"); err != nil {
+					return err
+				}
+				continue
+			}
+			live = append(live, m)
+			pot += m.Stake
+			if m.OptionID == winningOptionID {
+				winningStakes += m.Stake
+			}
+		}
+
+		if winningStakes == 0 || winningStakes == pot {
+			note := "No winners, side bet stake refunded"
+			if winningStakes == pot {
+				note = "Nobody took the other side, side bet stake refunded"
+			}
+			for _, m := range live {
+				payouts[m.UserID] = m.Stake
+				if err := creditMember(tx, pool.GroupID, m.UserID, m.Stake, SideBetEscrow(sb.ID), models.PointsLogSideBetRefund, sb.ID, note); err != nil {
+					return err
+				}
+			}
+		} else {
+			var winners []models.SideBetMember
+			for _, m := range live {
+				if m.OptionID == winningOptionID {
+					winners = append(winners, m)
+				}
+			}
+			distributed := 0
+			for j, m := range winners {
+				share := (m.Stake * pot) / winningStakes
+				if j == len(winners)-1 {
+					// Last winner gets remainder to avoid rounding loss
+					share = pot - distributed
+				}
+				distributed += share
+				payouts[m.UserID] = share
+				if err := creditMember(tx, pool.GroupID, m.UserID, share, SideBetEscrow(sb.ID), models.PointsLogSideBetWon, sb.ID,
+					fmt.Sprintf("Won %d points from a side bet on pool \"%s\"", share, pool.Title)); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := closeSideBetTx(tx, sb, models.SideBetStatusSettled, payouts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refundSideBetsTx refunds every open side bet on a pool being cancelled.
+func refundSideBetsTx(tx *gorm.DB, pool *models.Pool, note string) error {
+	sideBets, err := openSideBetsTx(tx, pool.ID)
+	if err != nil {
+		return err
+	}
+	for i := range sideBets {
+		if err := refundSideBetTx(tx, &sideBets[i], note); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func refundSideBetTx(tx *gorm.DB, sb *models.SideBet, note string) error {
+	var members []models.SideBetMember
+	if err := tx.Where("side_bet_id = ? AND joined_at IS NOT NULL", sb.ID).Find(&members).Error; err != nil {
+		return err
+	}
+	payouts := make(map[string]int, len(members))
+	for _, m := range members {
+		payouts[m.UserID] = m.Stake
+		if err := creditMember(tx, sb.GroupID, m.UserID, m.Stake, SideBetEscrow(sb.ID), models.PointsLogSideBetRefund, sb.ID, note); err != nil {
+			return err
+		}
+	}
+	return closeSideBetTx(tx, sb, models.SideBetStatusRefunded, payouts)
+}
+
+func closeSideBetTx(tx *gorm.DB, sb *models.SideBet, status models.SideBetStatus, payouts map[string]int) error {
+	for userID, amount := range payouts {
+		if err := tx.Model(&models.SideBetMember{}).
+			Where("side_bet_id = ? AND user_id = ?", sb.ID, userID).
+			Update("payout", amount).Error; err != nil {
+			return err
+		}
+	}
+	now := time.Now()
+	return tx.Model(&models.SideBet{}).Where("id = ?", sb.ID).Updates(map[string]interface{}{
+		"status":     status,
+		"settled_at": now,
+	}).Error
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/codyseavey/bets/models"
+)
+
+func TestSideBetSettlesWithPool(t *testing.T) {
+	db, poolSvc, groupSvc, group, alice, bob := setupPoolTest(t)
+	carol := createTestUser(t, db, "carol", "Carol")
+	groupSvc.JoinGroup(group.InviteCode, carol.ID)
+	sideSvc := NewSideBetService(db)
+
+	pool, _ := poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{
+		Title:   "Derby",
+		Options: []string{"Home", "Away"},
+	})
+	home, away := pool.Options[0].ID, pool.Options[1].ID
+
+	if _, err := sideSvc.CreateSideBet(group.ID, pool.ID, alice.ID, CreateSideBetRequest{
+		Terms: "Loser buys pizza", OptionID: home, Stake: 100, MemberIDs: []string{alice.ID},
+	}); err == nil {
+		t.Error("expected error with nobody else invited")
+	}
+
+	sb, err := sideSvc.CreateSideBet(group.ID, pool.ID, alice.ID, CreateSideBetRequest{
+		Terms: "Loser buys pizza", OptionID: home, Stake: 100, MemberIDs: []string{bob.ID},
+	})
+	if err != nil {
+		t.Fatalf("CreateSideBet failed: %v", err)
+	}
+	if got := memberBalance(t, poolSvc, group.ID, alice.ID); got != 900 {
+		t.Errorf("expected Alice's stake escrowed (900), got %d", got)
+	}
+
+	if _, err := sideSvc.GetSideBet(sb.ID, carol.ID); err == nil {
+		t.Error("expected side bet hidden from uninvited member")
+	}
+	if _, err := sideSvc.JoinSideBet(sb.ID, carol.ID, JoinSideBetRequest{OptionID: away, Stake: 50}); err == nil {
+		t.Error("expected error joining without an invite")
+	}
+	if _, err := sideSvc.JoinSideBet(sb.ID, bob.ID, JoinSideBetRequest{OptionID: away, Stake: 300}); err != nil {
+		t.Fatalf("JoinSideBet failed: %v", err)
+	}
+	if _, err := sideSvc.JoinSideBet(sb.ID, bob.ID, JoinSideBetRequest{OptionID: away, Stake: 300}); err == nil {
+		t.Error("expected error joining twice")
+	}
+
+	// The side bet settles even though nobody bet on the pool itself
+	if err := poolSvc.ResolvePool(pool.ID, home, alice.ID, false); err != nil {
+		t.Fatalf("ResolvePool failed: %v", err)
+	}
+
+	if got := memberBalance(t, poolSvc, group.ID, alice.ID); got != 1300 {
+		t.Errorf("expected Alice at 1300, got %d", got)
+	}
+	if got := memberBalance(t, poolSvc, group.ID, bob.ID); got != 700 {
+		t.Errorf("expected Bob at 700, got %d", got)
+	}
+
+	settled, _ := sideSvc.GetSideBet(sb.ID, bob.ID)
+	if settled.Status != models.SideBetStatusSettled {
+		t.Errorf("expected settled, got %s", settled.Status)
+	}
+	for _, m := range settled.Members {
+		if m.UserID == alice.ID && m.Payout != 400 {
+			t.Errorf("expected Alice's payout recorded as 400, got %d", m.Payout)
+		}
+	}
+
+	assertReconciled(t, db, group.ID)
+}
+
+func TestSideBetRefundedWhenPoolCancelled(t *testing.T) {
+	db, poolSvc, _, group, alice, bob := setupPoolTest(t)
+	sideSvc := NewSideBetService(db)
+
+	pool, _ := poolSvc.CreatePool(group.ID, alice.ID, CreatePoolRequest{
+		Title:   "Rain delay",
+		Options: []string{"Yes", "No"},
+	})
+
+	sb, err := sideSvc.CreateSideBet(group.ID, pool.ID, bob.ID, CreateSideBetRequest{
+		Terms: "Double or nothing", OptionID: pool.Options[0].ID, Stake: 200, MemberIDs: []string{alice.ID},
+	})
+	if err != nil {
+		t.Fatalf("CreateSideBet failed: %v", err)
+	}
+	sideSvc.JoinSideBet(sb.ID, alice.ID, JoinSideBetRequest{OptionID: pool.Options[1].ID, Stake: 200})
+
+	if err := poolSvc.CancelPool(pool.ID, alice.ID, false); err != nil {
+		t.Fatalf("CancelPool failed: %v", err)
+	}
+
+	if got := memberBalance(t, poolSvc, group.ID, alice.ID); got != 1000 {
+		t.Errorf("expected Alice refunded to 1000, got %d", got)
+	}
+	if got := memberBalance(t, poolSvc, group.ID, bob.ID); got != 1000 {
+		t.Errorf("expected Bob refunded to 1000, got %d", got)
+	}
+	refunded, _ := sideSvc.GetSideBet(sb.ID, bob.ID)
+	if refunded.Status != models.SideBetStatusRefunded {
+		t.Errorf("expected refunded, got %s", refunded.Status)
+	}
+
+	assertReconciled(t, db, group.ID)
+}
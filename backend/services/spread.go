@@ -0,0 +1,156 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codyseavey/bets/models"
+)
+
+type CreateSpreadRequest struct {
+	Title          string     `json:"title"` // defaults to "<team a> vs <team b>"
+	Description    string     `json:"description"`
+	TeamA          string     `json:"team_a" binding:"required"`
+	TeamB          string     `json:"team_b" binding:"required"`
+	Line           float64    `json:"line"` // added to team A's score, e.g. -3.5 when team A is favoured
+	LockAt         *time.Time `json:"lock_at"`
+	ParticipantIDs []string   `json:"participant_ids"`
+}
+
+// FormatSpreadLine renders a line the way sportsbooks do: "-3.5", "+7", or
+// "PK" for a pick'em.
+func FormatSpreadLine(line float64) string {
+	if line == 0 {
+		return "PK"
+	}
+	s := strconv.FormatFloat(line, 'f', -1, 64)
+	if line > 0 {
+		s = "+" + s
+	}
+	return s
+}
+
+// CreateSpread opens a two-option pool, one option per side of the line.
+func (s *PoolService) CreateSpread(groupID, userID string, req CreateSpreadRequest) (*models.Pool, error) {
+	req.TeamA = strings.TrimSpace(req.TeamA)
+	req.TeamB = strings.TrimSpace(req.TeamB)
+	if req.TeamA == "" || req.TeamB == "" {
+		return nil, fmt.Errorf("both teams are required")
+	}
+	if strings.EqualFold(req.TeamA, req.TeamB) {
+		return nil, fmt.Errorf("teams must be different")
+	}
+	if math.IsNaN(req.Line) || math.IsInf(req.Line, 0) || req.Line*2 != math.Trunc(req.Line*2) {
+		return nil, fmt.Errorf("line must be a multiple of 0.5")
+	}
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		title = req.TeamA + " vs " + req.TeamB
+	}
+
+	tx := s.db.Begin()
+
+	pool, err := createPoolTx(tx, groupID, userID, models.PoolTypeSpread, CreatePoolRequest{
+		Title:       title,
+		Description: req.Description,
+		Options: []string{
+			req.TeamA + " " + FormatSpreadLine(req.Line),
+			req.TeamB + " " + FormatSpreadLine(-req.Line),
+		},
+		LockAt:         req.LockAt,
+		ParticipantIDs: req.ParticipantIDs,
+	})
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	spread := &models.PoolSpread{
+		PoolID:    pool.ID,
+		TeamA:     req.TeamA,
+		TeamB:     req.TeamB,
+		Line:      req.Line,
+		OptionAID: pool.Options[0].ID,
+		OptionBID: pool.Options[1].ID,
+	}
+	if err := tx.Create(spread).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to create spread: %w", err)
+	}
+	pool.Spread = spread
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return pool, nil
+}
+
+type ResolveSpreadRequest struct {
+	ScoreA *int `json:"score_a" binding:"required,gte=0"`
+	ScoreB *int `json:"score_b" binding:"required,gte=0"`
+}
+
+// ResolveSpread records the final score and settles the pool against the
+// line. If the adjusted margin is exactly zero both sides push and every bet
+// is refunded.
+func (s *PoolService) ResolveSpread(poolID, userID string, isAdmin bool, req ResolveSpreadRequest) error {
+	if req.ScoreA == nil || req.ScoreB == nil {
+		return fmt.Errorf("both scores are required")
+	}
+	if *req.ScoreA < 0 || *req.ScoreB < 0 {
+		return fmt.Errorf("scores can't be negative")
+	}
+
+	tx := s.db.Begin()
+
+	var pool models.Pool
+	if err := tx.Preload("Spread").First(&pool, "id = ?", poolID).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("pool not found")
+	}
+	if pool.Type != models.PoolTypeSpread || pool.Spread == nil {
+		tx.Rollback()
+		return fmt.Errorf("pool isn't a spread pool")
+	}
+	if pool.Status != models.PoolStatusOpen && pool.Status != models.PoolStatusLocked {
+		tx.Rollback()
+		return fmt.Errorf("pool cannot be resolved (status: %s)", pool.Status)
+	}
+	if pool.CreatedBy != userID && !isAdmin {
+		tx.Rollback()
+		return fmt.Errorf("only pool creator or group admin can resolve")
+	}
+
+	spread := pool.Spread
+	if err := tx.Model(spread).Updates(map[string]interface{}{
+		"score_a": *req.ScoreA,
+		"score_b": *req.ScoreB,
+	}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// Compare in half points so the line never goes through float arithmetic
+	margin := 2*(*req.ScoreA-*req.ScoreB) + int(math.Round(spread.Line*2))
+	var winningOptionID string
+	var pushes []string
+	switch {
+	case margin > 0:
+		winningOptionID = spread.OptionAID
+	case margin < 0:
+		winningOptionID = spread.OptionBID
+	default:
+		pushes = []string{spread.OptionAID, spread.OptionBID}
+	}
+
+	if err := settlePoolTx(tx, &pool, winningOptionID, pushes, userID, ""); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
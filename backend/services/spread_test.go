@@ -0,0 +1,96 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/codyseavey/bets/models"
+)
+
+func intPtr(n int) *int { return &n }
+
+func TestSpreadPoolCoversLine(t *testing.T) {
+	_, poolSvc, _, group, alice, bob := setupPoolTest(t)
+
+	if _, err := poolSvc.CreateSpread(group.ID, alice.ID, CreateSpreadRequest{TeamA: "Hawks", TeamB: "Owls", Line: -3.25}); err == nil {
+		t.Error("expected error for a line that isn't a multiple of 0.5")
+	}
+
+	pool, err := poolSvc.CreateSpread(group.ID, alice.ID, CreateSpreadRequest{TeamA: "Hawks", TeamB: "Owls", Line: -3.5})
+	if err != nil {
+		t.Fatalf("CreateSpread failed: %v", err)
+	}
+	if pool.Title != "Hawks vs Owls" {
+		t.Errorf("expected default title, got %q", pool.Title)
+	}
+	if pool.Options[0].Label != "Hawks -3.5" || pool.Options[1].Label != "Owls +3.5" {
+		t.Errorf("unexpected option labels %q, %q", pool.Options[0].Label, pool.Options[1].Label)
+	}
+
+	poolSvc.PlaceBet(pool.ID, alice.ID, PlaceBetRequest{OptionID: pool.Spread.OptionAID, Points: 100})
+	poolSvc.PlaceBet(pool.ID, bob.ID, PlaceBetRequest{OptionID: pool.Spread.OptionBID, Points: 100})
+
+	if err := poolSvc.ResolvePool(pool.ID, pool.Spread.OptionAID, alice.ID, true); err == nil {
+		t.Error("expected spread pools to reject picking a winner directly")
+	}
+	if err := poolSvc.ResolveSpread(pool.ID, bob.ID, false, ResolveSpreadRequest{ScoreA: intPtr(24), ScoreB: intPtr(21)}); err == nil {
+		t.Error("expected error resolving as a non-creator")
+	}
+
+	// Hawks win by 3, which doesn't cover -3.5
+	if err := poolSvc.ResolveSpread(pool.ID, alice.ID, false, ResolveSpreadRequest{ScoreA: intPtr(24), ScoreB: intPtr(21)}); err != nil {
+		t.Fatalf("ResolveSpread failed: %v", err)
+	}
+
+	resolved, _ := poolSvc.GetPool(pool.ID, "")
+	if resolved.WinningOptionID != pool.Spread.OptionBID {
+		t.Errorf("expected the underdog side to win, got %q", resolved.WinningOptionID)
+	}
+	if resolved.Spread == nil || resolved.Spread.ScoreA == nil || *resolved.Spread.ScoreA != 24 {
+		t.Error("expected final score to be stored")
+	}
+	if got := memberBalance(t, poolSvc, group.ID, bob.ID); got != 1100 {
+		t.Errorf("expected Bob at 1100, got %d", got)
+	}
+	if got := memberBalance(t, poolSvc, group.ID, alice.ID); got != 900 {
+		t.Errorf("expected Alice at 900, got %d", got)
+	}
+}
+
+func TestSpreadPoolPushesOnWholeLine(t *testing.T) {
+	_, poolSvc, _, group, alice, bob := setupPoolTest(t)
+
+	pool, err := poolSvc.CreateSpread(group.ID, alice.ID, CreateSpreadRequest{Title: "Derby", TeamA: "Hawks", TeamB: "Owls", Line: 7})
+	if err != nil {
+		t.Fatalf("CreateSpread failed: %v", err)
+	}
+	if pool.Options[0].Label != "Hawks +7" || pool.Options[1].Label != "Owls -7" {
+		t.Errorf("unexpected option labels %q, %q", pool.Options[0].Label, pool.Options[1].Label)
+	}
+
+	poolSvc.PlaceBet(pool.ID, alice.ID, PlaceBetRequest{OptionID: pool.Spread.OptionAID, Points: 100})
+	poolSvc.PlaceBet(pool.ID, bob.ID, PlaceBetRequest{OptionID: pool.Spread.OptionBID, Points: 250})
+
+	// Owls win by exactly 7
+	if err := poolSvc.ResolveSpread(pool.ID, alice.ID, false, ResolveSpreadRequest{ScoreA: intPtr(10), ScoreB: intPtr(17)}); err != nil {
+		t.Fatalf("ResolveSpread failed: %v", err)
+	}
+
+	resolved, _ := poolSvc.GetPool(pool.ID, "")
+	if resolved.Status != models.PoolStatusResolved {
+		t.Errorf("expected resolved, got %s", resolved.Status)
+	}
+	if resolved.WinningOptionID != "" {
+		t.Errorf("expected no winning option on a push, got %q", resolved.WinningOptionID)
+	}
+	for _, o := range resolved.Options {
+		if !o.Push {
+			t.Errorf("expected option %q to be marked push", o.Label)
+		}
+	}
+	if got := memberBalance(t, poolSvc, group.ID, alice.ID); got != 1000 {
+		t.Errorf("expected Alice refunded to 1000, got %d", got)
+	}
+	if got := memberBalance(t, poolSvc, group.ID, bob.ID); got != 1000 {
+		t.Errorf("expected Bob refunded to 1000, got %d", got)
+	}
+}
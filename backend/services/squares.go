@@ -0,0 +1,392 @@
+package services
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/codyseavey/bets/models"
+)
+
+const squaresDrawActor = "system:squares_draw"
+
+var defaultSquaresPayouts = []int{25, 25, 25, 25}
+
+type SquaresService struct {
+	db *gorm.DB
+}
+
+func NewSquaresService(db *gorm.DB) *SquaresService {
+	return &SquaresService{db: db}
+}
+
+type CreateSquaresRequest struct {
+	Title          string `json:"title" binding:"required"`
+	Description    string `json:"description"`
+	RowTeam        string `json:"row_team" binding:"required"`
+	ColTeam        string `json:"col_team" binding:"required"`
+	PricePerSquare int    `json:"price_per_square" binding:"required,gt=0"`
+	Payouts        []int  `json:"payouts"` // percent per quarter; defaults to 25 each
+}
+
+func (s *SquaresService) CreateGame(groupID, userID string, req CreateSquaresRequest) (*models.SquaresGame, error) {
+	payouts := req.Payouts
+	if len(payouts) == 0 {
+		payouts = defaultSquaresPayouts
+	}
+	if len(payouts) != models.SquaresQuarters {
+		return nil, fmt.Errorf("payouts must list a percentage for each of the %d quarters", models.SquaresQuarters)
+	}
+	total := 0
+	parts := make([]string, len(payouts))
+	for i, p := range payouts {
+		if p < 0 {
+			return nil, fmt.Errorf("payout percentages can't be negative")
+		}
+		total += p
+		parts[i] = strconv.Itoa(p)
+	}
+	if total != 100 {
+		return nil, fmt.Errorf("payout percentages must add up to 100, got %d", total)
+	}
+
+	game := &models.SquaresGame{
+		ID:             uuid.New().String(),
+		GroupID:        groupID,
+		Title:          req.Title,
+		Description:    req.Description,
+		RowTeam:        req.RowTeam,
+		ColTeam:        req.ColTeam,
+		PricePerSquare: req.PricePerSquare,
+		Payouts:        strings.Join(parts, ","),
+		Status:         models.SquaresStatusOpen,
+		CreatedBy:      userID,
+	}
+	if err := s.db.Create(game).Error; err != nil {
+		return nil, fmt.Errorf("failed to create squares game: %w", err)
+	}
+	return game, nil
+}
+
+func (s *SquaresService) GetGroupGames(groupID string) ([]models.SquaresGame, error) {
+	var games []models.SquaresGame
+	err := s.db.Where("group_id = ?", groupID).
+		Preload("Creator").
+		Order("created_at DESC").
+		Find(&games).Error
+	return games, err
+}
+
+func (s *SquaresService) GetGame(gameID string) (*models.SquaresGame, error) {
+	var game models.SquaresGame
+	err := s.db.
+		Preload("Creator").
+		Preload("Claims", func(db *gorm.DB) *gorm.DB {
+			return db.Order("row ASC, col ASC")
+		}).
+		Preload("Claims.User").
+		Preload("Quarters", func(db *gorm.DB) *gorm.DB {
+			return db.Order("quarter ASC")
+		}).
+		Preload("Quarters.Winner").
+		First(&game, "id = ?", gameID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &game, nil
+}
+
+func (s *SquaresService) GetGameGroupID(gameID string) (string, error) {
+	var game models.SquaresGame
+	if err := s.db.Select("group_id").First(&game, "id = ?", gameID).Error; err != nil {
+		return "", err
+	}
+	return game.GroupID, nil
+}
+
+type SquareInput struct {
+	Row int `json:"row" binding:"gte=0,lt=10"`
+	Col int `json:"col" binding:"gte=0,lt=10"`
+}
+
+type ClaimSquaresRequest struct {
+	Squares []SquareInput `json:"squares" binding:"required,min=1,dive"`
+}
+
+// ClaimSquares buys one or more empty squares at the game's price. Claiming
+// the last open square triggers the digit draw.
+func (s *SquaresService) ClaimSquares(gameID, userID string, req ClaimSquaresRequest) (*models.SquaresGame, error) {
+	tx := s.db.Begin()
+
+	var game models.SquaresGame
+	if err := tx.First(&game, "id = ?", gameID).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("squares game not found")
+	}
+	if game.Status != models.SquaresStatusOpen {
+		tx.Rollback()
+		return nil, fmt.Errorf("squares game is %s", game.Status)
+	}
+
+	seen := make(map[[2]int]bool, len(req.Squares))
+	for _, sq := range req.Squares {
+		if sq.Row < 0 || sq.Row >= models.SquaresGridSize || sq.Col < 0 || sq.Col >= models.SquaresGridSize {
+			tx.Rollback()
+			return nil, fmt.Errorf("square (%d, %d) is off the grid", sq.Row, sq.Col)
+		}
+		k := [2]int{sq.Row, sq.Col}
+		if seen[k] {
+			tx.Rollback()
+			return nil, fmt.Errorf("square (%d, %d) listed twice", sq.Row, sq.Col)
+		}
+		seen[k] = true
+
+		var taken int64
+		tx.Model(&models.SquaresClaim{}).Where("game_id = ? AND row = ? AND col = ?", gameID, sq.Row, sq.Col).Count(&taken)
+		if taken > 0 {
+			tx.Rollback()
+			return nil, fmt.Errorf("square (%d, %d) is already taken", sq.Row, sq.Col)
+		}
+	}
+
+	cost := game.PricePerSquare * len(req.Squares)
+	if err := debitMember(tx, game.GroupID, userID, cost, SquaresEscrow(gameID), models.PointsLogSquaresClaim, gameID,
+		fmt.Sprintf("Claimed %d square(s) in \"%s\"", len(req.Squares), game.Title)); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	for _, sq := range req.Squares {
+		claim := models.SquaresClaim{GameID: gameID, Row: sq.Row, Col: sq.Col, UserID: userID}
+		if err := tx.Create(&claim).Error; err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to claim square: %w", err)
+		}
+	}
+
+	var claimed int64
+	tx.Model(&models.SquaresClaim{}).Where("game_id = ?", gameID).Count(&claimed)
+	if claimed == models.SquaresGridSize*models.SquaresGridSize {
+		if err := drawSquaresDigitsTx(tx, &game); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetGame(gameID)
+}
+
+// drawSquaresDigitsTx assigns each axis an independent random permutation of
+// 0-9 using crypto/rand and writes a zero-amount ledger entry with the result
+// so the draw can be audited later.
+func drawSquaresDigitsTx(tx *gorm.DB, game *models.SquaresGame) error {
+	rows, err := shuffledDigits()
+	if err != nil {
+		return err
+	}
+	cols, err := shuffledDigits()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	game.RowDigits, game.ColDigits, game.DrawnAt = rows, cols, &now
+	game.Status = models.SquaresStatusDrawn
+	if err := tx.Model(game).Updates(map[string]interface{}{
+		"row_digits": rows,
+		"col_digits": cols,
+		"drawn_at":   now,
+		"status":     models.SquaresStatusDrawn,
+	}).Error; err != nil {
+		return err
+	}
+
+	drawLog := &models.PointsLog{
+		ID:          uuid.New().String(),
+		GroupID:     game.GroupID,
+		UserID:      game.CreatedBy,
+		Amount:      0,
+		Type:        models.PointsLogSquaresDraw,
+		ReferenceID: game.ID,
+		Note:        fmt.Sprintf("Drew digits for \"%s\": %s rows %s, %s columns %s", game.Title, game.RowTeam, rows, game.ColTeam, cols),
+		Actor:       squaresDrawActor,
+	}
+	return tx.Create(drawLog).Error
+}
+
+// shuffledDigits returns the digits 0-9 in a uniformly random order, as a
+// 10-character string, via a Fisher-Yates shuffle driven by crypto/rand.
+func shuffledDigits() (string, error) {
+	digits := []byte("0123456789")
+	for i := len(digits) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", fmt.Errorf("failed to draw digits: %w", err)
+		}
+		digits[i], digits[j.Int64()] = digits[j.Int64()], digits[i]
+	}
+	return string(digits), nil
+}
+
+type ReportQuarterRequest struct {
+	Quarter  int `json:"quarter" binding:"required,gte=1,lte=4"`
+	RowScore int `json:"row_score" binding:"gte=0"`
+	ColScore int `json:"col_score" binding:"gte=0"`
+}
+
+// ReportQuarter settles a quarter from the cumulative score at its end. The
+// owner of the square whose row and column digits match the last digit of
+// each team's score is paid that quarter's share. Quarters settle in order,
+// and the final quarter takes whatever is left so rounding never loses points.
+func (s *SquaresService) ReportQuarter(gameID string, req ReportQuarterRequest) (*models.SquaresGame, error) {
+	tx := s.db.Begin()
+
+	var game models.SquaresGame
+	if err := tx.First(&game, "id = ?", gameID).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("squares game not found")
+	}
+	if game.Status != models.SquaresStatusDrawn {
+		if game.Status == models.SquaresStatusOpen {
+			tx.Rollback()
+			return nil, fmt.Errorf("the grid isn't full yet")
+		}
+		tx.Rollback()
+		return nil, fmt.Errorf("squares game is already %s", game.Status)
+	}
+
+	var settled []models.SquaresQuarter
+	tx.Where("game_id = ?", gameID).Order("quarter ASC").Find(&settled)
+	if req.Quarter != len(settled)+1 {
+		tx.Rollback()
+		return nil, fmt.Errorf("quarter %d is next to be settled", len(settled)+1)
+	}
+
+	row := strings.IndexByte(game.RowDigits, byte('0'+req.RowScore%10))
+	col := strings.IndexByte(game.ColDigits, byte('0'+req.ColScore%10))
+	if row < 0 || col < 0 {
+		tx.Rollback()
+		return nil, fmt.Errorf("digits haven't been drawn")
+	}
+
+	var claim models.SquaresClaim
+	if err := tx.First(&claim, "game_id = ? AND row = ? AND col = ?", gameID, row, col).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("winning square has no owner")
+	}
+
+	pot := game.PricePerSquare * models.SquaresGridSize * models.SquaresGridSize
+	amount := pot * squaresPayoutPercent(game.Payouts, req.Quarter) / 100
+	if req.Quarter == models.SquaresQuarters {
+		paid := 0
+		for _, q := range settled {
+			paid += q.Amount
+		}
+		amount = pot - paid
+	}
+
+	result := models.SquaresQuarter{
+		GameID:   gameID,
+		Quarter:  req.Quarter,
+		RowScore: req.RowScore,
+		ColScore: req.ColScore,
+		Row:      row,
+		Col:      col,
+		WinnerID: claim.UserID,
+		Amount:   amount,
+	}
+	if err := tx.Create(&result).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to record quarter: %w", err)
+	}
+
+	if amount > 0 {
+		if err := creditMember(tx, game.GroupID, claim.UserID, amount, SquaresEscrow(gameID), models.PointsLogSquaresWon, gameID,
+			fmt.Sprintf("Won %d points in \"%s\" Q%d (%s %d, %s %d)", amount, game.Title, req.Quarter,
+				game.RowTeam, req.RowScore, game.ColTeam, req.ColScore)); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if req.Quarter == models.SquaresQuarters {
+		if err := tx.Model(&game).Update("status", models.SquaresStatusCompleted).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetGame(gameID)
+}
+
+func squaresPayoutPercent(payouts string, quarter int) int {
+	parts := strings.Split(payouts, ",")
+	if quarter < 1 || quarter > len(parts) {
+		return 0
+	}
+	pct, _ := strconv.Atoi(parts[quarter-1])
+	return pct
+}
+
+// CancelGame refunds every claimed square. A game can't be cancelled once any
+// quarter has paid out.
+func (s *SquaresService) CancelGame(gameID string) error {
+	tx := s.db.Begin()
+
+	var game models.SquaresGame
+	if err := tx.First(&game, "id = ?", gameID).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("squares game not found")
+	}
+	if game.Status == models.SquaresStatusCompleted || game.Status == models.SquaresStatusCancelled {
+		tx.Rollback()
+		return fmt.Errorf("squares game is already %s", game.Status)
+	}
+	var quarters int64
+	tx.Model(&models.SquaresQuarter{}).Where("game_id = ?", gameID).Count(&quarters)
+	if quarters > 0 {
+		tx.Rollback()
+		return fmt.Errorf("can't cancel after a quarter has paid out")
+	}
+
+	var owned []struct {
+		UserID string
+		Count  int
+	}
+	if err := tx.Model(&models.SquaresClaim{}).
+		Select("user_id, COUNT(*) AS count").
+		Where("game_id = ?", gameID).
+		Group("user_id").
+		Scan(&owned).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	for _, o := range owned {
+		if err := creditMember(tx, game.GroupID, o.UserID, o.Count*game.PricePerSquare, SquaresEscrow(gameID), models.PointsLogSquaresRefund, gameID,
+			fmt.Sprintf("Squares game \"%s\" cancelled, %d square(s) refunded", game.Title, o.Count)); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Model(&game).Update("status", models.SquaresStatusCancelled).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/codyseavey/bets/models"
+)
+
+func squaresRows(from, to int) ClaimSquaresRequest {
+	var req ClaimSquaresRequest
+	for r := from; r < to; r++ {
+		for c := 0; c < models.SquaresGridSize; c++ {
+			req.Squares = append(req.Squares, SquareInput{Row: r, Col: c})
+		}
+	}
+	return req
+}
+
+func TestShuffledDigitsIsPermutation(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		digits, err := shuffledDigits()
+		if err != nil {
+			t.Fatalf("shuffledDigits failed: %v", err)
+		}
+		sorted := strings.Split(digits, "")
+		sort.Strings(sorted)
+		if strings.Join(sorted, "") != "0123456789" {
+			t.Fatalf("expected a permutation of 0-9, got %q", digits)
+		}
+	}
+}
+
+func TestSquares_ClaimDrawAndSettle(t *testing.T) {
+	db, _, _, group, alice, bob := setupPoolTest(t)
+	svc := NewSquaresService(db)
+
+	if _, err := svc.CreateGame(group.ID, alice.ID, CreateSquaresRequest{
+		Title: "Bad payouts", RowTeam: "Chiefs", ColTeam: "Eagles", PricePerSquare: 5, Payouts: []int{50, 50, 50, 50},
+	}); err == nil {
+		t.Error("expected error for payouts not adding to 100")
+	}
+
+	game, err := svc.CreateGame(group.ID, alice.ID, CreateSquaresRequest{
+		Title: "Super Bowl", RowTeam: "Chiefs", ColTeam: "Eagles", PricePerSquare: 5, Payouts: []int{10, 20, 30, 40},
+	})
+	if err != nil {
+		t.Fatalf("CreateGame failed: %v", err)
+	}
+
+	if _, err := svc.ClaimSquares(game.ID, alice.ID, squaresRows(0, 5)); err != nil {
+		t.Fatalf("alice ClaimSquares failed: %v", err)
+	}
+	if _, err := svc.ClaimSquares(game.ID, bob.ID, ClaimSquaresRequest{Squares: []SquareInput{{Row: 0, Col: 0}}}); err == nil {
+		t.Error("expected error claiming a taken square")
+	}
+	if _, err := svc.ReportQuarter(game.ID, ReportQuarterRequest{Quarter: 1, RowScore: 7, ColScore: 3}); err == nil {
+		t.Error("expected error settling before the grid fills")
+	}
+
+	filled, err := svc.ClaimSquares(game.ID, bob.ID, squaresRows(5, 10))
+	if err != nil {
+		t.Fatalf("bob ClaimSquares failed: %v", err)
+	}
+	if filled.Status != models.SquaresStatusDrawn || len(filled.RowDigits) != 10 || len(filled.ColDigits) != 10 {
+		t.Fatalf("expected digits drawn once the grid filled, got %+v", filled)
+	}
+
+	var drawLog models.PointsLog
+	if err := db.Where("type = ? AND reference_id = ?", models.PointsLogSquaresDraw, game.ID).First(&drawLog).Error; err != nil {
+		t.Fatalf("expected draw audit entry: %v", err)
+	}
+	if !strings.Contains(drawLog.Note, filled.RowDigits) || !strings.Contains(drawLog.Note, filled.ColDigits) {
+		t.Errorf("expected draw log to record the digits, got %q", drawLog.Note)
+	}
+
+	if _, err := svc.ReportQuarter(game.ID, ReportQuarterRequest{Quarter: 2, RowScore: 7, ColScore: 3}); err == nil {
+		t.Error("expected error settling quarters out of order")
+	}
+
+	balances := map[string]int{alice.ID: 750, bob.ID: 750}
+	scores := [][2]int{{7, 3}, {14, 10}, {17, 24}, {27, 24}}
+	expected := []int{50, 100, 150, 200}
+	for i, sc := range scores {
+		updated, err := svc.ReportQuarter(game.ID, ReportQuarterRequest{Quarter: i + 1, RowScore: sc[0], ColScore: sc[1]})
+		if err != nil {
+			t.Fatalf("ReportQuarter %d failed: %v", i+1, err)
+		}
+		q := updated.Quarters[i]
+		if q.Amount != expected[i] {
+			t.Errorf("Q%d: expected payout %d, got %d", i+1, expected[i], q.Amount)
+		}
+		if string(updated.RowDigits[q.Row]) != string(rune('0'+sc[0]%10)) || string(updated.ColDigits[q.Col]) != string(rune('0'+sc[1]%10)) {
+			t.Errorf("Q%d: square (%d, %d) doesn't match score %v", i+1, q.Row, q.Col, sc)
+		}
+		owner := alice.ID
+		if q.Row >= 5 {
+			owner = bob.ID
+		}
+		if q.WinnerID != owner {
+			t.Errorf("Q%d: expected winner %s, got %s", i+1, owner, q.WinnerID)
+		}
+		balances[owner] += q.Amount
+	}
+
+	var final models.SquaresGame
+	db.First(&final, "id = ?", game.ID)
+	if final.Status != models.SquaresStatusCompleted {
+		t.Errorf("expected completed, got %s", final.Status)
+	}
+	for userID, want := range balances {
+		var m models.GroupMember
+		db.Where("group_id = ? AND user_id = ?", group.ID, userID).First(&m)
+		if m.PointsBalance != want {
+			t.Errorf("expected %s balance %d, got %d", userID, want, m.PointsBalance)
+		}
+	}
+
+	assertReconciled(t, db, group.ID)
+}
+
+func TestSquares_CancelRefunds(t *testing.T) {
+	db, _, _, group, alice, bob := setupPoolTest(t)
+	svc := NewSquaresService(db)
+
+	game, _ := svc.CreateGame(group.ID, alice.ID, CreateSquaresRequest{
+		Title: "Game", RowTeam: "A", ColTeam: "B", PricePerSquare: 10,
+	})
+	svc.ClaimSquares(game.ID, bob.ID, ClaimSquaresRequest{Squares: []SquareInput{{Row: 1, Col: 1}, {Row: 2, Col: 2}}})
+
+	if err := svc.CancelGame(game.ID); err != nil {
+		t.Fatalf("CancelGame failed: %v", err)
+	}
+	var m models.GroupMember
+	db.Where("group_id = ? AND user_id = ?", group.ID, bob.ID).First(&m)
+	if m.PointsBalance != 1000 {
+		t.Errorf("expected Bob refunded to 1000, got %d", m.PointsBalance)
+	}
+	if _, err := svc.ClaimSquares(game.ID, alice.ID, ClaimSquaresRequest{Squares: []SquareInput{{Row: 0, Col: 0}}}); err == nil {
+		t.Error("expected error claiming in a cancelled game")
+	}
+
+	assertReconciled(t, db, group.ID)
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/codyseavey/bets/models"
+)
+
+// transferWindow is how far back a member's sent transfers count against the
+// group's TransferLimit.
+const transferWindow = 24 * time.Hour
+
+type TransferService struct {
+	db *gorm.DB
+}
+
+func NewTransferService(db *gorm.DB) *TransferService {
+	return &TransferService{db: db}
+}
+
+type SendTransferRequest struct {
+	ToUserID string `json:"to_user_id" binding:"required"`
+	Amount   int    `json:"amount" binding:"required,gt=0"`
+	Note     string `json:"note"`
+}
+
+// SendPoints moves points straight from one member's balance to another's,
+// within the sender's daily limit.
+func (s *TransferService) SendPoints(groupID, fromUserID string, req SendTransferRequest) (*models.Transfer, error) {
+	if req.ToUserID == fromUserID {
+		return nil, fmt.Errorf("you can't send points to yourself")
+	}
+
+	var group models.Group
+	if err := s.db.First(&group, "id = ?", groupID).Error; err != nil {
+		return nil, fmt.Errorf("group not found")
+	}
+	if group.TransferLimit == 0 {
+		return nil, fmt.Errorf("transfers are turned off in this group")
+	}
+
+	tx := s.db.Begin()
+
+	if !isMemberTx(tx, groupID, req.ToUserID) {
+		tx.Rollback()
+		return nil, fmt.Errorf("recipient is not a member of this group")
+	}
+
+	var sent int
+	tx.Model(&models.Transfer{}).
+		Where("group_id = ? AND from_user_id = ? AND created_at > ?", groupID, fromUserID, time.Now().Add(-transferWindow)).
+		Select("COALESCE(SUM(amount), 0)").Scan(&sent)
+	if sent+req.Amount > group.TransferLimit {
+		tx.Rollback()
+		return nil, fmt.Errorf("daily transfer limit is %d points, you can send %d more today", group.TransferLimit, max(group.TransferLimit-sent, 0))
+	}
+
+	nameOf := userNamesTx(tx, fromUserID, req.ToUserID)
+
+	transfer := &models.Transfer{
+		ID:         uuid.New().String(),
+		GroupID:    groupID,
+		FromUserID: fromUserID,
+		ToUserID:   req.ToUserID,
+		Amount:     req.Amount,
+		Note:       req.Note,
+	}
+	if err := tx.Create(transfer).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to create transfer: %w", err)
+	}
+
+	sentNote := fmt.Sprintf("Sent to %s", nameOf[req.ToUserID])
+	receivedNote := fmt.Sprintf("Received from %s", nameOf[fromUserID])
+	if req.Note != "" {
+		sentNote += ": " + req.Note
+		receivedNote += ": " + req.Note
+	}
+	if err := payMemberTx(tx, groupID, fromUserID, req.ToUserID, req.Amount,
+		models.PointsLogTransferSent, models.PointsLogTransferReceived, transfer.ID, sentNote, receivedNote); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+
+	s.db.Preload("FromUser").Preload("ToUser").First(transfer, "id = ?", transfer.ID)
+	return transfer, nil
+}
+
+// GetUserTransfers lists the transfers a member sent or received, newest first.
+func (s *TransferService) GetUserTransfers(groupID, userID string) ([]models.Transfer, error) {
+	var transfers []models.Transfer
+	err := s.db.Where("group_id = ? AND (from_user_id = ? OR to_user_id = ?)", groupID, userID, userID).
+		Preload("FromUser").
+		Preload("ToUser").
+		Order("created_at DESC").
+		Find(&transfers).Error
+	return transfers, err
+}
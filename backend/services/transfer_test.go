@@ -0,0 +1,90 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/codyseavey/bets/models"
+)
+
+func TestSendPoints(t *testing.T) {
+	db, poolSvc, groupSvc, group, alice, bob := setupPoolTest(t)
+	transferSvc := NewTransferService(db)
+
+	transfer, err := transferSvc.SendPoints(group.ID, alice.ID, SendTransferRequest{
+		ToUserID: bob.ID, Amount: 150, Note: "you owe me one",
+	})
+	if err != nil {
+		t.Fatalf("SendPoints failed: %v", err)
+	}
+	if transfer.FromUser.Name != "Alice" || transfer.ToUser.Name != "Bob" {
+		t.Errorf("expected users loaded on the transfer, got %+v", transfer)
+	}
+	if got := memberBalance(t, poolSvc, group.ID, alice.ID); got != 850 {
+		t.Errorf("expected Alice at 850, got %d", got)
+	}
+	if got := memberBalance(t, poolSvc, group.ID, bob.ID); got != 1150 {
+		t.Errorf("expected Bob at 1150, got %d", got)
+	}
+
+	var received models.PointsLog
+	db.Where("user_id = ? AND type = ?", bob.ID, models.PointsLogTransferReceived).First(&received)
+	if received.Amount != 150 || received.ReferenceID != transfer.ID || received.Note != "Received from Alice: you owe me one" {
+		t.Errorf("unexpected received log %+v", received)
+	}
+
+	if _, err := transferSvc.SendPoints(group.ID, alice.ID, SendTransferRequest{ToUserID: alice.ID, Amount: 10}); err == nil {
+		t.Error("expected error sending to yourself")
+	}
+	outsider := createTestUser(t, db, "carol", "Carol")
+	if _, err := transferSvc.SendPoints(group.ID, alice.ID, SendTransferRequest{ToUserID: outsider.ID, Amount: 10}); err == nil {
+		t.Error("expected error sending to a non-member")
+	}
+
+	// Default limit is 500 a day; 150 already sent
+	if _, err := transferSvc.SendPoints(group.ID, alice.ID, SendTransferRequest{ToUserID: bob.ID, Amount: 351}); err == nil {
+		t.Error("expected the daily limit to be enforced")
+	}
+	if _, err := transferSvc.SendPoints(group.ID, alice.ID, SendTransferRequest{ToUserID: bob.ID, Amount: 350}); err != nil {
+		t.Errorf("expected the rest of the limit to be usable, got %v", err)
+	}
+	if _, err := transferSvc.SendPoints(group.ID, bob.ID, SendTransferRequest{ToUserID: alice.ID, Amount: 500}); err != nil {
+		t.Errorf("expected Bob's limit to be separate, got %v", err)
+	}
+
+	zero := 0
+	groupSvc.UpdateGroup(group.ID, group.Name, group.DefaultPoints, &zero, nil)
+	if _, err := transferSvc.SendPoints(group.ID, bob.ID, SendTransferRequest{ToUserID: alice.ID, Amount: 1}); err == nil {
+		t.Error("expected transfers to be off with a zero limit")
+	}
+
+	history, _ := transferSvc.GetUserTransfers(group.ID, alice.ID)
+	if len(history) != 3 {
+		t.Errorf("expected 3 transfers in Alice's history, got %d", len(history))
+	}
+
+	assertReconciled(t, db, group.ID)
+	report, _ := AuditGroup(db, group.ID)
+	if !report.Clean() {
+		t.Errorf("expected a clean audit, got %+v", report.Balances)
+	}
+}
+
+func TestSendPointsInsufficientBalance(t *testing.T) {
+	db, poolSvc, groupSvc, group, alice, bob := setupPoolTest(t)
+	transferSvc := NewTransferService(db)
+
+	limit := 5000
+	groupSvc.UpdateGroup(group.ID, group.Name, group.DefaultPoints, &limit, nil)
+	if _, err := transferSvc.SendPoints(group.ID, alice.ID, SendTransferRequest{ToUserID: bob.ID, Amount: 1001}); err == nil {
+		t.Error("expected error sending more than the balance")
+	}
+	if got := memberBalance(t, poolSvc, group.ID, bob.ID); got != 1000 {
+		t.Errorf("expected Bob untouched, got %d", got)
+	}
+
+	var count int64
+	db.Model(&models.Transfer{}).Count(&count)
+	if count != 0 {
+		t.Errorf("expected the failed transfer rolled back, got %d", count)
+	}
+}
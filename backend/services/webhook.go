@@ -0,0 +1,167 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/clause"
+
+	"github.com/codyseavey/bets/models"
+)
+
+// Inbound webhooks are signed like Stripe/GitHub hooks: the sender computes
+// HMAC-SHA256 over "<timestamp>.<METHOD>.<path>.<raw body>" with the group's
+// secret and sends
+//
+//	X-Bets-Timestamp: <unix seconds>
+//	X-Bets-Signature: sha256=<hex digest>
+//
+// The method and path tie a signature to one endpoint, so a signed lock can't
+// be replayed as a resolve or against another pool. The timestamp lets us
+// reject old requests, and each accepted signature is recorded so it can't be
+// used twice while the timestamp is still fresh.
+const (
+	WebhookTimestampHeader = "X-Bets-Timestamp"
+	WebhookSignatureHeader = "X-Bets-Signature"
+	WebhookActor           = "webhook"
+
+	webhookMaxSkew = 5 * time.Minute
+)
+
+var (
+	ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+	ErrWebhookReplayed         = errors.New("webhook has already been received")
+)
+
+// SignWebhook returns the X-Bets-Signature header value for a request.
+func SignWebhook(secret, method, path, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write([]byte(strings.ToUpper(method)))
+	mac.Write([]byte("."))
+	mac.Write([]byte(path))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature checks a signed request against the group secret and
+// rejects timestamps more than a few minutes away from now. It doesn't check
+// for replays; see RecordWebhookDelivery.
+func VerifyWebhookSignature(secret, method, path, timestamp, signature string, body []byte, now time.Time) error {
+	if secret == "" {
+		return fmt.Errorf("webhooks are not enabled for this group")
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("missing or invalid %s header", WebhookTimestampHeader)
+	}
+	skew := now.Sub(time.Unix(ts, 0))
+	if skew > webhookMaxSkew || skew < -webhookMaxSkew {
+		return fmt.Errorf("webhook timestamp outside allowed window")
+	}
+
+	if !strings.HasPrefix(signature, "sha256=") {
+		return ErrInvalidWebhookSignature
+	}
+	expected := SignWebhook(secret, method, path, timestamp, body)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return ErrInvalidWebhookSignature
+	}
+	return nil
+}
+
+// RecordWebhookDelivery remembers a verified webhook and returns
+// ErrWebhookReplayed if the same timestamp and signature were seen before.
+// Deliveries older than the allowed timestamp window can't pass verification
+// again, so they're pruned here.
+func (s *GroupService) RecordWebhookDelivery(groupID, timestamp, signature string, now time.Time) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("missing or invalid %s header", WebhookTimestampHeader)
+	}
+
+	if err := s.db.Where("created_at < ?", now.Add(-2*webhookMaxSkew)).Delete(&models.WebhookDelivery{}).Error; err != nil {
+		return err
+	}
+
+	result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.WebhookDelivery{
+		GroupID:   groupID,
+		Signature: signature,
+		Timestamp: ts,
+		CreatedAt: now,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrWebhookReplayed
+	}
+	return nil
+}
+
+// WebhookOutcome turns a webhook's winning option and push options, each an
+// ID or a label, into option IDs. The winner can be left out when every
+// option is a push, as with a manual resolve.
+func WebhookOutcome(options []models.PoolOption, winning string, pushes []string) (string, []string, error) {
+	pushIDs := make([]string, 0, len(pushes))
+	for _, p := range pushes {
+		id := MatchOption(options, p)
+		if id == "" {
+			return "", nil, fmt.Errorf("invalid push option %s", p)
+		}
+		pushIDs = append(pushIDs, id)
+	}
+
+	if strings.TrimSpace(winning) == "" {
+		if len(pushIDs) == 0 {
+			return "", nil, fmt.Errorf("a winning option is required unless every option is a push")
+		}
+		return "", pushIDs, nil
+	}
+	optionID := MatchOption(options, winning)
+	if optionID == "" {
+		return "", nil, fmt.Errorf("invalid winning option")
+	}
+	return optionID, pushIDs, nil
+}
+
+// RegenerateWebhookSecret creates (or rotates) the group's webhook secret.
+// The secret is only returned here; it's never serialized with the group.
+func (s *GroupService) RegenerateWebhookSecret(groupID string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	secret := hex.EncodeToString(b)
+
+	result := s.db.Model(&models.Group{}).Where("id = ?", groupID).Update("webhook_secret", secret)
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return "", fmt.Errorf("group not found")
+	}
+	return secret, nil
+}
+
+func (s *GroupService) DisableWebhooks(groupID string) error {
+	return s.db.Model(&models.Group{}).Where("id = ?", groupID).Update("webhook_secret", "").Error
+}
+
+func (s *GroupService) GetWebhookSecret(groupID string) (string, error) {
+	var group models.Group
+	if err := s.db.Select("webhook_secret").First(&group, "id = ?", groupID).Error; err != nil {
+		return "", err
+	}
+	return group.WebhookSecret, nil
+}
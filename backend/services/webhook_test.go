@@ -0,0 +1,161 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/codyseavey/bets/models"
+)
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	secret := "s3cret"
+	body := []byte(`{"winning_option":"Lions"}`)
+	now := time.Now()
+	ts := strconv.FormatInt(now.Unix(), 10)
+	path := "/api/hooks/groups/g1/pools/p1/resolve"
+	sig := SignWebhook(secret, "POST", path, ts, body)
+
+	if err := VerifyWebhookSignature(secret, "POST", path, ts, sig, body, now); err != nil {
+		t.Errorf("expected valid signature, got %v", err)
+	}
+
+	// Tampered body
+	if err := VerifyWebhookSignature(secret, "POST", path, ts, sig, []byte(`{"winning_option":"Bears"}`), now); err == nil {
+		t.Error("expected error for tampered body")
+	}
+
+	// Same signature against another endpoint
+	if err := VerifyWebhookSignature(secret, "POST", "/api/hooks/groups/g1/pools/p1/lock", ts, sig, body, now); err == nil {
+		t.Error("expected error for a different path")
+	}
+	if err := VerifyWebhookSignature(secret, "PUT", path, ts, sig, body, now); err == nil {
+		t.Error("expected error for a different method")
+	}
+
+	// Wrong secret
+	if err := VerifyWebhookSignature("other", "POST", path, ts, sig, body, now); err == nil {
+		t.Error("expected error for wrong secret")
+	}
+
+	// Replayed long after signing
+	if err := VerifyWebhookSignature(secret, "POST", path, ts, sig, body, now.Add(time.Hour)); err == nil {
+		t.Error("expected error for stale timestamp")
+	}
+
+	// Hooks disabled
+	if err := VerifyWebhookSignature("", "POST", path, ts, sig, body, now); err == nil {
+		t.Error("expected error when group has no secret")
+	}
+}
+
+func TestRecordWebhookDelivery_RejectsReplays(t *testing.T) {
+	db := setupTestDB(t)
+	svc := NewGroupService(db)
+	alice := createTestUser(t, db, "alice", "Alice")
+	group, _ := svc.CreateGroup("Hooks", 1000, alice.ID)
+	other, _ := svc.CreateGroup("Other Hooks", 1000, alice.ID)
+
+	now := time.Now()
+	ts := strconv.FormatInt(now.Unix(), 10)
+	if err := svc.RecordWebhookDelivery(group.ID, ts, "sha256=abc", now); err != nil {
+		t.Fatalf("RecordWebhookDelivery failed: %v", err)
+	}
+	if err := svc.RecordWebhookDelivery(group.ID, ts, "sha256=abc", now.Add(time.Second)); !errors.Is(err, ErrWebhookReplayed) {
+		t.Errorf("expected ErrWebhookReplayed, got %v", err)
+	}
+	if err := svc.RecordWebhookDelivery(other.ID, ts, "sha256=abc", now); err != nil {
+		t.Errorf("expected another group's delivery to be independent, got %v", err)
+	}
+
+	// Old deliveries are pruned once they can no longer verify
+	svc.RecordWebhookDelivery(group.ID, ts, "sha256=def", now.Add(time.Hour))
+	var count int64
+	db.Model(&models.WebhookDelivery{}).Where("signature = ?", "sha256=abc").Count(&count)
+	if count != 0 {
+		t.Errorf("expected stale deliveries pruned, got %d", count)
+	}
+}
+
+func TestRegenerateWebhookSecret(t *testing.T) {
+	db := setupTestDB(t)
+	svc := NewGroupService(db)
+	alice := createTestUser(t, db, "alice", "Alice")
+	group, _ := svc.CreateGroup("Hooks", 1000, alice.ID)
+
+	first, err := svc.RegenerateWebhookSecret(group.ID)
+	if err != nil {
+		t.Fatalf("RegenerateWebhookSecret failed: %v", err)
+	}
+	second, _ := svc.RegenerateWebhookSecret(group.ID)
+	if first == "" || first == second {
+		t.Error("expected a new non-empty secret on each rotation")
+	}
+
+	stored, _ := svc.GetWebhookSecret(group.ID)
+	if stored != second {
+		t.Error("expected the latest secret to be stored")
+	}
+
+	if err := svc.DisableWebhooks(group.ID); err != nil {
+		t.Fatalf("DisableWebhooks failed: %v", err)
+	}
+	stored, _ = svc.GetWebhookSecret(group.ID)
+	if stored != "" {
+		t.Error("expected secret cleared after disabling")
+	}
+}
+
+func TestResolvePoolAs_RecordsActor(t *testing.T) {
+	db, poolSvc, _, group, alice, bob := setupPoolTest(t)
+
+	pool, _ := poolSvc.CreatePool(group.ID, bob.ID, CreatePoolRequest{
+		Title:   "Hook Resolve",
+		Options: []string{"Yes", "No"},
+	})
+	poolSvc.PlaceBet(pool.ID, alice.ID, PlaceBetRequest{OptionID: pool.Options[0].ID, Points: 100})
+
+	if err := poolSvc.ResolvePoolAs(pool.ID, pool.Options[0].ID, nil, WebhookActor); err != nil {
+		t.Fatalf("ResolvePoolAs failed: %v", err)
+	}
+
+	var resolution models.PointsLog
+	db.Where("group_id = ? AND type = ?", group.ID, "pool_resolved").First(&resolution)
+	if resolution.Actor != WebhookActor {
+		t.Errorf("expected actor %q, got %q", WebhookActor, resolution.Actor)
+	}
+	if resolution.UserID != bob.ID {
+		t.Errorf("expected resolution attributed to pool creator, got %s", resolution.UserID)
+	}
+}
+
+func TestWebhookOutcome_AllPush(t *testing.T) {
+	db, poolSvc, _, group, alice, bob := setupPoolTest(t)
+
+	pool, _ := poolSvc.CreatePool(group.ID, bob.ID, CreatePoolRequest{
+		Title:   "Rained off",
+		Options: []string{"Home", "Away"},
+	})
+	poolSvc.PlaceBet(pool.ID, alice.ID, PlaceBetRequest{OptionID: pool.Options[0].ID, Points: 100})
+	poolSvc.PlaceBet(pool.ID, bob.ID, PlaceBetRequest{OptionID: pool.Options[1].ID, Points: 50})
+
+	if _, _, err := WebhookOutcome(pool.Options, "", nil); err == nil {
+		t.Error("expected error with neither a winner nor pushes")
+	}
+	if _, _, err := WebhookOutcome(pool.Options, "Draw", nil); err == nil {
+		t.Error("expected error for an unknown winning option")
+	}
+
+	optionID, pushIDs, err := WebhookOutcome(pool.Options, "", []string{"home", pool.Options[1].ID})
+	if err != nil || optionID != "" || len(pushIDs) != 2 {
+		t.Fatalf("expected every option pushed with no winner, got %q %v %v", optionID, pushIDs, err)
+	}
+	if err := poolSvc.ResolvePoolAs(pool.ID, optionID, pushIDs, WebhookActor); err != nil {
+		t.Fatalf("ResolvePoolAs failed: %v", err)
+	}
+	if got := memberBalance(t, poolSvc, group.ID, alice.ID); got != 1000 {
+		t.Errorf("expected Alice refunded to 1000, got %d", got)
+	}
+	assertReconciled(t, db, group.ID)
+}
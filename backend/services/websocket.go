@@ -97,6 +97,38 @@ func (h *Hub) BroadcastToGroup(groupID string, event WSEvent) {
 	}
 }
 
+// SendToUsers delivers an event only to the given users' connections in a
+// group, for notifications that shouldn't go to the whole room.
+func (h *Hub) SendToUsers(groupID string, userIDs []string, event WSEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to marshal WS event: %v", err)
+		return
+	}
+
+	targets := make(map[string]bool, len(userIDs))
+	for _, id := range userIDs {
+		targets[id] = true
+	}
+
+	h.mu.RLock()
+	clients := make([]*Client, 0, len(userIDs))
+	for c := range h.rooms[groupID] {
+		if targets[c.userID] {
+			clients = append(clients, c)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, client := range clients {
+		select {
+		case client.send <- data:
+		default:
+			h.unregister <- client
+		}
+	}
+}
+
 func (h *Hub) Upgrade(w http.ResponseWriter, r *http.Request) *websocket.Conn {
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/codyseavey/bets/config"
+	"github.com/codyseavey/bets/models"
+	"github.com/codyseavey/bets/services"
+	"github.com/codyseavey/bets/storage"
+)
+
+// runVerify implements `bets verify`: it walks each group's PointsLog hash
+// chain and reports entries that were edited or deleted. It exits 1 if any
+// chain is broken.
+func runVerify(args []string) int {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	dbPath := fs.String("db", "", "path to the SQLite database (defaults to $DB_PATH)")
+	groupID := fs.String("group", "", "only verify this group")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: bets verify [--db PATH] [--group ID] [--json]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	path := *dbPath
+	if path == "" {
+		path = config.Load().DBPath
+	}
+	db := storage.InitDB(path)
+
+	groupIDs := []string{*groupID}
+	if *groupID == "" {
+		groupIDs = nil
+		if err := db.Model(&models.Group{}).Order("created_at").Pluck("id", &groupIDs).Error; err != nil {
+			fmt.Fprintf(os.Stderr, "verify: %v\n", err)
+			return 2
+		}
+	}
+
+	reports := make([]*services.ChainReport, 0, len(groupIDs))
+	broken := 0
+	for _, id := range groupIDs {
+		report, err := services.VerifyChain(db, id)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "verify: group %s: %v\n", id, err)
+			return 2
+		}
+		if !report.OK() {
+			broken++
+		}
+		reports = append(reports, report)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(reports); err != nil {
+			fmt.Fprintf(os.Stderr, "verify: %v\n", err)
+			return 2
+		}
+	} else {
+		printVerify(reports)
+	}
+
+	if broken > 0 {
+		return 1
+	}
+	return 0
+}
+
+func printVerify(reports []*services.ChainReport) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	for _, r := range reports {
+		status := "ok"
+		if !r.OK() {
+			status = fmt.Sprintf("%d problems", len(r.Problems))
+		}
+		fmt.Fprintf(w, "%s: %d entries, head %d %s, %s\n", r.GroupID, r.Entries, r.HeadSeq, r.HeadHash, status)
+		for _, p := range r.Problems {
+			fmt.Fprintf(w, "  seq %d\t%s\t%s\n", p.Seq, p.LogID, p.Problem)
+		}
+	}
+}